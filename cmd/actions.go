@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/fail2ban"
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/ui"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sourceActions carries out the actions on sources requested from the TUI
+type sourceActions struct {
+	whitelist   *iplist.List
+	blacklist   *iplist.List
+	fail2banLog string // Log the ban requests are written to, if any
+	logger      *logrus.Logger
+}
+
+// Whitelist adds ip to the allow list and saves it
+func (a *sourceActions) Whitelist(ip string) error {
+	list, err := a.list(ui.ListAllow)
+	if err != nil {
+		return err
+	}
+	if err := addToList(list, ip); err != nil {
+		return fmt.Errorf("failed to whitelist %s: %w", ip, err)
+	}
+	a.logger.Infof("Whitelisted %s from the TUI", ip)
+	return nil
+}
+
+// Blacklist adds ip to the deny list and saves it
+func (a *sourceActions) Blacklist(ip string) error {
+	list, err := a.list(ui.ListDeny)
+	if err != nil {
+		return err
+	}
+	if err := addToList(list, ip); err != nil {
+		return fmt.Errorf("failed to blacklist %s: %w", ip, err)
+	}
+	a.logger.Infof("Blacklisted %s from the TUI", ip)
+	return nil
+}
+
+// Block adds ip to the deny list and writes a ban request for it to the
+// fail2ban log. Without a fail2ban log nothing would ban the source, so
+// blocking is refused. No event is published, so the statistics, store and
+// other sinks only show what the source actually did.
+func (a *sourceActions) Block(ip string) error {
+	if a.fail2banLog == "" {
+		return errors.New("blocking needs --fail2ban-log")
+	}
+	if err := a.Blacklist(ip); err != nil {
+		return err
+	}
+
+	if err := appendLine(a.fail2banLog, fail2ban.BanLine(ip, time.Now())); err != nil {
+		return fmt.Errorf("failed to ban %s: %w", ip, err)
+	}
+	a.logger.Warnf("Blocked %s from the TUI", ip)
+	return nil
+}
+
+// list returns the allow or deny list by its TUI name, or an error when it
+// is disabled
+func (a *sourceActions) list(name string) (*iplist.List, error) {
+	var list *iplist.List
+	switch name {
+	case ui.ListAllow:
+		list = a.whitelist
+	case ui.ListDeny:
+		list = a.blacklist
+	default:
+		return nil, fmt.Errorf("unknown list %q", name)
+	}
+	if list == nil {
+		return nil, fmt.Errorf("the %s list is disabled", name)
+	}
+	return list, nil
+}
+
+// Entries returns the entries of the named list
+func (a *sourceActions) Entries(name string) []string {
+	list, err := a.list(name)
+	if err != nil {
+		return nil
+	}
+	return list.Entries()
+}
+
+// Add adds an address or CIDR range to the named list and saves it
+func (a *sourceActions) Add(name, entry string) error {
+	list, err := a.list(name)
+	if err != nil {
+		return err
+	}
+	if err := addToList(list, entry); err != nil {
+		return fmt.Errorf("failed to add %s to the %s list: %w", entry, name, err)
+	}
+	a.logger.Infof("Added %s to the %s list from the TUI", entry, name)
+	return nil
+}
+
+// Remove removes an entry from the named list and saves it
+func (a *sourceActions) Remove(name, entry string) error {
+	list, err := a.list(name)
+	if err != nil {
+		return err
+	}
+	removed, err := list.Remove(entry)
+	if err == nil && removed {
+		err = list.Save()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from the %s list: %w", entry, name, err)
+	}
+	a.logger.Infof("Removed %s from the %s list from the TUI", entry, name)
+	return nil
+}
+
+// appendLine appends line to the file at path. Short appends are atomic, so
+// the line does not interleave with those of the event log sink.
+func appendLine(path, line string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// addToList adds ip to list and saves the list when it was new
+func addToList(list *iplist.List, ip string) error {
+	added, err := list.Add(ip)
+	if err != nil || !added {
+		return err
+	}
+	return list.Save()
+}
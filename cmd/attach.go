@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/client"
+	"jonasbn.github.com/portscammer/internal/config"
+	"jonasbn.github.com/portscammer/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var (
+	attachSocket      string
+	attachAddr        string
+	attachToken       string
+	attachColumns     []string
+	attachRefreshRate time.Duration
+	attachTheme       string
+	attachKeys        []string
+	attachExportDir   string
+	attachIncidentGap time.Duration
+)
+
+// attachCmd runs the TUI against the API of a running daemon
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Run the terminal UI against a running daemon",
+	Long: `Run the terminal UI against the API of a portscammer daemon running in
+headless mode, on a unix socket or a TCP address. Events are streamed from
+the daemon as they are detected, and annotations and changes to the allow
+and deny lists are stored by the daemon. Blocking a source is only
+available in the daemon's own TUI.
+
+Examples:
+  portscammer --no-ui --api --api-addr unix:/run/portscammer.sock
+  portscammer attach --socket /run/portscammer.sock
+  portscammer attach --addr 192.0.2.10:8090 --token secret`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAttach(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+
+	attachCmd.Flags().StringVar(&attachSocket, "socket", "", "Unix socket of the daemon's API, takes precedence over --addr")
+	attachCmd.Flags().StringVar(&attachAddr, "addr", config.DefaultConfig().APIAddr, "Address of the daemon's API as host:port or URL")
+	attachCmd.Flags().StringVar(&attachToken, "token", os.Getenv("PORTSCAMMER_API_TOKEN"), "Bearer token required by the API (default $PORTSCAMMER_API_TOKEN)")
+	attachCmd.Flags().StringSliceVar(&attachColumns, "columns", config.DefaultConfig().UIColumns, "Columns of the TUI event table, in order ("+strings.Join(ui.ColumnNames(), ", ")+")")
+	attachCmd.Flags().DurationVar(&attachRefreshRate, "refresh-rate", config.DefaultConfig().RefreshRate, "Minimum time between TUI redraws while events arrive")
+	attachCmd.Flags().StringVar(&attachTheme, "theme", config.DefaultConfig().UITheme, "Color theme of the TUI ("+strings.Join(ui.ThemeNames(), ", ")+"), colors are off when NO_COLOR is set")
+	attachCmd.Flags().StringArrayVar(&attachKeys, "key", nil, "Remap a TUI key as action=keys, e.g. search=f or quit=ctrl+q, may be repeated")
+	attachCmd.Flags().StringVar(&attachExportDir, "export-dir", config.DefaultConfig().UIExportDir, "Directory the TUI exports the filtered events to")
+	attachCmd.Flags().DurationVar(&attachIncidentGap, "incident-gap", config.DefaultConfig().TimeWindow, "Maximum gap between events of one incident")
+}
+
+// runAttach connects to the daemon and runs the TUI until it is quit
+func runAttach(cmd *cobra.Command) error {
+	if attachRefreshRate <= 0 {
+		return config.ErrInvalidRefreshRate
+	}
+	if err := ui.CheckColumns(attachColumns); err != nil {
+		return err
+	}
+	theme, err := ui.LookupTheme(attachTheme)
+	if err != nil {
+		return err
+	}
+	keys, err := ui.ParseKeyMap(attachKeys)
+	if err != nil {
+		return err
+	}
+
+	remote, err := client.New(client.Options{
+		Socket: attachSocket,
+		Addr:   attachAddr,
+		Token:  attachToken,
+	})
+	if err != nil {
+		return err
+	}
+	if err := remote.Ping(); err != nil {
+		return fmt.Errorf("failed to attach: %w", err)
+	}
+
+	// Receive events as the daemon publishes them rather than polling for them
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := ui.NewModel(remote, false).
+		WithEvents(remote.Subscribe(ctx)).
+		WithRefreshRate(attachRefreshRate).
+		WithColumns(attachColumns).
+		WithTheme(theme).
+		WithKeyMap(keys).
+		WithExportDir(attachExportDir).
+		WithIncidentGap(attachIncidentGap).
+		WithActions(remote).
+		WithLists(remote)
+	p := tea.NewProgram(model, tea.WithAltScreen())
+
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error running TUI: %w", err)
+	}
+	return nil
+}
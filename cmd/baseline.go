@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/baseline"
+	"jonasbn.github.com/portscammer/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// baselineSaveInterval is how often the baseline is saved while running
+const baselineSaveInterval = time.Minute
+
+// loadBaseline loads the baseline file of the configuration, starting a new
+// baseline when it does not exist
+func loadBaseline(cfg *config.Config, logger *logrus.Logger) (*baseline.Detector, error) {
+	detector, err := baseline.Load(cfg.BaselineFile, baseline.Options{
+		Threshold: cfg.BaselineThreshold,
+		Learning:  cfg.BaselineLearning,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state := detector.State()
+	if state.Started.IsZero() {
+		logger.Infof("Starting a new baseline in %s, learning for %s", cfg.BaselineFile, cfg.BaselineLearning)
+	} else if detector.Learning(time.Now()) {
+		logger.Infof("Loaded baseline from %s, learning until %s", cfg.BaselineFile, state.Started.Add(cfg.BaselineLearning).Format(time.RFC3339))
+	} else {
+		logger.Infof("Loaded baseline from %s, learned since %s", cfg.BaselineFile, state.Started.Format(time.RFC3339))
+	}
+	return detector, nil
+}
+
+// saveBaseline saves the baseline every interval. It blocks and is meant to
+// be run in its own goroutine.
+func saveBaseline(detector *baseline.Detector, path string, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := detector.Save(path); err != nil {
+			logger.Errorf("Failed to save baseline: %v", err)
+		}
+	}
+}
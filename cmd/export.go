@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/config"
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/fail2ban"
+	"jonasbn.github.com/portscammer/internal/intel"
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/store"
+	"jonasbn.github.com/portscammer/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutputDir string
+
+	fail2banJail = fail2ban.DefaultJailOptions()
+)
+
+// exportCmd groups the commands exporting data and integrations
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export data and integration files",
+	Long:  `Export scan data and configuration snippets for integration with other tools.`,
+}
+
+// exportFail2banCmd writes fail2ban filter and jail definitions
+var exportFail2banCmd = &cobra.Command{
+	Use:   "fail2ban",
+	Short: "Write fail2ban filter.d and jail.d definitions",
+	Long: `Write a fail2ban filter and jail definition matching the log lines written
+by portscammer when started with --fail2ban-log.
+
+The files are written to <output-dir>/filter.d/<name>.conf and
+<output-dir>/jail.d/<name>.conf, so pointing --output-dir at /etc/fail2ban
+installs them directly.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportFail2ban(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportFail2banCmd)
+
+	exportFail2banCmd.Flags().StringVarP(&exportOutputDir, "output-dir", "o", ".", "Directory to write filter.d and jail.d files to")
+	exportFail2banCmd.Flags().StringVar(&fail2banJail.Name, "name", fail2banJail.Name, "Name of the filter and jail")
+	exportFail2banCmd.Flags().StringVar(&fail2banJail.LogPath, "log-path", fail2banJail.LogPath, "Path of the log file written with --fail2ban-log")
+	exportFail2banCmd.Flags().IntVar(&fail2banJail.MaxRetry, "max-retry", fail2banJail.MaxRetry, "Number of matches before a ban")
+	exportFail2banCmd.Flags().DurationVar(&fail2banJail.FindTime, "find-time", fail2banJail.FindTime, "Window in which matches are counted")
+	exportFail2banCmd.Flags().DurationVar(&fail2banJail.BanTime, "ban-time", fail2banJail.BanTime, "Duration of a ban")
+	exportFail2banCmd.Flags().StringVar(&fail2banJail.BanAction, "ban-action", fail2banJail.BanAction, "fail2ban ban action")
+}
+
+// runExportFail2ban writes the fail2ban filter and jail definitions
+func runExportFail2ban(cmd *cobra.Command) error {
+	files := []struct {
+		path    string
+		content string
+	}{
+		{filepath.Join(exportOutputDir, "filter.d", fail2banJail.Name+".conf"), fail2ban.Filter()},
+		{filepath.Join(exportOutputDir, "jail.d", fail2banJail.Name+".conf"), fail2ban.Jail(fail2banJail)},
+	}
+
+	for _, file := range files {
+		path, content := file.path, file.content
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+	}
+
+	return nil
+}
+
+var (
+	exportEventsStoreDir string
+	exportEventsSince    string
+	exportEventsFormat   string
+	exportEventsOutput   string
+)
+
+// exportEventsCmd writes stored events in one of the export formats
+var exportEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Export stored scan events",
+	Long: `Export the events persisted with --store-dir in a format for other tools.
+
+Supported formats: table, json, jsonl, csv and markdown, the SIEM formats cef (ArcSight),
+leef (IBM QRadar), ecs (Elastic Common Schema) and ocsf (OCSF Network
+Activity), and the fail2ban log line format.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportEvents(cmd)
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportEventsCmd)
+
+	exportEventsCmd.Flags().StringVarP(&exportEventsStoreDir, "store-dir", "s", "", "Directory of the event store")
+	exportEventsCmd.Flags().StringVar(&exportEventsSince, "since", "", "Only events since this RFC 3339 time or duration ago (e.g. 24h, 7d)")
+	exportEventsCmd.Flags().StringVarP(&exportEventsFormat, "format", "f", "jsonl", "Export format ("+strings.Join(export.Names(), ", ")+")")
+	exportEventsCmd.Flags().StringVarP(&exportEventsOutput, "output", "o", "", "File to write to (default stdout)")
+
+	exportEventsCmd.MarkFlagRequired("store-dir")
+}
+
+// runExportEvents writes the stored events in the selected format
+func runExportEvents(cmd *cobra.Command) error {
+	formatter, err := export.Get(exportEventsFormat)
+	if err != nil {
+		return err
+	}
+
+	var filter models.EventFilter
+	if exportEventsSince != "" {
+		if filter.Since, err = utils.ParseSince(exportEventsSince, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	st, err := store.Open(store.Options{Dir: exportEventsStoreDir, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	events, err := st.Events(filter)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if exportEventsOutput != "" {
+		file, err := os.Create(exportEventsOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", exportEventsOutput, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return formatter.FormatEvents(out, events)
+}
+
+var (
+	exportIntelStoreDir       string
+	exportIntelSince          string
+	exportIntelOutput         string
+	exportIntelWhitelist      string
+	exportIntelIncludePrivate bool
+)
+
+// exportSTIXCmd writes the stored scan sources as a STIX 2.1 bundle
+var exportSTIXCmd = &cobra.Command{
+	Use:   "stix",
+	Short: "Export scan sources as a STIX 2.1 bundle",
+	Long: `Export the sources of the stored scan events as a STIX 2.1 bundle.
+
+Each source becomes an ipv4-addr or ipv6-addr observable with an indicator,
+the observed data and a sighting carrying first_seen, last_seen and count.
+Whitelisted and private addresses are left out unless --include-private is
+given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportIntel(cmd, func(w io.Writer, sightings []intel.Sighting, now time.Time) error {
+			return intel.WriteSTIX(w, sightings, "portscammer", now)
+		})
+	},
+}
+
+// exportMISPCmd writes the stored scan sources as a MISP event
+var exportMISPCmd = &cobra.Command{
+	Use:   "misp",
+	Short: "Export scan sources as a MISP event",
+	Long: `Export the sources of the stored scan events as a MISP event in JSON, with
+an ip-src attribute per source ready to be imported into a MISP instance.
+Whitelisted and private addresses are left out unless --include-private is
+given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportIntel(cmd, func(w io.Writer, sightings []intel.Sighting, now time.Time) error {
+			info := fmt.Sprintf("portscammer scan sources since %s", exportIntelSince)
+			return intel.WriteMISP(w, sightings, info, now)
+		})
+	},
+}
+
+func init() {
+	defaults := config.DefaultConfig()
+
+	for _, cmd := range []*cobra.Command{exportSTIXCmd, exportMISPCmd} {
+		exportCmd.AddCommand(cmd)
+
+		cmd.Flags().StringVarP(&exportIntelStoreDir, "store-dir", "s", "", "Directory of the event store")
+		cmd.Flags().StringVar(&exportIntelSince, "since", "7d", "Only events since this RFC 3339 time or duration ago (e.g. 24h, 7d)")
+		cmd.Flags().StringVarP(&exportIntelOutput, "output", "o", "", "File to write to (default stdout)")
+		cmd.Flags().StringVar(&exportIntelWhitelist, "whitelist-file", defaults.WhitelistFile, "Whitelist of addresses to leave out")
+		cmd.Flags().BoolVar(&exportIntelIncludePrivate, "include-private", false, "Include whitelisted and private addresses")
+
+		cmd.MarkFlagRequired("store-dir")
+	}
+}
+
+// runExportIntel aggregates the stored events per source and writes them with write
+func runExportIntel(cmd *cobra.Command, write func(io.Writer, []intel.Sighting, time.Time) error) error {
+	now := time.Now()
+
+	var filter models.EventFilter
+	var err error
+	if exportIntelSince != "" {
+		if filter.Since, err = utils.ParseSince(exportIntelSince, now); err != nil {
+			return err
+		}
+	}
+
+	var exclude func(ip string) bool
+	if !exportIntelIncludePrivate {
+		whitelist, err := iplist.Load(exportIntelWhitelist)
+		if err != nil {
+			return err
+		}
+		exclude = func(ip string) bool {
+			return utils.IsPrivateIP(ip) || whitelist.Contains(ip)
+		}
+	}
+
+	st, err := store.Open(store.Options{Dir: exportIntelStoreDir, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	events, err := st.Events(filter)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if exportIntelOutput != "" {
+		file, err := os.Create(exportIntelOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", exportIntelOutput, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return write(out, intel.Aggregate(events, exclude), now)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/intel"
+
+	"github.com/sirupsen/logrus"
+)
+
+// feedCheckInterval is how often feed files are checked for changes
+const feedCheckInterval = time.Second * 30
+
+// loadFeeds parses the feed specifications and reads the feeds
+func loadFeeds(specs []string, bump int, logger *logrus.Logger) (*intel.Feeds, error) {
+	parsed := make([]intel.FeedSpec, 0, len(specs))
+	for _, s := range specs {
+		spec, err := intel.ParseFeedSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, spec)
+	}
+
+	feeds := intel.NewFeeds(parsed, bump)
+	loaded, err := feeds.Refresh(true)
+	if err != nil {
+		return nil, err
+	}
+	logFeeds(loaded, logger)
+
+	return feeds, nil
+}
+
+// watchFeeds reloads feeds whose files changed every check interval and all
+// feeds every refresh interval, until stop is closed
+func watchFeeds(feeds *intel.Feeds, refresh, check time.Duration, stop <-chan struct{}, logger *logrus.Logger) {
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	lastFull := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			force := now.Sub(lastFull) >= refresh
+			if force {
+				lastFull = now
+			}
+
+			loaded, err := feeds.Refresh(force)
+			if err != nil {
+				logger.Errorf("Failed to refresh threat-intel feeds: %v", err)
+			}
+			logFeeds(loaded, logger)
+		}
+	}
+}
+
+// logFeeds logs the feeds that were loaded
+func logFeeds(loaded []intel.FeedInfo, logger *logrus.Logger) {
+	for _, info := range loaded {
+		logger.Infof("Loaded threat-intel feed %s from %s: %d entries, %d skipped", info.Name, info.Path, info.Entries, info.Skipped)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/portscammer"
+)
+
+const (
+	// eventPollInterval is how often the scanner is checked for new events
+	eventPollInterval = time.Millisecond * 250
+
+	// sinkBufferSize is the subscription buffer size used by alert sinks
+	sinkBufferSize = 1024
+
+	// uiBufferSize is the subscription buffer size of the TUI
+	uiBufferSize = 4096
+)
+
+// followEvents polls the scanner every interval and calls fn for each event
+// not seen before. The scanner only offers its event list, with no callback
+// or channel for new events, so the broker is fed by polling it. When stop
+// is closed it polls a last time and returns.
+func followEvents(scanner *portscammer.Scanner, interval time.Duration, stop <-chan struct{}, fn func(models.ScanEvent)) {
+	var last models.ScanEvent
+	seen := make(map[string]bool) // IDs of the events at last's timestamp
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for stopped := false; !stopped; {
+		select {
+		case <-stop:
+			stopped = true
+		case <-ticker.C:
+		}
+
+		for _, event := range newEvents(scanner.GetEvents(), last, seen) {
+			fn(event)
+			if !event.Timestamp.Equal(last.Timestamp) {
+				seen = make(map[string]bool)
+			}
+			seen[event.ID] = true
+			last = event
+		}
+	}
+}
+
+// newEvents returns the events after last in the scanner's event list. The
+// scanner trims its list, so positions shift between polls; last is found by
+// its ID, or when it was trimmed away the events from its timestamp on are
+// new, except those in seen.
+func newEvents(events []models.ScanEvent, last models.ScanEvent, seen map[string]bool) []models.ScanEvent {
+	if last.ID == "" {
+		return events
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ID == last.ID {
+			return events[i+1:]
+		}
+	}
+	for i, event := range events {
+		if event.Timestamp.After(last.Timestamp) || event.Timestamp.Equal(last.Timestamp) && !seen[event.ID] {
+			return events[i:]
+		}
+	}
+	return nil
+}
+
+// enricher adds information to an event before it is published
+type enricher func(event *models.ScanEvent)
+
+// enrich returns the event with each enricher applied in order
+func enrich(event models.ScanEvent, enrichers []enricher) models.ScanEvent {
+	for _, fn := range enrichers {
+		fn(&event)
+	}
+	return event
+}
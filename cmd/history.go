@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sourceEventLimit is the number of events kept in memory by historySource
+const sourceEventLimit = 10000
+
+// statsHistoryWindow is the period of the per-minute counts charted by the TUI
+const statsHistoryWindow = time.Hour
+
+// historySource combines the events and statistics loaded from the store at
+// startup with the events published since, after the lists, enrichment and
+// rules. The statistics count the published events rather than the
+// scanner's, so suppressed events and changed severities are reflected.
+type historySource struct {
+	store    *store.Store                   // Store events and annotations are written to, nil keeps them in memory
+	stats    models.ScanStats               // Latest snapshot loaded from the store
+	hostname func(ip string) (string, bool) // Cached reverse DNS lookup, if enabled
+	history  *models.StatsHistory           // Per-minute counts of the recent events
+
+	mu        sync.RWMutex
+	events    []models.ScanEvent
+	published models.ScanStats // Counts of the events published since startup
+}
+
+// newHistorySource loads events from the last window and the latest
+// statistics snapshot from the store
+func newHistorySource(st *store.Store, window time.Duration) (*historySource, error) {
+	source := &historySource{
+		history: models.NewStatsHistory(statsHistoryWindow),
+	}
+	if st == nil {
+		return source, nil
+	}
+	source.store = st
+
+	events, err := st.Events(models.EventFilter{Since: time.Now().Add(-window)})
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > sourceEventLimit {
+		events = events[len(events)-sourceEventLimit:]
+	}
+	source.events = events
+	for _, event := range events {
+		source.history.Add(event)
+	}
+
+	if snapshot, ok := st.LatestStats(); ok {
+		source.stats = snapshot.Stats
+	}
+
+	return source, nil
+}
+
+// GetEvents returns the stored events followed by the published events.
+// Hostnames resolved since an event was published are filled in.
+func (h *historySource) GetEvents() []models.ScanEvent {
+	h.mu.RLock()
+	events := append([]models.ScanEvent(nil), h.events...)
+	h.mu.RUnlock()
+
+	if h.hostname != nil {
+		for i := range events {
+			if events[i].Hostname == "" {
+				events[i].Hostname, _ = h.hostname(events[i].SourceIP)
+			}
+		}
+	}
+	return events
+}
+
+// Hostname returns the cached hostname of ip, so the TUI can fill in the
+// hostnames of pushed events resolved after they were published
+func (h *historySource) Hostname(ip string) (string, bool) {
+	if h.hostname == nil {
+		return "", false
+	}
+	return h.hostname(ip)
+}
+
+// Len returns the number of events held in memory
+func (h *historySource) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.events)
+}
+
+// GetStats returns the stored statistics merged with the counts of the
+// events published since
+func (h *historySource) GetStats() models.ScanStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return models.MergeStats(h.stats, h.published)
+}
+
+// GetStatsHistory returns the per-minute event counts of the last hour
+func (h *historySource) GetStatsHistory() []models.StatsMinute {
+	return h.history.Minutes(time.Now())
+}
+
+// Annotate acknowledges events or attaches a note to them, persisting the
+// annotation when a store is configured
+func (h *historySource) Annotate(annotation models.Annotation) error {
+	if annotation.Time.IsZero() {
+		annotation.Time = time.Now()
+	}
+	if h.store != nil {
+		if err := h.store.Annotate(annotation); err != nil {
+			return fmt.Errorf("failed to store annotation: %w", err)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	annotation.ApplyTo(h.events)
+	return nil
+}
+
+// record keeps a published event, writes it to the store when configured
+// and counts it. It is called before the event is published, so the store
+// misses no event.
+func (h *historySource) record(event models.ScanEvent) error {
+	var err error
+	if h.store != nil {
+		err = h.store.Append(event)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, event)
+	h.history.Add(event)
+	if len(h.events) > sourceEventLimit {
+		h.events = append(h.events[:0:0], h.events[len(h.events)-sourceEventLimit:]...)
+	}
+	h.published.Add(event)
+	return err
+}
+
+// maintainStore takes a statistics snapshot and applies retention every
+// interval, and takes a last snapshot when stop is closed
+func maintainStore(st *store.Store, source *historySource, interval time.Duration, stop <-chan struct{}, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			// Keep the latest statistics across restarts
+			if err := st.AppendStats(time.Now(), source.GetStats()); err != nil {
+				logger.Errorf("Failed to store statistics snapshot: %v", err)
+			}
+			return
+		case now := <-ticker.C:
+			if err := st.AppendStats(now, source.GetStats()); err != nil {
+				logger.Errorf("Failed to store statistics snapshot: %v", err)
+			}
+			if err := st.ApplyRetention(now); err != nil {
+				logger.Errorf("Failed to apply retention: %v", err)
+			}
+		}
+	}
+}
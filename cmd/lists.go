@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blacklistedTag is added to the events of sources on the deny list
+const blacklistedTag = "blacklisted"
+
+// listPolicy applies the allow and deny lists to new events before they are
+// published. A nil list is disabled.
+type listPolicy struct {
+	whitelist *iplist.List
+	blacklist *iplist.List
+}
+
+// loadLists loads the allow and deny lists enabled in the configuration
+func loadLists(whitelistFile, blacklistFile string, whitelistOn, blacklistOn bool, logger *logrus.Logger) (listPolicy, error) {
+	var policy listPolicy
+	var err error
+	if whitelistOn {
+		if policy.whitelist, err = iplist.Load(whitelistFile); err != nil {
+			return policy, err
+		}
+		logger.Infof("Loaded %d whitelist entries from %s", policy.whitelist.Len(), whitelistFile)
+	}
+	if blacklistOn {
+		if policy.blacklist, err = iplist.Load(blacklistFile); err != nil {
+			return policy, err
+		}
+		logger.Infof("Loaded %d blacklist entries from %s", policy.blacklist.Len(), blacklistFile)
+	}
+	return policy, nil
+}
+
+// apply drops the events of whitelisted sources by returning false, and
+// raises those of blacklisted sources to critical, so the fail2ban log and
+// the other sinks act on them
+func (p listPolicy) apply(event *models.ScanEvent) bool {
+	if p.whitelist != nil && p.whitelist.Contains(event.SourceIP) {
+		return false
+	}
+	if p.blacklist != nil && p.blacklist.Contains(event.SourceIP) {
+		event.Severity = models.SeverityCritical
+		event.AddTag(blacklistedTag)
+	}
+	return true
+}
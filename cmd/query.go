@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryStoreDir string
+	queryIP       string
+	querySince    string
+	queryUntil    string
+	querySeverity string
+	queryPort     int
+	queryType     string
+	queryFormat   string
+	queryGroupBy  string
+	queryTop      int
+	queryLimit    int
+)
+
+// queryCmd searches the stored event history
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Search stored scan events",
+	Long: `Search the events persisted with --store-dir without starting the scanner.
+
+The store is opened read-only, so it is safe to query it while portscammer is
+running and writing to it.
+
+Examples:
+  portscammer query --store-dir /var/lib/portscammer --ip 203.0.113.0/24 --since 24h
+  portscammer query --store-dir /var/lib/portscammer --severity high --port 22 --format csv
+  portscammer query --store-dir /var/lib/portscammer --since 7d --group-by ip --top 10`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runQuery(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().StringVarP(&queryStoreDir, "store-dir", "s", "", "Directory of the event store")
+	queryCmd.Flags().StringVar(&queryIP, "ip", "", "Only events from this IP address or CIDR range")
+	queryCmd.Flags().StringVar(&querySince, "since", "", "Only events since this RFC 3339 time or duration ago (e.g. 24h, 7d)")
+	queryCmd.Flags().StringVar(&queryUntil, "until", "", "Only events before this RFC 3339 time or duration ago")
+	queryCmd.Flags().StringVar(&querySeverity, "severity", "", "Only events with at least this severity (low, medium, high, critical)")
+	queryCmd.Flags().IntVar(&queryPort, "port", 0, "Only events targeting this port")
+	queryCmd.Flags().StringVar(&queryType, "type", "", "Only events of this scan type")
+	queryCmd.Flags().StringVarP(&queryFormat, "format", "f", "table", "Output format (table, json, csv, markdown)")
+	queryCmd.Flags().StringVarP(&queryGroupBy, "group-by", "g", "", "Group events by ip, port, type or hour")
+	queryCmd.Flags().IntVar(&queryTop, "top", 10, "Number of groups to show with --group-by, 0 for all")
+	queryCmd.Flags().IntVar(&queryLimit, "limit", 0, "Show only the most recent events, 0 for all")
+
+	queryCmd.MarkFlagRequired("store-dir")
+}
+
+// runQuery searches the store and writes the matching events or groups
+func runQuery(cmd *cobra.Command) error {
+	filter, err := queryFilter()
+	if err != nil {
+		return err
+	}
+
+	st, err := store.Open(store.Options{Dir: queryStoreDir, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	events, err := st.Events(filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	if queryGroupBy != "" {
+		groups, err := models.GroupEvents(events, queryGroupBy)
+		if err != nil {
+			return err
+		}
+		if queryTop > 0 && len(groups) > queryTop {
+			groups = groups[:queryTop]
+		}
+		return export.WriteGroups(out, queryFormat, groups)
+	}
+
+	if queryLimit > 0 && len(events) > queryLimit {
+		events = events[len(events)-queryLimit:]
+	}
+
+	formatter, err := export.Get(queryFormat)
+	if err != nil {
+		return err
+	}
+	return formatter.FormatEvents(out, events)
+}
+
+// queryFilter builds the event filter from the query flags, sharing the
+// parsing with the API's /events endpoint
+func queryFilter() (models.EventFilter, error) {
+	params := map[string][]string{}
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = []string{value}
+		}
+	}
+
+	set("ip", queryIP)
+	set("since", querySince)
+	set("until", queryUntil)
+	set("severity", querySeverity)
+	set("type", queryType)
+	if queryPort != 0 {
+		set("port", strconv.Itoa(queryPort))
+	}
+
+	filter, err := models.ParseEventFilter(params, time.Now())
+	if err != nil {
+		return filter, fmt.Errorf("invalid query: %w", err)
+	}
+	return filter, nil
+}
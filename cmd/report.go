@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/report"
+	"jonasbn.github.com/portscammer/internal/store"
+	"jonasbn.github.com/portscammer/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportStoreDir    string
+	reportPeriod      string
+	reportFormat      string
+	reportOutput      string
+	reportIncidentGap time.Duration
+)
+
+// reportCmd writes a summary report from stored history
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a summary report of stored scan events",
+	Long: `Write a summary report covering totals, new and repeat sources, top ports,
+a severity breakdown, the incident timeline and an hourly histogram.
+
+The HTML report is self-contained with inline SVG charts and no external
+assets, so it can be mailed or archived as a single file.
+
+Examples:
+  portscammer report --store-dir /var/lib/portscammer --period 24h --format md
+  portscammer report --store-dir /var/lib/portscammer --period 7d --format html -o weekly.html`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReport(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVarP(&reportStoreDir, "store-dir", "s", "", "Directory of the event store")
+	reportCmd.Flags().StringVar(&reportPeriod, "period", "24h", "Period covered by the report, ending now (e.g. 24h, 7d)")
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "md", "Report format (md, html)")
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "File to write the report to (default stdout)")
+	reportCmd.Flags().DurationVar(&reportIncidentGap, "incident-gap", time.Minute*5, "Maximum gap between events of one incident")
+
+	reportCmd.MarkFlagRequired("store-dir")
+}
+
+// runReport builds the report from the store and writes it
+func runReport(cmd *cobra.Command) error {
+	period, err := utils.ParseDuration(reportPeriod)
+	if err != nil || period <= 0 {
+		return fmt.Errorf("invalid period %q", reportPeriod)
+	}
+
+	st, err := store.Open(store.Options{Dir: reportStoreDir, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	r, err := report.FromStore(st, period, time.Now(), reportIncidentGap)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if reportOutput != "" {
+		file, err := os.Create(reportOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return r.Write(out, reportFormat)
+}
+
+// scheduleReports writes a report covering the previous interval to dir at
+// the end of every interval, aligned to local midnight, until stop is closed
+func scheduleReports(st *store.Store, dir, format string, interval, incidentGap time.Duration, stop <-chan struct{}, logger *logrus.Logger) {
+	for {
+		end := report.NextRun(time.Now(), interval)
+		timer := time.NewTimer(time.Until(end))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		path, err := writeReportFile(st, dir, format, interval, end, incidentGap)
+		if err != nil {
+			logger.Errorf("Failed to write scheduled report: %v", err)
+			continue
+		}
+		logger.Infof("Wrote scheduled report to %s", path)
+	}
+}
+
+// writeReportFile writes a report for the period ending at end to a new
+// file in dir and returns its path
+func writeReportFile(st *store.Store, dir, format string, period time.Duration, end time.Time, incidentGap time.Duration) (string, error) {
+	r, err := report.FromStore(st, period, end, incidentGap)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "portscammer-report-"+end.Format("20060102-1504")+report.Extension(format))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report: %w", err)
+	}
+	defer file.Close()
+
+	if err := r.Write(file, format); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -2,10 +2,26 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"jonasbn.github.com/portscammer/internal/api"
+	"jonasbn.github.com/portscammer/internal/baseline"
 	"jonasbn.github.com/portscammer/internal/config"
+	"jonasbn.github.com/portscammer/internal/geoip"
+	"jonasbn.github.com/portscammer/internal/intel"
+	"jonasbn.github.com/portscammer/internal/metrics"
+	"jonasbn.github.com/portscammer/internal/models"
 	"jonasbn.github.com/portscammer/internal/portscammer"
+	"jonasbn.github.com/portscammer/internal/rdns"
+	"jonasbn.github.com/portscammer/internal/sigma"
+	"jonasbn.github.com/portscammer/internal/store"
+	"jonasbn.github.com/portscammer/internal/stream"
 	"jonasbn.github.com/portscammer/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,13 +30,47 @@ import (
 )
 
 var (
-	port      int
-	host      string
-	logFile   string
-	logLevel  string
-	threshold int
-	noUI      bool
-	debug     bool
+	port              int
+	host              string
+	logFile           string
+	logLevel          string
+	threshold         int
+	noUI              bool
+	debug             bool
+	fail2banLog       string
+	eventLog          string
+	eventFormat       string
+	metricsAddr       string
+	apiEnabled        bool
+	apiAddr           string
+	apiToken          string
+	storeDir          string
+	maxAge            time.Duration
+	maxSizeMB         int64
+	reportDir         string
+	reportFmt         string
+	feeds             []string
+	feedRefresh       time.Duration
+	geoipDBs          []string
+	rdnsEnabled       bool
+	rdnsServer        string
+	rdnsTimeout       time.Duration
+	rdnsWorkers       int
+	rulesFile         string
+	sigmaPaths        []string
+	uiColumns         []string
+	refreshRate       time.Duration
+	uiTheme           string
+	uiKeys            []string
+	uiExportDir       string
+	baselineOn        bool
+	baselineFile      string
+	baselineThreshold float64
+	baselineLearning  time.Duration
+	whitelistOn       bool
+	blacklistOn       bool
+	whitelistFile     string
+	blacklistFile     string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -51,8 +101,42 @@ func init() {
 	rootCmd.Flags().StringVarP(&logFile, "log-file", "l", "portscammer.log", "Log file path")
 	rootCmd.Flags().StringVarP(&logLevel, "log-level", "L", "info", "Log level (debug, info, warn, error)")
 	rootCmd.Flags().IntVarP(&threshold, "threshold", "t", 1, "Number of connections to trigger scan detection")
+	rootCmd.Flags().BoolVar(&whitelistOn, "whitelist", false, "Ignore the events of sources on the whitelist")
+	rootCmd.Flags().BoolVar(&blacklistOn, "blacklist", false, "Raise the events of sources on the blacklist to critical")
+	rootCmd.Flags().StringVar(&whitelistFile, "whitelist-file", config.DefaultConfig().WhitelistFile, "Whitelist file, one address or CIDR range per line")
+	rootCmd.Flags().StringVar(&blacklistFile, "blacklist-file", config.DefaultConfig().BlacklistFile, "Blacklist file, one address or CIDR range per line")
 	rootCmd.Flags().BoolVarP(&noUI, "no-ui", "n", false, "Disable terminal UI and run in headless mode")
 	rootCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
+	rootCmd.Flags().StringVar(&fail2banLog, "fail2ban-log", "", "Write scan events to this file in a fail2ban compatible format")
+	rootCmd.Flags().StringVar(&eventLog, "event-log", "", "Write scan events to this file, one line per event")
+	rootCmd.Flags().StringVar(&eventFormat, "event-format", "jsonl", "Format of the event log (jsonl, cef, leef, ecs, ocsf, fail2ban)")
+	rootCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on (e.g. localhost:9090)")
+	rootCmd.Flags().BoolVar(&apiEnabled, "api", false, "Enable the local HTTP/JSON API")
+	rootCmd.Flags().StringVar(&apiAddr, "api-addr", "localhost:8090", "API listen address, host:port or unix:/path/to/socket")
+	rootCmd.Flags().StringVar(&apiToken, "api-token", os.Getenv("PORTSCAMMER_API_TOKEN"), "Bearer token required by the API (default $PORTSCAMMER_API_TOKEN)")
+	rootCmd.Flags().StringVarP(&storeDir, "store-dir", "s", "", "Directory to persist events and statistics in")
+	rootCmd.Flags().DurationVar(&maxAge, "retention-max-age", time.Hour*24*30, "Remove stored data older than this")
+	rootCmd.Flags().Int64Var(&maxSizeMB, "retention-max-size", 1024, "Remove the oldest stored data beyond this size in MiB")
+	rootCmd.Flags().StringVar(&reportDir, "report-dir", "", "Write a daily summary report to this directory (requires --store-dir)")
+	rootCmd.Flags().StringVar(&reportFmt, "report-format", "html", "Format of the daily summary report (md, html)")
+	rootCmd.Flags().StringArrayVar(&feeds, "feed", nil, "Threat-intel feed file as name[:format]=path, may be repeated")
+	rootCmd.Flags().DurationVar(&feedRefresh, "feed-refresh", time.Hour, "Interval between full reloads of the threat-intel feeds")
+	rootCmd.Flags().StringArrayVar(&geoipDBs, "geoip-db", nil, "GeoLite2-City or GeoLite2-ASN format mmdb file for enrichment, may be repeated")
+	rootCmd.Flags().StringSliceVar(&uiColumns, "columns", config.DefaultConfig().UIColumns, "Columns of the TUI event table, in order ("+strings.Join(ui.ColumnNames(), ", ")+")")
+	rootCmd.Flags().DurationVar(&refreshRate, "refresh-rate", config.DefaultConfig().RefreshRate, "Minimum time between TUI redraws while events arrive")
+	rootCmd.Flags().StringVar(&uiTheme, "theme", config.DefaultConfig().UITheme, "Color theme of the TUI ("+strings.Join(ui.ThemeNames(), ", ")+"), colors are off when NO_COLOR is set")
+	rootCmd.Flags().StringArrayVar(&uiKeys, "key", nil, "Remap a TUI key as action=keys, e.g. search=f or quit=ctrl+q, may be repeated")
+	rootCmd.Flags().StringVar(&uiExportDir, "export-dir", config.DefaultConfig().UIExportDir, "Directory the TUI exports the filtered events to")
+	rootCmd.Flags().StringVar(&rulesFile, "rules", "", "YAML file with detection rules, reloaded when it changes")
+	rootCmd.Flags().StringArrayVar(&sigmaPaths, "sigma", nil, "Sigma rule file or directory of firewall/network_connection rules, may be repeated")
+	rootCmd.Flags().BoolVar(&baselineOn, "baseline", false, "Learn a baseline of event rates and report deviations as anomaly events")
+	rootCmd.Flags().StringVar(&baselineFile, "baseline-file", "baseline.json", "File the baseline is persisted in")
+	rootCmd.Flags().Float64Var(&baselineThreshold, "baseline-threshold", 3, "Score from which a deviation from the baseline is an anomaly")
+	rootCmd.Flags().DurationVar(&baselineLearning, "baseline-learning", time.Hour*24, "Learning period after the first event during which no anomalies are reported")
+	rootCmd.Flags().BoolVar(&rdnsEnabled, "rdns", false, "Look up the hostnames of scanning sources")
+	rootCmd.Flags().StringVar(&rdnsServer, "rdns-server", "", "DNS server for reverse lookups as host:port (default system resolver)")
+	rootCmd.Flags().DurationVar(&rdnsTimeout, "rdns-timeout", time.Second*2, "Timeout of a single reverse lookup")
+	rootCmd.Flags().IntVar(&rdnsWorkers, "rdns-concurrency", 4, "Maximum number of reverse lookups in flight")
 }
 
 // runPortScammer starts the port scanner detection application
@@ -64,14 +148,62 @@ func runPortScammer() {
 	cfg.LogFile = logFile
 	cfg.LogLevel = logLevel
 	cfg.ScanThreshold = threshold
+	cfg.WhitelistEnabled = whitelistOn
+	cfg.BlacklistEnabled = blacklistOn
+	cfg.WhitelistFile = whitelistFile
+	cfg.BlacklistFile = blacklistFile
 	cfg.UIEnabled = !noUI
 	cfg.Debug = debug
+	cfg.Fail2banLog = fail2banLog
+	cfg.EventLog = eventLog
+	cfg.EventLogFormat = eventFormat
+	cfg.MetricsAddr = metricsAddr
+	cfg.APIEnabled = apiEnabled
+	cfg.APIAddr = apiAddr
+	cfg.APIToken = apiToken
+	cfg.StoreDir = storeDir
+	cfg.RetentionMaxAge = maxAge
+	cfg.RetentionMaxSize = maxSizeMB << 20
+	cfg.ReportDir = reportDir
+	cfg.ReportFormat = reportFmt
+	cfg.Feeds = feeds
+	cfg.FeedRefresh = feedRefresh
+	cfg.GeoIPDBs = geoipDBs
+	cfg.ReverseDNS = rdnsEnabled
+	cfg.DNSServer = rdnsServer
+	cfg.DNSTimeout = rdnsTimeout
+	cfg.DNSConcurrency = rdnsWorkers
+	cfg.RulesFile = rulesFile
+	cfg.SigmaRules = sigmaPaths
+	cfg.UIColumns = uiColumns
+	cfg.RefreshRate = refreshRate
+	cfg.UITheme = uiTheme
+	cfg.UIKeys = uiKeys
+	cfg.UIExportDir = uiExportDir
+	cfg.Baseline = baselineOn
+	cfg.BaselineFile = baselineFile
+	cfg.BaselineThreshold = baselineThreshold
+	cfg.BaselineLearning = baselineLearning
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		fmt.Printf("Configuration error: %v\n", err)
 		os.Exit(1)
 	}
+	if err := ui.CheckColumns(cfg.UIColumns); err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	theme, err := ui.LookupTheme(cfg.UITheme)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	keys, err := ui.ParseKeyMap(cfg.UIKeys)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Setup logger
 	logger := logrus.New()
@@ -107,9 +239,285 @@ func runPortScammer() {
 		}
 	}()
 
+	// Load the enabled allow and deny lists
+	lists, err := loadLists(cfg.WhitelistFile, cfg.BlacklistFile, cfg.WhitelistEnabled, cfg.BlacklistEnabled, logger)
+	if err != nil {
+		logger.Fatalf("Failed to load allow and deny lists: %v", err)
+	}
+	whitelist, blacklist := lists.whitelist, lists.blacklist
+
+	// Open event store if specified
+	var st *store.Store
+	if cfg.StoreDir != "" {
+		st, err = store.Open(store.Options{
+			Dir:     cfg.StoreDir,
+			MaxAge:  cfg.RetentionMaxAge,
+			MaxSize: cfg.RetentionMaxSize,
+		})
+		if err != nil {
+			logger.Fatalf("Failed to open event store: %v", err)
+		}
+		defer st.Close()
+	}
+
+	// Load threat-intel feeds
+	var enrichers []enricher
+	var feeds *intel.Feeds
+	if len(cfg.Feeds) > 0 {
+		feeds, err = loadFeeds(cfg.Feeds, cfg.FeedSeverityBump, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load threat-intel feeds: %v", err)
+		}
+		enrichers = append(enrichers, feeds.Enrich)
+	}
+
+	// Open GeoIP databases
+	if len(cfg.GeoIPDBs) > 0 {
+		geo, err := geoip.Open(cfg.GeoIPDBs...)
+		if err != nil {
+			logger.Fatalf("Failed to open GeoIP databases: %v", err)
+		}
+		defer geo.Close()
+		enrichers = append(enrichers, geo.Enrich)
+	}
+
+	// Resolve source hostnames in the background, and store them for the
+	// events published before they were known
+	var resolver *rdns.Resolver
+	if cfg.ReverseDNS {
+		var onResolved func(ip, hostname string)
+		if st != nil {
+			onResolved = func(ip, hostname string) {
+				if err := st.SetHostname(ip, hostname, time.Now()); err != nil {
+					logger.Errorf("Failed to store hostname of %s: %v", ip, err)
+				}
+			}
+		}
+		resolver = rdns.New(rdns.Options{
+			Server:      cfg.DNSServer,
+			Timeout:     cfg.DNSTimeout,
+			Concurrency: cfg.DNSConcurrency,
+			CacheSize:   cfg.DNSCacheSize,
+			TTL:         cfg.DNSCacheTTL,
+			OnResolved:  onResolved,
+		})
+		defer resolver.Close()
+		enrichers = append(enrichers, resolver.Enrich)
+	}
+
+	// Load detection rules
+	var handler *ruleHandler
+	if cfg.RulesFile != "" {
+		handler, err = newRuleHandler(cfg, blacklist, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load rules: %v", err)
+		}
+		defer handler.Close()
+	}
+
+	// Load Sigma rules
+	var sigmaRules []*sigma.Rule
+	if len(cfg.SigmaRules) > 0 {
+		sigmaRules, err = loadSigmaRules(cfg.SigmaRules, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load Sigma rules: %v", err)
+		}
+	}
+
+	// Load the baseline
+	var detector *baseline.Detector
+	if cfg.Baseline {
+		detector, err = loadBaseline(cfg, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load baseline: %v", err)
+		}
+		go saveBaseline(detector, cfg.BaselineFile, baselineSaveInterval, logger)
+		defer func() {
+			if err := detector.Save(cfg.BaselineFile); err != nil {
+				logger.Errorf("Failed to save baseline: %v", err)
+			}
+		}()
+	}
+
+	// Combine stored history with the running scanner
+	source, err := newHistorySource(st, cfg.HistoryWindow)
+	if err != nil {
+		logger.Fatalf("Failed to load stored events: %v", err)
+	}
+	if resolver != nil {
+		source.hostname = resolver.Cached
+	}
+
+	// Setup metrics. The scanner's own listener and connection tracker are
+	// not reachable from here, so connections are counted from the events it
+	// reports, listener errors from the API and metrics listeners, and the
+	// tracked entries are the events held in memory.
+	collector := metrics.New(source.GetStats)
+	collector.SetTrackerEntries(source.Len)
+	if blacklist != nil {
+		collector.SetBlacklistSize(blacklist.Len)
+	}
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", collector.Handler())
+
+		go func() {
+			logger.Infof("Serving metrics on http://%s/metrics", cfg.MetricsAddr)
+			if err := http.ListenAndServe(cfg.MetricsAddr, mux); err != nil {
+				collector.IncListenerErrors()
+				logger.Errorf("Metrics listener failed: %v", err)
+			}
+		}()
+	}
+
+	// Stop the goroutines writing to the store before it is closed, and the
+	// event logs once the last events published by them are written
+	stop := make(chan struct{})
+	var workers sync.WaitGroup
+	var closeSinks []func() error
+	defer func() {
+		close(stop)
+		workers.Wait()
+		for _, closeSink := range closeSinks {
+			if err := closeSink(); err != nil {
+				logger.Errorf("Failed to close event log: %v", err)
+			}
+		}
+	}()
+
+	// Apply the allow and deny lists to new scanner events, enrich them,
+	// apply the rules, store and publish them and score them against the
+	// baseline
+	broker := stream.NewBroker(stream.DefaultHistorySize, stream.DropOldest)
+	publish := func(event models.ScanEvent) {
+		if err := source.record(event); err != nil {
+			logger.Errorf("Failed to store event: %v", err)
+		}
+		broker.Publish(event)
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		followEvents(scanner, eventPollInterval, stop, func(event models.ScanEvent) {
+			collector.IncConnectionsAccepted()
+			if !lists.apply(&event) {
+				return
+			}
+			event = enrich(event, enrichers)
+			if handler != nil && !handler.handle(&event) {
+				return
+			}
+			matchSigma(sigmaRules, &event, logger)
+			publish(event)
+
+			if detector != nil {
+				for _, anomaly := range detector.Observe(event) {
+					logger.Warnf("Anomaly: %s", anomaly.Description)
+					publish(anomaly)
+				}
+			}
+		})
+	}()
+
+	// Reload the threat-intel feeds
+	if feeds != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			watchFeeds(feeds, cfg.FeedRefresh, feedCheckInterval, stop, logger)
+		}()
+	}
+
+	// Reload the rules file
+	if handler != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			watchRules(handler.engine, cfg.RulesFile, ruleCheckInterval, stop, logger)
+		}()
+	}
+
+	// Snapshot statistics and apply retention
+	if st != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			maintainStore(st, source, cfg.SnapshotInterval, stop, logger)
+		}()
+
+		if cfg.ReportDir != "" {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				scheduleReports(st, cfg.ReportDir, cfg.ReportFormat, cfg.ReportInterval, cfg.TimeWindow, stop, logger)
+			}()
+		}
+	}
+
+	// Setup API
+	if cfg.APIEnabled {
+		server := api.NewServer(source, api.Options{
+			Token:       cfg.APIToken,
+			Whitelist:   whitelist,
+			Blacklist:   blacklist,
+			IncidentGap: cfg.TimeWindow,
+			Broker:      broker,
+		})
+
+		listener, err := api.Listen(cfg.APIAddr)
+		if err != nil {
+			logger.Fatalf("Failed to start API listener: %v", err)
+		}
+
+		go func() {
+			logger.Infof("Serving API on %s", cfg.APIAddr)
+			if err := http.Serve(listener, server); err != nil {
+				collector.IncListenerErrors()
+				logger.Errorf("API listener failed: %v", err)
+			}
+		}()
+	}
+
+	// Setup event log sinks if specified
+	sinks := []struct{ path, format string }{
+		{cfg.Fail2banLog, "fail2ban"},
+		{cfg.EventLog, cfg.EventLogFormat},
+	}
+	for _, sink := range sinks {
+		if sink.path == "" {
+			continue
+		}
+		closeSink, err := startLogSink(sink.path, sink.format, broker, collector, logger)
+		if err != nil {
+			logger.Fatalf("Failed to start event log: %v", err)
+		}
+		closeSinks = append(closeSinks, closeSink)
+	}
+
 	if cfg.UIEnabled {
 		// Start TUI
-		model := ui.NewModel(scanner, cfg.Debug)
+		actions := &sourceActions{
+			whitelist:   whitelist,
+			blacklist:   blacklist,
+			fail2banLog: cfg.Fail2banLog,
+			logger:      logger,
+		}
+		// Receive events as they are published rather than polling for them
+		sub := broker.Subscribe(models.EventFilter{}, uiBufferSize, "")
+		defer sub.Close()
+
+		model := ui.NewModel(source, cfg.Debug).
+			WithEvents(sub.C).
+			WithRefreshRate(cfg.RefreshRate).
+			WithColumns(cfg.UIColumns).
+			WithTheme(theme).
+			WithKeyMap(keys).
+			WithExportDir(cfg.UIExportDir).
+			WithIncidentGap(cfg.TimeWindow).
+			WithActions(actions)
+		if whitelist != nil || blacklist != nil {
+			model = model.WithLists(actions)
+		}
 		p := tea.NewProgram(model, tea.WithAltScreen())
 
 		if _, err := p.Run(); err != nil {
@@ -119,7 +527,10 @@ func runPortScammer() {
 		// Run in headless mode
 		logger.Info("Running in headless mode. Press Ctrl+C to stop.")
 
-		// Block until interrupted
-		select {}
+		// Block until interrupted, then run the deferred shutdown
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		logger.Info("Shutting down")
 	}
 }
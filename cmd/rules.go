@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/config"
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/rules"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ruleCheckInterval is how often the rules file is checked for changes
+const ruleCheckInterval = time.Second * 5
+
+// ruleHandler evaluates the rules against events and carries out the
+// actions of those matching
+type ruleHandler struct {
+	engine    *rules.Engine
+	blacklist *iplist.List
+	alerts    *os.File // Alert file, nil when alerts are disabled
+	logger    *logrus.Logger
+}
+
+// newRuleHandler loads the rules file of the configuration
+func newRuleHandler(cfg *config.Config, blacklist *iplist.List, logger *logrus.Logger) (*ruleHandler, error) {
+	loaded, err := rules.LoadFile(cfg.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Loaded %d rules from %s", len(loaded), cfg.RulesFile)
+
+	h := &ruleHandler{
+		engine:    rules.NewEngine(loaded),
+		blacklist: blacklist,
+		logger:    logger,
+	}
+
+	if cfg.AlertsEnabled && cfg.AlertFile != "" {
+		h.alerts, err = os.OpenFile(cfg.AlertFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open alert file: %w", err)
+		}
+	}
+
+	return h, nil
+}
+
+// Close closes the alert file
+func (h *ruleHandler) Close() error {
+	if h.alerts == nil {
+		return nil
+	}
+	return h.alerts.Close()
+}
+
+// handle applies the rules matching event to it and carries out their
+// alert and block actions. It returns false when the event is suppressed.
+// Rules run on the events taken from the scanner, so they act on what is
+// published, stored and counted, but not on the scanner's own log.
+func (h *ruleHandler) handle(event *models.ScanEvent) bool {
+	result := h.engine.Evaluate(*event)
+	if len(result.Matched) == 0 {
+		return true
+	}
+	if result.Suppress {
+		h.logger.Debugf("Event %s suppressed by rules %s", event.ID, strings.Join(result.Matched, ", "))
+		return false
+	}
+
+	result.Apply(event)
+
+	if result.Alert {
+		h.logger.Warnf("Rule alert from %s on port %d: %s", event.SourceIP, event.TargetPort, strings.Join(result.Matched, ", "))
+		if h.alerts != nil {
+			line := fmt.Sprintf("%s ALERT rules=%s source=%s port=%d severity=%s\n",
+				event.Timestamp.UTC().Format(time.RFC3339), strings.Join(result.Matched, ","), event.SourceIP, event.TargetPort, event.Severity)
+			if _, err := io.WriteString(h.alerts, line); err != nil {
+				h.logger.Errorf("Failed to write alert: %v", err)
+			}
+		}
+	}
+
+	if result.Block && h.blacklist == nil {
+		h.logger.Warnf("Rules %s block %s, but the blacklist is disabled", strings.Join(result.Matched, ", "), event.SourceIP)
+	} else if result.Block {
+		added, err := h.blacklist.Add(event.SourceIP)
+		if err != nil {
+			h.logger.Errorf("Failed to block %s: %v", event.SourceIP, err)
+		} else if added {
+			h.logger.Infof("Blocked %s by rules %s", event.SourceIP, strings.Join(result.Matched, ", "))
+			if err := h.blacklist.Save(); err != nil {
+				h.logger.Errorf("Failed to save blacklist: %v", err)
+			}
+		}
+	}
+
+	return true
+}
+
+// watchRules reloads the rules file every interval when it changed, until
+// stop is closed. Rules that fail validation are logged and the previous
+// rules kept.
+func watchRules(engine *rules.Engine, path string, interval time.Duration, stop <-chan struct{}, logger *logrus.Logger) {
+	var modTime time.Time
+	if stat, err := os.Stat(path); err == nil {
+		modTime = stat.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil || stat.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = stat.ModTime()
+
+		loaded, err := rules.LoadFile(path)
+		if err != nil {
+			logger.Errorf("Failed to reload rules, keeping the previous rules: %v", err)
+			continue
+		}
+		engine.SetRules(loaded)
+		logger.Infof("Reloaded %d rules from %s", len(loaded), path)
+	}
+}
+
+var rulesFilePath string
+
+// rulesCmd groups the commands working with detection rules
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Work with detection rules",
+	Long:  `Validate and test the detection rules loaded with --rules.`,
+}
+
+// rulesTestCmd evaluates rules against sample events
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [events-file...]",
+	Short: "Validate rules and evaluate them against sample events",
+	Long: `Validate a rules file and evaluate the rules against sample events, in order.
+
+Events are read from the given files, or standard input when a file is "-",
+as a JSON array or one JSON object per line, in the format written by
+"export events --format jsonl". A sample event may carry an "expect" list of
+rule names; the command fails when the rules matching the event differ.
+
+Without event files the rules are only validated.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRulesTest(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+
+	rulesTestCmd.Flags().StringVarP(&rulesFilePath, "rules", "r", "rules.yaml", "Rules file to test")
+}
+
+// sampleEvent is an event given to rules test, with the rules it should match
+type sampleEvent struct {
+	models.ScanEvent
+	Expect *[]string `json:"expect,omitempty"`
+}
+
+// runRulesTest validates the rules and evaluates them against sample events
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	loaded, err := rules.LoadFile(rulesFilePath)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s: %d rules OK\n", rulesFilePath, len(loaded))
+	if len(args) == 0 {
+		return nil
+	}
+
+	var samples []sampleEvent
+	for _, arg := range args {
+		var read []sampleEvent
+		if arg == "-" {
+			read, err = readSampleEvents(cmd.InOrStdin())
+		} else {
+			var file *os.File
+			file, err = os.Open(arg)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", arg, err)
+			}
+			read, err = readSampleEvents(file)
+			file.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", arg, err)
+		}
+		samples = append(samples, read...)
+	}
+
+	engine := rules.NewEngine(loaded)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nTIME\tSOURCE\tPORT\tSEVERITY\tMATCHED\tACTIONS\tRESULT")
+
+	failed := 0
+	for _, sample := range samples {
+		event := sample.ScanEvent
+		if event.Timestamp.IsZero() {
+			event.Timestamp = time.Now()
+		}
+
+		result := engine.Evaluate(event)
+		result.Apply(&event)
+
+		status := ""
+		if sample.Expect != nil {
+			status = "PASS"
+			if !sameRules(*sample.Expect, result.Matched) {
+				status = fmt.Sprintf("FAIL (expected %s)", listOrDash(*sample.Expect))
+				failed++
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			event.Timestamp.Format(time.RFC3339), event.SourceIP, event.TargetPort, event.Severity,
+			listOrDash(result.Matched), describeActions(result), status)
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sample events did not match the expected rules", failed, len(samples))
+	}
+	return nil
+}
+
+// readSampleEvents reads events as a JSON array or one JSON object per line
+func readSampleEvents(r io.Reader) ([]sampleEvent, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []sampleEvent
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &samples)
+		return samples, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var sample sampleEvent
+		if err := json.Unmarshal(line, &sample); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("no events")
+	}
+	return samples, nil
+}
+
+// sameRules reports whether two lists name the same rules
+func sameRules(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// describeActions summarises the actions of a rules result
+func describeActions(result rules.Result) string {
+	var actions []string
+	if result.Suppress {
+		actions = append(actions, "suppress")
+	}
+	if result.Severity != nil {
+		actions = append(actions, "severity="+result.Severity.String())
+	}
+	for _, tag := range result.Tags {
+		actions = append(actions, "tag="+tag)
+	}
+	if result.Alert {
+		actions = append(actions, "alert")
+	}
+	if result.Block {
+		actions = append(actions, "block")
+	}
+	return listOrDash(actions)
+}
+
+// listOrDash joins a list with commas, or returns a dash when it is empty
+func listOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
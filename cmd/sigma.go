@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/sigma"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadSigmaRules loads the Sigma rules in the given files and directories
+func loadSigmaRules(paths []string, logger *logrus.Logger) ([]*sigma.Rule, error) {
+	loaded, skipped, err := sigma.LoadPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range skipped {
+		logger.Warnf("Skipped Sigma rule: %v", err)
+	}
+	for _, rule := range loaded {
+		logger.Debugf("Loaded Sigma rule %q (%s, level %s) from %s", rule.Title, rule.LogSource.Category, rule.Level, rule.Path)
+	}
+	logger.Infof("Loaded %d Sigma rules", len(loaded))
+	return loaded, nil
+}
+
+// matchSigma tags event with the Sigma rules it matches and raises its
+// severity to their level
+func matchSigma(rules []*sigma.Rule, event *models.ScanEvent, logger *logrus.Logger) {
+	for _, rule := range sigma.Apply(rules, event) {
+		logger.Infof("Sigma rule %q matched %s on port %d", rule.Title, event.SourceIP, event.TargetPort)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/metrics"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/stream"
+
+	"github.com/sirupsen/logrus"
+)
+
+// startLogSink appends every event published by the broker to the file at
+// path, one line per event in the given format. The returned function
+// ends the subscription, waits for the buffered events to be written and
+// closes the file.
+func startLogSink(path, format string, broker *stream.Broker, collector *metrics.Metrics, logger *logrus.Logger) (func() error, error) {
+	formatter, err := export.GetLine(format)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	sub := broker.Subscribe(models.EventFilter{}, sinkBufferSize, "")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var dropped uint64
+		for event := range sub.C {
+			// A slow file loses the oldest events rather than holding up others
+			if total := sub.Dropped(); total > dropped {
+				logger.Warnf("Dropped %d events not written to %s in time", total-dropped, path)
+				dropped = total
+			}
+
+			line, err := formatter.FormatEvent(event)
+			if err == nil {
+				_, err = fmt.Fprintln(file, line)
+			}
+			if err != nil {
+				collector.IncAlertSinkFailures()
+				logger.Errorf("Failed to write event to %s: %v", path, err)
+			}
+		}
+	}()
+
+	return func() error {
+		sub.Close()
+		<-done
+		return file.Close()
+	}, nil
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/stream"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Source provides the events and statistics served by the API
+type Source interface {
+	GetEvents() []models.ScanEvent
+	GetStats() models.ScanStats
+}
+
+// Annotator is a Source that stores the acknowledgement and notes of
+// events, enabling POST /annotations
+type Annotator interface {
+	Annotate(annotation models.Annotation) error
+}
+
+// Options holds the API server settings
+type Options struct {
+	Token       string         // Bearer token required on every request, empty disables auth
+	Whitelist   *iplist.List   // Allow list managed through /lists/allow, nil when whitelisting is disabled
+	Blacklist   *iplist.List   // Deny list managed through /lists/deny, nil when blacklisting is disabled
+	IncidentGap time.Duration  // Maximum gap between events grouped into one incident
+	Broker      *stream.Broker // Event broker for /events/stream and /events/ws, nil disables streaming
+}
+
+// Server serves the local HTTP/JSON API
+type Server struct {
+	source Source
+	opts   Options
+	mux    *http.ServeMux
+}
+
+// NewServer creates a new API server for the given source
+func NewServer(source Source, opts Options) *Server {
+	if opts.IncidentGap <= 0 {
+		opts.IncidentGap = time.Minute * 5
+	}
+
+	s := &Server{
+		source: source,
+		opts:   opts,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/events", s.handleEvents)
+	s.mux.HandleFunc("/stats", s.handleStats)
+	s.mux.HandleFunc("/incidents", s.handleIncidents)
+	s.mux.HandleFunc("/lists/allow", s.handleList("allow", opts.Whitelist))
+	s.mux.HandleFunc("/lists/deny", s.handleList("deny", opts.Blacklist))
+	if annotator, ok := source.(Annotator); ok {
+		s.mux.HandleFunc("/annotations", s.handleAnnotations(annotator))
+	}
+
+	if opts.Broker != nil {
+		s.mux.HandleFunc("/events/stream", s.handleSSE)
+		s.mux.HandleFunc("/events/ws", s.handleWebSocket)
+	}
+
+	return s
+}
+
+// Handle registers an additional handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP checks authentication and dispatches the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Token != "" && !validToken(r, s.opts.Token) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="portscammer"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Listen creates a listener for addr. Addresses prefixed with "unix:" are
+// bound as unix sockets, replacing a stale socket file; everything else is
+// treated as a TCP host:port.
+func Listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale socket %q: %w", path, err)
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// eventsResponse is the response body of GET /events
+type eventsResponse struct {
+	Events []models.ScanEvent `json:"events"`
+	Total  int                `json:"total"`
+	Offset int                `json:"offset"`
+	Limit  int                `json:"limit"`
+}
+
+// handleEvents serves GET /events, newest events first
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := models.ParseEventFilter(query, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, err := intParam(query.Get("limit"), defaultLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", query.Get("limit")))
+		return
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	offset, err := intParam(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid offset %q", query.Get("offset")))
+		return
+	}
+
+	events := filter.Apply(s.source.GetEvents())
+	total := len(events)
+
+	page := make([]models.ScanEvent, 0, limit)
+	for i := total - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, events[i])
+	}
+
+	writeJSON(w, http.StatusOK, eventsResponse{
+		Events: page,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// handleStats serves GET /stats
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.source.GetStats())
+}
+
+// handleIncidents serves GET /incidents, optionally with a custom gap
+func (s *Server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	gap := s.opts.IncidentGap
+	if value := r.URL.Query().Get("gap"); value != "" {
+		d, err := utils.ParseDuration(value)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid gap %q", value))
+			return
+		}
+		gap = d
+	}
+
+	incidents := models.GroupIncidents(s.source.GetEvents(), gap)
+	if incidents == nil {
+		incidents = []models.Incident{}
+	}
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+// handleAnnotations serves POST /annotations, which acknowledges events or
+// attaches a note to them
+func (s *Server) handleAnnotations(annotator Annotator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethods(w, r, http.MethodPost) {
+			return
+		}
+
+		var annotation models.Annotation
+		if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		if len(annotation.EventIDs) == 0 {
+			writeError(w, http.StatusBadRequest, errors.New("no event_ids given"))
+			return
+		}
+		if annotation.Acknowledged == nil && annotation.Note == nil {
+			writeError(w, http.StatusBadRequest, errors.New("neither acknowledged nor note given"))
+			return
+		}
+		annotation.Time = time.Now()
+
+		if err := annotator.Annotate(annotation); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, annotation)
+	}
+}
+
+// listEntry is the request body of POST /lists/{allow,deny}
+type listEntry struct {
+	Entry string `json:"entry"`
+}
+
+// handleList serves GET, POST and DELETE for an allow or deny list. A
+// disabled list is a conflict with the configuration, as edits to it would
+// have no effect.
+func (s *Server) handleList(name string, list *iplist.List) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethods(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) {
+			return
+		}
+		if list == nil {
+			writeError(w, http.StatusConflict, fmt.Errorf("the %s list is disabled", name))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, list.Entries())
+
+		case http.MethodPost:
+			var body listEntry
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+				return
+			}
+			added, err := list.Add(body.Entry)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := list.Save(); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			status := http.StatusOK
+			if added {
+				status = http.StatusCreated
+			}
+			writeJSON(w, status, body)
+
+		case http.MethodDelete:
+			entry := r.URL.Query().Get("entry")
+			removed, err := list.Remove(entry)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if !removed {
+				writeError(w, http.StatusNotFound, fmt.Errorf("entry %q not found", entry))
+				return
+			}
+			if err := list.Save(); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// validToken checks the bearer token of the request in constant time. As
+// browsers cannot set headers on EventSource and WebSocket requests, the
+// token is also accepted in the access_token query parameter.
+func validToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		given = r.URL.Query().Get("access_token")
+	}
+	if given == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// allowMethods writes a 405 response and returns false if the request
+// method is not one of methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// intParam parses an integer query parameter, returning def if it is empty
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
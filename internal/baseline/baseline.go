@@ -0,0 +1,314 @@
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// ScanType is the scan type of anomaly events
+const ScanType = "anomaly"
+
+// Defaults for the zero values of Options
+const (
+	DefaultAlpha      = 0.1
+	DefaultThreshold  = 3.0
+	DefaultMinSamples = 10
+)
+
+// maxGap is the most empty buckets added when no events arrived for a while
+const maxGap = 1000
+
+// minPortMean is the hourly mean below which a port is forgotten
+const minPortMean = 0.001
+
+// Options configure a Detector
+type Options struct {
+	Alpha      float64       // Weight of the newest bucket in the moving averages
+	Threshold  float64       // Score from which a bucket is anomalous
+	MinSamples int           // Buckets needed before a metric is scored
+	Learning   time.Duration // Period after the first event during which no anomalies are raised
+}
+
+// State is the persisted baseline: the moving averages and the buckets in
+// progress
+type State struct {
+	Started time.Time `json:"started"` // Time of the first observed event
+
+	Minute      time.Time    `json:"minute"`       // Start of the current minute bucket
+	Connections EWMA         `json:"connections"`  // Events per minute
+	MinuteCount int          `json:"minute_count"` // Events in the current minute
+	Hour        time.Time    `json:"hour"`         // Start of the current hour bucket
+	Sources     EWMA         `json:"sources"`      // Distinct sources per hour
+	Ports       map[int]EWMA `json:"ports"`        // Events per hour by target port
+
+	HourSources map[string]bool `json:"hour_sources"` // Sources seen in the current hour
+	HourPorts   map[int]int     `json:"hour_ports"`   // Events in the current hour by target port
+	Flagged     map[string]bool `json:"flagged"`      // Metrics already reported in their current bucket
+}
+
+// Detector learns a baseline of event rates and reports events that
+// deviate from it
+type Detector struct {
+	mu    sync.Mutex
+	opts  Options
+	state State
+}
+
+// New creates a detector with an empty baseline
+func New(opts Options) *Detector {
+	if opts.Alpha <= 0 || opts.Alpha > 1 {
+		opts.Alpha = DefaultAlpha
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = DefaultMinSamples
+	}
+
+	d := &Detector{opts: opts}
+	d.state.init()
+	return d
+}
+
+// Load creates a detector with the baseline saved at path. A missing file
+// gives an empty baseline.
+func Load(path string, opts Options) (*Detector, error) {
+	d := New(opts)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.state); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	d.state.init()
+	return d, nil
+}
+
+// Save writes the baseline to path, replacing it atomically
+func (d *Detector) Save(path string) error {
+	d.mu.Lock()
+	data, err := json.MarshalIndent(d.state, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return nil
+}
+
+// State returns a copy of the baseline
+func (d *Detector) State() State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := d.state
+	state.Ports = make(map[int]EWMA, len(d.state.Ports))
+	for port, e := range d.state.Ports {
+		state.Ports[port] = e
+	}
+	state.HourSources = make(map[string]bool, len(d.state.HourSources))
+	for ip := range d.state.HourSources {
+		state.HourSources[ip] = true
+	}
+	state.HourPorts = make(map[int]int, len(d.state.HourPorts))
+	for port, n := range d.state.HourPorts {
+		state.HourPorts[port] = n
+	}
+	state.Flagged = make(map[string]bool, len(d.state.Flagged))
+	for metric := range d.state.Flagged {
+		state.Flagged[metric] = true
+	}
+	return state
+}
+
+// Learning reports whether the detector is still in its learning period at now
+func (d *Detector) Learning(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.learning(now)
+}
+
+func (d *Detector) learning(now time.Time) bool {
+	return d.state.Started.IsZero() || now.Before(d.state.Started.Add(d.opts.Learning))
+}
+
+// Observe adds event to the baseline and returns an anomaly event for each
+// metric the event pushes beyond the threshold. A metric is reported at
+// most once per bucket, and never during the learning period.
+func (d *Detector) Observe(event models.ScanEvent) []models.ScanEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := event.Timestamp
+	if d.state.Started.IsZero() {
+		d.state.Started = now
+	}
+	d.roll(now)
+
+	s := &d.state
+	s.MinuteCount++
+	s.HourSources[event.SourceIP] = true
+	s.HourPorts[event.TargetPort]++
+
+	if d.learning(now) {
+		return nil
+	}
+
+	var anomalies []models.ScanEvent
+	check := func(metric string, e EWMA, value float64, describe string) {
+		if s.Flagged[metric] || e.Samples < d.opts.MinSamples {
+			return
+		}
+		score := e.Score(value)
+		if score < d.opts.Threshold {
+			return
+		}
+		s.Flagged[metric] = true
+		description := fmt.Sprintf("%s %.0f above baseline %.1f (score %.1f)", describe, value, e.Mean, score)
+		anomalies = append(anomalies, d.anomaly(event, score, description))
+	}
+
+	check("connections", s.Connections, float64(s.MinuteCount), "Events per minute")
+	check("sources", s.Sources, float64(len(s.HourSources)), "Distinct sources per hour")
+	port := event.TargetPort
+	ports, ok := s.Ports[port]
+	if !ok {
+		// A port not in the baseline had no events in any hour learned
+		ports.Samples = s.Sources.Samples
+	}
+	check("port:"+strconv.Itoa(port), ports, float64(s.HourPorts[port]),
+		fmt.Sprintf("Events per hour on port %d", port))
+
+	return anomalies
+}
+
+// anomaly creates an anomaly event triggered by event
+func (d *Detector) anomaly(event models.ScanEvent, score float64, description string) models.ScanEvent {
+	anomaly := models.NewScanEvent(event.SourceIP, event.SourcePort, event.TargetPort, event.Protocol, ScanType, description)
+	anomaly.Timestamp = event.Timestamp
+	anomaly.Severity = Severity(score, d.opts.Threshold)
+	anomaly.AnomalyScore = score
+	return *anomaly
+}
+
+// Severity maps an anomaly score onto a severity: medium from the
+// threshold, high from twice and critical from four times the threshold
+func Severity(score, threshold float64) models.Severity {
+	switch {
+	case score >= threshold*4:
+		return models.SeverityCritical
+	case score >= threshold*2:
+		return models.SeverityHigh
+	case score >= threshold:
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
+
+// roll closes the buckets that ended before now, adding them and any empty
+// buckets since to the moving averages
+func (d *Detector) roll(now time.Time) {
+	s := &d.state
+	alpha := d.opts.Alpha
+
+	minute := now.Truncate(time.Minute)
+	if s.Minute.IsZero() {
+		s.Minute = minute
+	} else if minute.After(s.Minute) {
+		s.Connections.Update(float64(s.MinuteCount), alpha)
+		for i := 1; i < int(minute.Sub(s.Minute)/time.Minute) && i <= maxGap; i++ {
+			s.Connections.Update(0, alpha)
+		}
+		s.Minute = minute
+		s.MinuteCount = 0
+		delete(s.Flagged, "connections")
+	}
+
+	hour := now.Truncate(time.Hour)
+	if s.Hour.IsZero() {
+		s.Hour = hour
+	} else if hour.After(s.Hour) {
+		gaps := int(hour.Sub(s.Hour)/time.Hour) - 1
+		if gaps > maxGap {
+			gaps = maxGap
+		}
+
+		s.Sources.Update(float64(len(s.HourSources)), alpha)
+		// A new port starts from the empty hours learned before it
+		for port := range s.HourPorts {
+			if _, ok := s.Ports[port]; !ok {
+				s.Ports[port] = EWMA{Samples: s.Sources.Samples - 1}
+			}
+		}
+		for port, e := range s.Ports {
+			e.Update(float64(s.HourPorts[port]), alpha)
+			for i := 0; i < gaps; i++ {
+				e.Update(0, alpha)
+			}
+			if e.Mean < minPortMean {
+				delete(s.Ports, port)
+				continue
+			}
+			s.Ports[port] = e
+		}
+		for i := 0; i < gaps; i++ {
+			s.Sources.Update(0, alpha)
+		}
+
+		s.Hour = hour
+		s.HourSources = make(map[string]bool)
+		s.HourPorts = make(map[int]int)
+		for metric := range s.Flagged {
+			if metric != "connections" {
+				delete(s.Flagged, metric)
+			}
+		}
+	}
+}
+
+// init creates the maps of a new or loaded state
+func (s *State) init() {
+	if s.Ports == nil {
+		s.Ports = make(map[int]EWMA)
+	}
+	if s.HourSources == nil {
+		s.HourSources = make(map[string]bool)
+	}
+	if s.HourPorts == nil {
+		s.HourPorts = make(map[int]int)
+	}
+	if s.Flagged == nil {
+		s.Flagged = make(map[string]bool)
+	}
+}
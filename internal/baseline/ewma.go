@@ -0,0 +1,34 @@
+package baseline
+
+import "math"
+
+// EWMA is an exponentially weighted moving average and variance of a count
+type EWMA struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Samples  int     `json:"samples"`
+}
+
+// Update adds a sample, weighting it by alpha against the history
+func (e *EWMA) Update(x, alpha float64) {
+	if e.Samples == 0 {
+		e.Mean = x
+		e.Variance = 0
+		e.Samples = 1
+		return
+	}
+
+	diff := x - e.Mean
+	increment := alpha * diff
+	e.Mean += increment
+	e.Variance = (1 - alpha) * (e.Variance + diff*increment)
+	e.Samples++
+}
+
+// Score returns how many standard deviations x lies above the mean. The
+// deviation is at least that of a Poisson count with the same mean, and at
+// least one, so a flat history does not turn every change into an anomaly.
+func (e EWMA) Score(x float64) float64 {
+	deviation := math.Max(math.Sqrt(e.Variance), math.Max(math.Sqrt(e.Mean), 1))
+	return (x - e.Mean) / deviation
+}
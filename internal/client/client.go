@@ -0,0 +1,370 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+const (
+	// DefaultAddr is the address of the API unless configured otherwise
+	DefaultAddr = "localhost:8090"
+
+	// MaxEvents is the number of events loaded from the daemon
+	MaxEvents = 10000
+
+	// pageSize is the number of events requested at a time, the most the
+	// API returns
+	pageSize = 1000
+
+	// requestTimeout bounds every request except the event stream
+	requestTimeout = time.Second * 5
+
+	// maxBackoff is the longest wait before reconnecting the event stream
+	maxBackoff = time.Second * 30
+)
+
+// Options holds the client settings
+type Options struct {
+	Socket string // Path of the unix socket of the API, takes precedence over Addr
+	Addr   string // host:port or http(s) URL of the API
+	Token  string // Bearer token required by the API, if any
+}
+
+// Client reads the events and statistics of a running daemon through its
+// local API. It provides the same data as the scanner does to a TUI in the
+// daemon's process, so the TUI can be attached to a headless daemon.
+// Failed requests return the last data received, and Err reports the
+// failure until a request succeeds again.
+type Client struct {
+	base  string
+	token string
+	http  *http.Client
+
+	mu      sync.Mutex
+	events  []models.ScanEvent
+	stats   models.ScanStats
+	entries map[string][]string // Entries of the lists by name
+	err     error
+}
+
+// New creates a client for the API on opts.Socket or opts.Addr
+func New(opts Options) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	base := opts.Addr
+
+	switch {
+	case opts.Socket != "":
+		socket := opts.Socket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		// The host is ignored when dialing the socket
+		base = "http://portscammer"
+	case base == "":
+		base = "http://" + DefaultAddr
+	case !strings.Contains(base, "://"):
+		base = "http://" + base
+	}
+
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid API address %q", opts.Addr)
+	}
+
+	return &Client{
+		base:  strings.TrimSuffix(u.String(), "/"),
+		token: opts.Token,
+		http:  &http.Client{Transport: transport},
+	}, nil
+}
+
+// Ping checks that the API can be reached with the configured token
+func (c *Client) Ping() error {
+	var stats models.ScanStats
+	return c.do(http.MethodGet, "/stats", nil, &stats)
+}
+
+// Err returns the error of the last failed request, or nil once a request
+// has succeeded since
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// setErr records the outcome of a request
+func (c *Client) setErr(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+}
+
+// GetEvents returns up to MaxEvents of the daemon's latest events, oldest
+// first
+func (c *Client) GetEvents() []models.ScanEvent {
+	var events []models.ScanEvent
+	seen := make(map[string]bool)
+
+	// Pages are newest first, and events arriving meanwhile shift them, so
+	// an event may be returned twice
+	for offset := 0; offset < MaxEvents; offset += pageSize {
+		var page struct {
+			Events []models.ScanEvent `json:"events"`
+			Total  int                `json:"total"`
+		}
+		query := url.Values{"limit": {strconv.Itoa(pageSize)}, "offset": {strconv.Itoa(offset)}}
+		if err := c.do(http.MethodGet, "/events?"+query.Encode(), nil, &page); err != nil {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return append([]models.ScanEvent(nil), c.events...)
+		}
+		for _, event := range page.Events {
+			if !seen[event.ID] {
+				seen[event.ID] = true
+				events = append(events, event)
+			}
+		}
+		if offset+pageSize >= page.Total {
+			break
+		}
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	c.mu.Lock()
+	c.events = events
+	c.mu.Unlock()
+	return append([]models.ScanEvent(nil), events...)
+}
+
+// GetStats returns the daemon's statistics
+func (c *Client) GetStats() models.ScanStats {
+	var stats models.ScanStats
+	err := c.do(http.MethodGet, "/stats", nil, &stats)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		return c.stats
+	}
+	c.stats = stats
+	return stats
+}
+
+// Annotate acknowledges events or attaches a note to them on the daemon
+func (c *Client) Annotate(annotation models.Annotation) error {
+	return c.do(http.MethodPost, "/annotations", annotation, nil)
+}
+
+// Entries returns the entries of the daemon's allow or deny list
+func (c *Client) Entries(list string) []string {
+	var entries []string
+	err := c.do(http.MethodGet, "/lists/"+list, nil, &entries)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		return append([]string(nil), c.entries[list]...)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string][]string)
+	}
+	c.entries[list] = entries
+	return append([]string(nil), entries...)
+}
+
+// Add adds entry to the daemon's allow or deny list
+func (c *Client) Add(list, entry string) error {
+	return c.do(http.MethodPost, "/lists/"+list, map[string]string{"entry": entry}, nil)
+}
+
+// Remove removes entry from the daemon's allow or deny list
+func (c *Client) Remove(list, entry string) error {
+	return c.do(http.MethodDelete, "/lists/"+list+"?"+url.Values{"entry": {entry}}.Encode(), nil, nil)
+}
+
+// Whitelist adds ip to the daemon's allow list
+func (c *Client) Whitelist(ip string) error {
+	return c.Add("allow", ip)
+}
+
+// Blacklist adds ip to the daemon's deny list
+func (c *Client) Blacklist(ip string) error {
+	return c.Add("deny", ip)
+}
+
+// Block is not offered by the API, as it writes to the daemon's fail2ban
+// log. Blacklisting keeps the source out without the ban.
+func (c *Client) Block(ip string) error {
+	return errors.New("blocking is not available when attached, blacklist the source instead")
+}
+
+// do sends a request with body encoded as JSON, if any, and decodes the
+// response into result, if any
+func (c *Client) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := c.newRequest(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to reach the daemon: %w", err)
+		c.setErr(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := responseError(resp); err != nil {
+		c.setErr(err)
+		return err
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			err = fmt.Errorf("failed to decode response of %s: %w", path, err)
+			c.setErr(err)
+			return err
+		}
+	}
+	c.setErr(nil)
+	return nil
+}
+
+// newRequest creates a request to the API carrying the token
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// responseError returns the error described by a failed response
+func responseError(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		return fmt.Errorf("daemon returned %s: %s", resp.Status, body.Error)
+	}
+	return fmt.Errorf("daemon returned %s", resp.Status)
+}
+
+// Subscribe streams the daemon's events as they are published until ctx is
+// done, when the channel is closed. A dropped stream is reconnected with
+// backoff, resuming after the last event received.
+func (c *Client) Subscribe(ctx context.Context) <-chan models.ScanEvent {
+	ch := make(chan models.ScanEvent, pageSize)
+	go func() {
+		defer close(ch)
+
+		var lastID string
+		backoff := time.Second
+		for {
+			received, err := c.stream(ctx, ch, &lastID)
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				backoff = time.Second
+			}
+			if err != nil {
+				c.setErr(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}()
+	return ch
+}
+
+// stream reads events from /events/stream into ch until the stream ends,
+// keeping lastID up to date. It reports whether any event was received.
+func (c *Client) stream(ctx context.Context, ch chan<- models.ScanEvent, lastID *string) (bool, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/events/stream", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach the daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := responseError(resp); err != nil {
+		return false, err
+	}
+	c.setErr(nil)
+
+	// Events are separated by a blank line, their JSON is on a data line
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event models.ScanEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return received, nil
+		}
+		*lastID = event.ID
+		received = true
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return received, fmt.Errorf("event stream failed: %w", err)
+	}
+	return received, errors.New("event stream closed by the daemon")
+}
@@ -26,34 +26,119 @@ type Config struct {
 
 	// UI configuration
 	UIEnabled     bool          `json:"ui_enabled"`      // Enable terminal UI
-	RefreshRate   time.Duration `json:"refresh_rate"`    // UI refresh rate
+	RefreshRate   time.Duration `json:"refresh_rate"`    // Minimum time between TUI redraws
 	MaxLogEntries int           `json:"max_log_entries"` // Maximum log entries to display
+	UIColumns     []string      `json:"ui_columns"`      // Columns of the event table, in order
+	UITheme       string        `json:"ui_theme"`        // Color theme, e.g. dark, light or high-contrast
+	UIKeys        []string      `json:"ui_keys"`         // Key remappings as action=keys
+	UIExportDir   string        `json:"ui_export_dir"`   // Directory the TUI exports the event table to
 
 	// Alert configuration
-	AlertsEnabled bool   `json:"alerts_enabled"` // Enable alerts
-	AlertFile     string `json:"alert_file"`     // Path to alert file
+	AlertsEnabled  bool   `json:"alerts_enabled"`   // Enable alerts
+	AlertFile      string `json:"alert_file"`       // Path to alert file
+	Fail2banLog    string `json:"fail2ban_log"`     // Path to fail2ban compatible log file
+	EventLog       string `json:"event_log"`        // Path to log file receiving every event
+	EventLogFormat string `json:"event_log_format"` // Format of the event log, e.g. jsonl, cef, leef, ecs, ocsf
+
+	// Metrics configuration
+	MetricsAddr string `json:"metrics_addr"` // Address for the Prometheus metrics listener
+
+	// API configuration
+	APIEnabled bool   `json:"api_enabled"` // Enable the local HTTP/JSON API
+	APIAddr    string `json:"api_addr"`    // API listen address, host:port or unix:/path
+	APIToken   string `json:"api_token"`   // Bearer token required by the API, empty disables auth
+
+	// Storage configuration
+	StoreDir         string        `json:"store_dir"`          // Directory of the event store, empty disables persistence
+	RetentionMaxAge  time.Duration `json:"retention_max_age"`  // Stored data older than this is removed
+	RetentionMaxSize int64         `json:"retention_max_size"` // Oldest stored data is removed beyond this size in bytes
+	SnapshotInterval time.Duration `json:"snapshot_interval"`  // Interval between statistics snapshots
+	HistoryWindow    time.Duration `json:"history_window"`     // Stored events from this window are loaded at startup
+
+	// Report configuration
+	ReportDir      string        `json:"report_dir"`      // Directory for scheduled reports, empty disables them
+	ReportFormat   string        `json:"report_format"`   // Format of scheduled reports, md or html
+	ReportInterval time.Duration `json:"report_interval"` // Interval between scheduled reports
+
+	// Threat-intel configuration
+	Feeds            []string      `json:"feeds"`              // Local threat-intel feeds as name[:format]=path
+	FeedRefresh      time.Duration `json:"feed_refresh"`       // Interval between full reloads of the feeds
+	FeedSeverityBump int           `json:"feed_severity_bump"` // Severity levels added to events from listed sources
+
+	// Enrichment configuration
+	GeoIPDBs       []string      `json:"geoip_dbs"`       // GeoLite2-City/ASN format mmdb files used for enrichment
+	ReverseDNS     bool          `json:"reverse_dns"`     // Enable reverse DNS lookups of sources
+	DNSServer      string        `json:"dns_server"`      // DNS server for reverse lookups as host:port, empty uses the system resolver
+	DNSTimeout     time.Duration `json:"dns_timeout"`     // Timeout of a single reverse lookup
+	DNSConcurrency int           `json:"dns_concurrency"` // Maximum number of reverse lookups in flight
+	DNSCacheSize   int           `json:"dns_cache_size"`  // Maximum number of cached reverse lookups
+	DNSCacheTTL    time.Duration `json:"dns_cache_ttl"`   // How long a reverse lookup is cached
+
+	// Rules configuration
+	RulesFile  string   `json:"rules_file"`  // YAML file with detection rules, empty disables rules
+	SigmaRules []string `json:"sigma_rules"` // Sigma rule files or directories matched against events
+
+	// Baseline configuration
+	Baseline          bool          `json:"baseline"`           // Learn a baseline of event rates and report anomalies
+	BaselineFile      string        `json:"baseline_file"`      // File the baseline is persisted in
+	BaselineThreshold float64       `json:"baseline_threshold"` // Score from which a deviation is an anomaly
+	BaselineLearning  time.Duration `json:"baseline_learning"`  // Period after the first event without anomalies
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Port:             8080,
-		Host:             "localhost",
-		Protocol:         "tcp",
-		LogFile:          "portscammer.log",
-		LogLevel:         "info",
-		Debug:            false, // Debug disabled by default
-		ScanThreshold:    1,
-		TimeWindow:       time.Minute * 5,
-		BlacklistEnabled: false,
-		WhitelistEnabled: false,
-		BlacklistFile:    "blacklist.txt",
-		WhitelistFile:    "whitelist.txt",
-		UIEnabled:        true,
-		RefreshRate:      time.Second * 2,
-		MaxLogEntries:    100,
-		AlertsEnabled:    true,
-		AlertFile:        "alerts.log",
+		Port:              8080,
+		Host:              "localhost",
+		Protocol:          "tcp",
+		LogFile:           "portscammer.log",
+		LogLevel:          "info",
+		Debug:             false, // Debug disabled by default
+		ScanThreshold:     1,
+		TimeWindow:        time.Minute * 5,
+		BlacklistEnabled:  false,
+		WhitelistEnabled:  false,
+		BlacklistFile:     "blacklist.txt",
+		WhitelistFile:     "whitelist.txt",
+		UIEnabled:         true,
+		RefreshRate:       time.Second * 2,
+		MaxLogEntries:     100,
+		UIColumns:         []string{"time", "source", "country", "port", "type", "severity", "ack", "description"},
+		UITheme:           "dark",
+		UIExportDir:       ".",
+		AlertsEnabled:     true,
+		AlertFile:         "alerts.log",
+		Fail2banLog:       "", // fail2ban log disabled by default
+		EventLog:          "", // Event log disabled by default
+		EventLogFormat:    "jsonl",
+		MetricsAddr:       "", // Metrics listener disabled by default
+		APIEnabled:        false,
+		APIAddr:           "localhost:8090",
+		APIToken:          "",
+		StoreDir:          "", // Persistence disabled by default
+		RetentionMaxAge:   time.Hour * 24 * 30,
+		RetentionMaxSize:  1 << 30,
+		SnapshotInterval:  time.Minute * 5,
+		HistoryWindow:     time.Hour * 24,
+		ReportDir:         "", // Scheduled reports disabled by default
+		ReportFormat:      "html",
+		ReportInterval:    time.Hour * 24,
+		Feeds:             nil, // No threat-intel feeds by default
+		FeedRefresh:       time.Hour,
+		FeedSeverityBump:  1,
+		GeoIPDBs:          nil, // GeoIP enrichment disabled by default
+		ReverseDNS:        false,
+		DNSServer:         "",
+		DNSTimeout:        time.Second * 2,
+		DNSConcurrency:    4,
+		DNSCacheSize:      4096,
+		DNSCacheTTL:       time.Hour,
+		RulesFile:         "",  // No detection rules by default
+		SigmaRules:        nil, // No Sigma rules by default
+		Baseline:          false,
+		BaselineFile:      "baseline.json",
+		BaselineThreshold: 3,
+		BaselineLearning:  time.Hour * 24,
 	}
 }
 
@@ -71,5 +156,37 @@ func (c *Config) Validate() error {
 	if c.MaxLogEntries <= 0 {
 		return ErrInvalidMaxLogEntries
 	}
+	if c.RefreshRate <= 0 {
+		return ErrInvalidRefreshRate
+	}
+	if c.RetentionMaxAge < 0 || c.RetentionMaxSize < 0 {
+		return ErrInvalidRetention
+	}
+	if c.StoreDir != "" && c.SnapshotInterval <= 0 {
+		return ErrInvalidSnapshotInterval
+	}
+	if c.ReportDir != "" {
+		if c.StoreDir == "" {
+			return ErrReportsRequireStore
+		}
+		if c.ReportInterval <= 0 {
+			return ErrInvalidReportInterval
+		}
+		if c.ReportFormat != "md" && c.ReportFormat != "html" {
+			return ErrInvalidReportFormat
+		}
+	}
+	if len(c.Feeds) > 0 && c.FeedRefresh <= 0 {
+		return ErrInvalidFeedRefresh
+	}
+	if c.FeedSeverityBump < 0 {
+		return ErrInvalidSeverityBump
+	}
+	if c.ReverseDNS && (c.DNSTimeout <= 0 || c.DNSConcurrency <= 0 || c.DNSCacheSize <= 0 || c.DNSCacheTTL <= 0) {
+		return ErrInvalidReverseDNS
+	}
+	if c.Baseline && (c.BaselineFile == "" || c.BaselineThreshold <= 0 || c.BaselineLearning < 0) {
+		return ErrInvalidBaseline
+	}
 	return nil
 }
@@ -4,8 +4,18 @@ import "errors"
 
 // Configuration validation errors
 var (
-	ErrInvalidPort          = errors.New("invalid port: must be between 1 and 65535")
-	ErrInvalidThreshold     = errors.New("invalid scan threshold: must be greater than 0")
-	ErrInvalidTimeWindow    = errors.New("invalid time window: must be greater than 0")
-	ErrInvalidMaxLogEntries = errors.New("invalid max log entries: must be greater than 0")
+	ErrInvalidPort             = errors.New("invalid port: must be between 1 and 65535")
+	ErrInvalidThreshold        = errors.New("invalid scan threshold: must be greater than 0")
+	ErrInvalidTimeWindow       = errors.New("invalid time window: must be greater than 0")
+	ErrInvalidMaxLogEntries    = errors.New("invalid max log entries: must be greater than 0")
+	ErrInvalidRefreshRate      = errors.New("invalid refresh rate: must be greater than 0")
+	ErrInvalidRetention        = errors.New("invalid retention: must not be negative")
+	ErrInvalidSnapshotInterval = errors.New("invalid snapshot interval: must be greater than 0")
+	ErrReportsRequireStore     = errors.New("scheduled reports require a store directory")
+	ErrInvalidReportInterval   = errors.New("invalid report interval: must be greater than 0")
+	ErrInvalidReportFormat     = errors.New("invalid report format: must be md or html")
+	ErrInvalidFeedRefresh      = errors.New("invalid feed refresh interval: must be greater than 0")
+	ErrInvalidSeverityBump     = errors.New("invalid severity bump: must not be negative")
+	ErrInvalidReverseDNS       = errors.New("invalid reverse DNS settings: timeout, concurrency, cache size and TTL must be greater than 0")
+	ErrInvalidBaseline         = errors.New("invalid baseline settings: file is required, threshold must be greater than 0 and learning period must not be negative")
 )
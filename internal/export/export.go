@@ -0,0 +1,191 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Formatter writes a set of scan events in a specific format
+type Formatter interface {
+	FormatEvents(w io.Writer, events []models.ScanEvent) error
+}
+
+// FormatterFunc adapts a function to the Formatter interface
+type FormatterFunc func(w io.Writer, events []models.ScanEvent) error
+
+// FormatEvents calls f(w, events)
+func (f FormatterFunc) FormatEvents(w io.Writer, events []models.ScanEvent) error {
+	return f(w, events)
+}
+
+// formatters holds the registered formatters by name
+var formatters = map[string]Formatter{
+	"table":    FormatterFunc(writeEventsTable),
+	"json":     FormatterFunc(writeEventsJSON),
+	"csv":      FormatterFunc(writeEventsCSV),
+	"markdown": FormatterFunc(writeEventsMarkdown),
+}
+
+// Get returns the formatter registered under name
+func Get(name string) (Formatter, error) {
+	f, ok := formatters[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q: must be one of %s", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names returns the names of all registered formatters in sorted order
+func Names() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eventColumns are the column headers used by the table and CSV formats
+var eventColumns = []string{"Time", "Source IP", "Source Port", "Port", "Protocol", "Type", "Severity", "Description", "Tags", "Hostname", "Acknowledged", "Note"}
+
+// eventRow returns the column values of an event
+func eventRow(event models.ScanEvent) []string {
+	return []string{
+		event.Timestamp.Format(time.RFC3339),
+		event.SourceIP,
+		strconv.Itoa(event.SourcePort),
+		strconv.Itoa(event.TargetPort),
+		event.Protocol,
+		event.ScanType,
+		event.Severity.String(),
+		event.Description,
+		strings.Join(event.Tags, ";"),
+		event.Hostname,
+		strconv.FormatBool(event.Acknowledged),
+		event.Note,
+	}
+}
+
+// writeEventsTable writes events as an aligned plain text table
+func writeEventsTable(w io.Writer, events []models.ScanEvent) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(eventColumns, "\t")))
+	for _, event := range events {
+		fmt.Fprintln(tw, strings.Join(eventRow(event), "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeEventsJSON writes events as an indented JSON array
+func writeEventsJSON(w io.Writer, events []models.ScanEvent) error {
+	if events == nil {
+		events = []models.ScanEvent{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
+
+// writeEventsCSV writes events as CSV with a header row
+func writeEventsCSV(w io.Writer, events []models.ScanEvent) error {
+	cw := csv.NewWriter(w)
+	cw.Write(eventColumns)
+	for _, event := range events {
+		cw.Write(eventRow(event))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownEscaper keeps cell values from breaking a Markdown table row
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ")
+
+// writeEventsMarkdown writes events as a Markdown table
+func writeEventsMarkdown(w io.Writer, events []models.ScanEvent) error {
+	rows := make([][]string, len(events))
+	for i, event := range events {
+		rows[i] = eventRow(event)
+	}
+	return writeMarkdownTable(w, eventColumns, rows)
+}
+
+// writeMarkdownTable writes rows as a Markdown table under the given headers
+func writeMarkdownTable(w io.Writer, columns []string, rows [][]string) error {
+	separators := make([]string, len(columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	lines := []string{
+		"| " + strings.Join(columns, " | ") + " |",
+		"| " + strings.Join(separators, " | ") + " |",
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = markdownEscaper.Replace(value)
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// groupColumns are the column headers used when writing event groups
+var groupColumns = []string{"Key", "Count", "Unique IPs", "Unique Ports", "First Seen", "Last Seen", "Max Severity"}
+
+// WriteGroups writes event groups as table, json, csv or markdown
+func WriteGroups(w io.Writer, format string, groups []models.EventGroup) error {
+	row := func(g models.EventGroup) []string {
+		return []string{
+			g.Key,
+			strconv.Itoa(g.Count),
+			strconv.Itoa(g.UniqueIPs),
+			strconv.Itoa(g.UniquePorts),
+			g.FirstSeen.Format(time.RFC3339),
+			g.LastSeen.Format(time.RFC3339),
+			g.MaxSeverity.String(),
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(groupColumns, "\t")))
+		for _, g := range groups {
+			fmt.Fprintln(tw, strings.Join(row(g), "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		if groups == nil {
+			groups = []models.EventGroup{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(groupColumns)
+		for _, g := range groups {
+			cw.Write(row(g))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "markdown":
+		rows := make([][]string, len(groups))
+		for i, g := range groups {
+			rows[i] = row(g)
+		}
+		return writeMarkdownTable(w, groupColumns, rows)
+	default:
+		return fmt.Errorf("unknown format %q for grouped output: must be one of csv, json, markdown, table", format)
+	}
+}
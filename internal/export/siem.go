@@ -0,0 +1,370 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/fail2ban"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Product identification used by the SIEM formats
+const (
+	ProductVendor  = "jonasbn"
+	ProductName    = "portscammer"
+	ProductVersion = "1.0"
+)
+
+// ECSVersion and OCSFVersion are the schema versions the mappings follow
+const (
+	ECSVersion  = "8.11.0"
+	OCSFVersion = "1.1.0"
+)
+
+// LineFormatter formats a single scan event as one line, which makes it
+// usable for alert sinks writing events as they happen
+type LineFormatter interface {
+	FormatEvent(event models.ScanEvent) (string, error)
+}
+
+// LineFormatterFunc adapts a function to both the LineFormatter and
+// Formatter interfaces
+type LineFormatterFunc func(event models.ScanEvent) (string, error)
+
+// FormatEvent calls f(event)
+func (f LineFormatterFunc) FormatEvent(event models.ScanEvent) (string, error) {
+	return f(event)
+}
+
+// FormatEvents writes one line per event
+func (f LineFormatterFunc) FormatEvents(w io.Writer, events []models.ScanEvent) error {
+	for _, event := range events {
+		line, err := f(event)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	formatters["jsonl"] = LineFormatterFunc(formatJSONLine)
+	formatters["cef"] = LineFormatterFunc(FormatCEF)
+	formatters["leef"] = LineFormatterFunc(FormatLEEF)
+	formatters["ecs"] = LineFormatterFunc(FormatECS)
+	formatters["ocsf"] = LineFormatterFunc(FormatOCSF)
+	formatters["fail2ban"] = LineFormatterFunc(func(event models.ScanEvent) (string, error) {
+		return fail2ban.FormatLine(event), nil
+	})
+}
+
+// GetLine returns the line formatter registered under name
+func GetLine(name string) (LineFormatter, error) {
+	f, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	lf, ok := f.(LineFormatter)
+	if !ok {
+		return nil, fmt.Errorf("format %q does not support one event per line", name)
+	}
+	return lf, nil
+}
+
+// severityScore maps a severity onto the 0-10 scale used by CEF and LEEF
+func severityScore(s models.Severity) int {
+	switch s {
+	case models.SeverityLow:
+		return 3
+	case models.SeverityMedium:
+		return 5
+	case models.SeverityHigh:
+		return 8
+	case models.SeverityCritical:
+		return 10
+	default:
+		return 0
+	}
+}
+
+// formatJSONLine formats an event as compact JSON
+func formatJSONLine(event models.ScanEvent) (string, error) {
+	data, err := json.Marshal(event)
+	return string(data), err
+}
+
+// FormatCEF formats an event in ArcSight Common Event Format
+func FormatCEF(event models.ScanEvent) (string, error) {
+	header := []string{
+		"CEF:0",
+		cefHeader(ProductVendor),
+		cefHeader(ProductName),
+		cefHeader(ProductVersion),
+		cefHeader(event.ScanType),
+		cefHeader("Port scan detected"),
+		strconv.Itoa(severityScore(event.Severity)),
+	}
+
+	ext := []string{
+		"rt=" + strconv.FormatInt(event.Timestamp.UnixMilli(), 10),
+		"src=" + cefExtension(event.SourceIP),
+		"spt=" + strconv.Itoa(event.SourcePort),
+		"dpt=" + strconv.Itoa(event.TargetPort),
+		"proto=" + cefExtension(strings.ToUpper(event.Protocol)),
+		"cat=" + cefExtension(event.ScanType),
+		"externalId=" + cefExtension(event.ID),
+		"msg=" + cefExtension(event.Description),
+	}
+	if event.Hostname != "" {
+		ext = append(ext, "shost="+cefExtension(event.Hostname))
+	}
+
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " "), nil
+}
+
+// cefHeader escapes a CEF header field
+func cefHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// cefExtension escapes a CEF extension value
+func cefExtension(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// FormatLEEF formats an event in IBM QRadar Log Event Extended Format 1.0
+func FormatLEEF(event models.ScanEvent) (string, error) {
+	header := []string{
+		"LEEF:1.0",
+		leefHeader(ProductVendor),
+		leefHeader(ProductName),
+		leefHeader(ProductVersion),
+		leefHeader(event.ScanType),
+	}
+
+	attrs := []string{
+		"devTime=" + leefValue(event.Timestamp.UTC().Format("Jan 02 2006 15:04:05.000")),
+		"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS",
+		"src=" + leefValue(event.SourceIP),
+		"srcPort=" + strconv.Itoa(event.SourcePort),
+		"dstPort=" + strconv.Itoa(event.TargetPort),
+		"proto=" + leefValue(strings.ToUpper(event.Protocol)),
+		"sev=" + strconv.Itoa(severityScore(event.Severity)),
+		"cat=" + leefValue(event.ScanType),
+		"eventId=" + leefValue(event.ID),
+		"msg=" + leefValue(event.Description),
+	}
+
+	return strings.Join(header, "|") + "|" + strings.Join(attrs, "\t"), nil
+}
+
+// leefHeader escapes a LEEF header field
+func leefHeader(s string) string {
+	return strings.NewReplacer(`|`, `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// leefValue removes the attribute delimiter and line breaks from a LEEF value
+func leefValue(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// ecsEvent is a scan event mapped onto the Elastic Common Schema
+type ecsEvent struct {
+	Timestamp string `json:"@timestamp"`
+	Message   string `json:"message,omitempty"`
+	ECS       struct {
+		Version string `json:"version"`
+	} `json:"ecs"`
+	Event struct {
+		ID       string   `json:"id"`
+		Kind     string   `json:"kind"`
+		Category []string `json:"category"`
+		Type     []string `json:"type"`
+		Action   string   `json:"action"`
+		Severity int      `json:"severity"`
+		Dataset  string   `json:"dataset"`
+		Created  string   `json:"created"`
+	} `json:"event"`
+	Source struct {
+		IP     string  `json:"ip"`
+		Port   int     `json:"port,omitempty"`
+		Domain string  `json:"domain,omitempty"`
+		Geo    *ecsGeo `json:"geo,omitempty"`
+		AS     *ecsAS  `json:"as,omitempty"`
+	} `json:"source"`
+	Destination struct {
+		Port int `json:"port"`
+	} `json:"destination"`
+	Network struct {
+		Transport string `json:"transport,omitempty"`
+	} `json:"network"`
+	Observer struct {
+		Vendor  string `json:"vendor"`
+		Product string `json:"product"`
+		Version string `json:"version"`
+		Type    string `json:"type"`
+	} `json:"observer"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+}
+
+// ecsGeo is the location of an address in the Elastic Common Schema
+type ecsGeo struct {
+	CountryISOCode string `json:"country_iso_code,omitempty"`
+	CityName       string `json:"city_name,omitempty"`
+}
+
+// ecsAS is the autonomous system of an address in the Elastic Common Schema
+type ecsAS struct {
+	Number       uint `json:"number,omitempty"`
+	Organization struct {
+		Name string `json:"name,omitempty"`
+	} `json:"organization"`
+}
+
+// FormatECS formats an event as Elastic Common Schema JSON
+func FormatECS(event models.ScanEvent) (string, error) {
+	var e ecsEvent
+
+	e.Timestamp = event.Timestamp.UTC().Format(time.RFC3339Nano)
+	e.Message = event.Description
+	e.ECS.Version = ECSVersion
+	e.Event.ID = event.ID
+	e.Event.Kind = "alert"
+	e.Event.Category = []string{"network", "intrusion_detection"}
+	e.Event.Type = []string{"connection", "info"}
+	e.Event.Action = event.ScanType
+	e.Event.Severity = severityScore(event.Severity)
+	e.Event.Dataset = "portscammer.scan"
+	e.Event.Created = e.Timestamp
+	e.Source.IP = event.SourceIP
+	e.Source.Port = event.SourcePort
+	e.Source.Domain = event.Hostname
+	if event.Country != "" || event.City != "" {
+		e.Source.Geo = &ecsGeo{CountryISOCode: event.Country, CityName: event.City}
+	}
+	if event.ASN != 0 {
+		e.Source.AS = &ecsAS{Number: event.ASN}
+		e.Source.AS.Organization.Name = event.Org
+	}
+	e.Destination.Port = event.TargetPort
+	e.Network.Transport = strings.ToLower(event.Protocol)
+	e.Observer.Vendor = ProductVendor
+	e.Observer.Product = ProductName
+	e.Observer.Version = ProductVersion
+	e.Observer.Type = "ids"
+	e.Labels = map[string]string{"severity": event.Severity.String()}
+	if event.Acknowledged {
+		e.Labels["acknowledged"] = "true"
+	}
+	if event.Note != "" {
+		e.Labels["note"] = event.Note
+	}
+	e.Tags = event.Tags
+
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+// OCSF Network Activity identifiers
+const (
+	ocsfCategoryNetwork  = 4
+	ocsfClassNetwork     = 4001
+	ocsfActivityOpen     = 1
+	ocsfSeverityUnknown  = 0
+	ocsfSeverityLow      = 2
+	ocsfSeverityMedium   = 3
+	ocsfSeverityHigh     = 4
+	ocsfSeverityCritical = 5
+)
+
+// ocsfEvent is a scan event mapped onto the OCSF Network Activity class
+type ocsfEvent struct {
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name"`
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name"`
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name"`
+	TypeUID      int    `json:"type_uid"`
+	SeverityID   int    `json:"severity_id"`
+	Severity     string `json:"severity"`
+	Time         int64  `json:"time"`
+	Message      string `json:"message,omitempty"`
+	Metadata     struct {
+		UID     string `json:"uid"`
+		Version string `json:"version"`
+		Product struct {
+			Name       string `json:"name"`
+			VendorName string `json:"vendor_name"`
+			Version    string `json:"version"`
+		} `json:"product"`
+		Labels []string `json:"labels,omitempty"`
+	} `json:"metadata"`
+	SrcEndpoint struct {
+		IP       string `json:"ip"`
+		Port     int    `json:"port,omitempty"`
+		Hostname string `json:"hostname,omitempty"`
+	} `json:"src_endpoint"`
+	DstEndpoint struct {
+		Port int `json:"port"`
+	} `json:"dst_endpoint"`
+	ConnectionInfo struct {
+		ProtocolName string `json:"protocol_name,omitempty"`
+		Direction    string `json:"direction"`
+		DirectionID  int    `json:"direction_id"`
+	} `json:"connection_info"`
+}
+
+// FormatOCSF formats an event as OCSF Network Activity JSON
+func FormatOCSF(event models.ScanEvent) (string, error) {
+	var e ocsfEvent
+
+	e.ClassUID = ocsfClassNetwork
+	e.ClassName = "Network Activity"
+	e.CategoryUID = ocsfCategoryNetwork
+	e.CategoryName = "Network Activity"
+	e.ActivityID = ocsfActivityOpen
+	e.ActivityName = "Open"
+	e.TypeUID = ocsfClassNetwork*100 + ocsfActivityOpen
+	e.Time = event.Timestamp.UnixMilli()
+	e.Message = event.Description
+	e.Metadata.UID = event.ID
+	e.Metadata.Version = OCSFVersion
+	e.Metadata.Product.Name = ProductName
+	e.Metadata.Product.VendorName = ProductVendor
+	e.Metadata.Product.Version = ProductVersion
+	if event.ScanType != "" {
+		e.Metadata.Labels = []string{event.ScanType}
+	}
+	e.SrcEndpoint.IP = event.SourceIP
+	e.SrcEndpoint.Port = event.SourcePort
+	e.SrcEndpoint.Hostname = event.Hostname
+	e.DstEndpoint.Port = event.TargetPort
+	e.ConnectionInfo.ProtocolName = strings.ToLower(event.Protocol)
+	e.ConnectionInfo.Direction = "Inbound"
+	e.ConnectionInfo.DirectionID = 1
+
+	switch event.Severity {
+	case models.SeverityLow:
+		e.SeverityID, e.Severity = ocsfSeverityLow, "Low"
+	case models.SeverityMedium:
+		e.SeverityID, e.Severity = ocsfSeverityMedium, "Medium"
+	case models.SeverityHigh:
+		e.SeverityID, e.Severity = ocsfSeverityHigh, "High"
+	case models.SeverityCritical:
+		e.SeverityID, e.Severity = ocsfSeverityCritical, "Critical"
+	default:
+		e.SeverityID, e.Severity = ocsfSeverityUnknown, "Unknown"
+	}
+
+	data, err := json.Marshal(e)
+	return string(data), err
+}
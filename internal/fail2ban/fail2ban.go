@@ -0,0 +1,104 @@
+package fail2ban
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// FilterName is the default name used for the generated filter and jail
+const FilterName = "portscammer"
+
+// lineTag identifies portscammer lines in a shared log file
+const lineTag = "portscammer[scan]:"
+
+// FormatLine formats a scan event as a single stable log line suitable for
+// fail2ban failregex matching. The format is:
+//
+//	2006-01-02T15:04:05Z portscammer[scan]: host=<ip> port=<port> severity=<SEVERITY> type=<type>
+func FormatLine(event models.ScanEvent) string {
+	scanType := strings.Join(strings.Fields(event.ScanType), "_")
+	if scanType == "" {
+		scanType = "unknown"
+	}
+
+	return fmt.Sprintf("%s %s host=%s port=%d severity=%s type=%s",
+		event.Timestamp.UTC().Format(time.RFC3339),
+		lineTag,
+		event.SourceIP,
+		event.TargetPort,
+		event.Severity.String(),
+		scanType)
+}
+
+// BanLine formats a line requesting a ban of ip, matching FailRegex like the
+// lines of scan events. It is written when a source is blocked by hand, so
+// the source is banned without an event being recorded for it.
+func BanLine(ip string, at time.Time) string {
+	return fmt.Sprintf("%s %s host=%s port=0 severity=%s type=blocked",
+		at.UTC().Format(time.RFC3339),
+		lineTag,
+		ip,
+		models.SeverityCritical.String())
+}
+
+// FailRegex returns the failregex matching lines produced by FormatLine
+func FailRegex() string {
+	return `^\s*portscammer\[scan\]: host=<HOST> port=\d+ severity=(?:LOW|MEDIUM|HIGH|CRITICAL|UNKNOWN) type=\S+$`
+}
+
+// JailOptions holds the settings used when generating a jail definition
+type JailOptions struct {
+	Name      string        // Jail and filter name
+	LogPath   string        // Path of the log file written by portscammer
+	MaxRetry  int           // Number of matches before a ban
+	FindTime  time.Duration // Window in which matches are counted
+	BanTime   time.Duration // Duration of a ban
+	BanAction string        // fail2ban ban action
+}
+
+// DefaultJailOptions returns the default jail options
+func DefaultJailOptions() JailOptions {
+	return JailOptions{
+		Name:      FilterName,
+		LogPath:   "/var/log/portscammer-fail2ban.log",
+		MaxRetry:  1,
+		FindTime:  time.Minute * 5,
+		BanTime:   time.Hour,
+		BanAction: "iptables-allports",
+	}
+}
+
+// Filter returns the contents of a filter.d definition matching FormatLine
+func Filter() string {
+	var b strings.Builder
+
+	b.WriteString("# fail2ban filter for portscammer scan detection events\n")
+	b.WriteString("# Generated by 'portscammer export fail2ban'\n\n")
+	b.WriteString("[Definition]\n\n")
+	b.WriteString("failregex = " + FailRegex() + "\n\n")
+	b.WriteString("ignoreregex =\n\n")
+	b.WriteString("datepattern = {^LN-BEG}ISO8601\n")
+
+	return b.String()
+}
+
+// Jail returns the contents of a jail.d definition using the given options
+func Jail(opts JailOptions) string {
+	var b strings.Builder
+
+	b.WriteString("# fail2ban jail for portscammer scan detection events\n")
+	b.WriteString("# Generated by 'portscammer export fail2ban'\n\n")
+	fmt.Fprintf(&b, "[%s]\n", opts.Name)
+	b.WriteString("enabled   = true\n")
+	fmt.Fprintf(&b, "filter    = %s\n", opts.Name)
+	fmt.Fprintf(&b, "logpath   = %s\n", opts.LogPath)
+	fmt.Fprintf(&b, "maxretry  = %d\n", opts.MaxRetry)
+	fmt.Fprintf(&b, "findtime  = %d\n", int(opts.FindTime.Seconds()))
+	fmt.Fprintf(&b, "bantime   = %d\n", int(opts.BanTime.Seconds()))
+	fmt.Fprintf(&b, "banaction = %s\n", opts.BanAction)
+
+	return b.String()
+}
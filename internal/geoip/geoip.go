@@ -0,0 +1,139 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Record holds the location and network owner of an address
+type Record struct {
+	Country     string `json:"country,omitempty"`      // ISO 3166-1 alpha-2 code
+	CountryName string `json:"country_name,omitempty"` // English country name
+	City        string `json:"city,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	Org         string `json:"org,omitempty"`
+}
+
+// Reader looks up addresses in one or more mmdb files, such as the MaxMind
+// GeoLite2-City and GeoLite2-ASN databases or the IPinfo databases
+type Reader struct {
+	dbs []*maxminddb.Reader
+}
+
+// Open opens the mmdb files at paths
+func Open(paths ...string) (*Reader, error) {
+	r := &Reader{}
+	for _, path := range paths {
+		db, err := maxminddb.Open(path)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to open GeoIP database %q: %w", path, err)
+		}
+		r.dbs = append(r.dbs, db)
+	}
+	return r, nil
+}
+
+// Close closes the databases
+func (r *Reader) Close() error {
+	var errs []error
+	for _, db := range r.dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup returns what the databases know about ip. Fields found in an
+// earlier database take precedence.
+func (r *Reader) Lookup(ip string) (Record, bool) {
+	var record Record
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return record, false
+	}
+
+	found := false
+	for _, db := range r.dbs {
+		var data map[string]interface{}
+		if err := db.Lookup(addr, &data); err != nil || len(data) == 0 {
+			continue
+		}
+		found = true
+		merge(&record, data)
+	}
+
+	return record, found
+}
+
+// Enrich adds the location and network owner of the event's source
+func (r *Reader) Enrich(event *models.ScanEvent) {
+	record, ok := r.Lookup(event.SourceIP)
+	if !ok {
+		return
+	}
+
+	event.Country = record.Country
+	event.City = record.City
+	event.ASN = record.ASN
+	event.Org = record.Org
+}
+
+// merge fills the empty fields of record from a decoded database entry. It
+// understands the MaxMind GeoLite2 layout, with nested country and city
+// objects, and the flat IPinfo layout.
+func merge(record *Record, data map[string]interface{}) {
+	switch country := data["country"].(type) {
+	case map[string]interface{}:
+		setString(&record.Country, country["iso_code"])
+		setString(&record.CountryName, englishName(country))
+	case string:
+		setString(&record.Country, country)
+		setString(&record.CountryName, data["country_name"])
+	}
+
+	switch city := data["city"].(type) {
+	case map[string]interface{}:
+		setString(&record.City, englishName(city))
+	case string:
+		setString(&record.City, city)
+	}
+
+	if record.ASN == 0 {
+		if asn, ok := data["autonomous_system_number"].(uint64); ok {
+			record.ASN = uint(asn)
+		}
+		if s, ok := data["asn"].(string); ok {
+			if n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(s), "AS"), 10, 32); err == nil {
+				record.ASN = uint(n)
+			}
+		}
+	}
+
+	setString(&record.Org, data["autonomous_system_organization"])
+	setString(&record.Org, data["as_name"])
+}
+
+// englishName returns the English entry of an object's names
+func englishName(object map[string]interface{}) interface{} {
+	if names, ok := object["names"].(map[string]interface{}); ok {
+		return names["en"]
+	}
+	return nil
+}
+
+// setString sets field to value if the field is empty and value a string
+func setString(field *string, value interface{}) {
+	if s, ok := value.(string); ok && *field == "" {
+		*field = s
+	}
+}
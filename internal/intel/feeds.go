@@ -0,0 +1,319 @@
+package intel
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Feed formats
+const (
+	FormatPlain = "plain"
+	FormatCIDR  = "cidr"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+// jsonAddressFields are the object fields checked for an address, in order,
+// when reading JSON feeds
+var jsonAddressFields = []string{"ip_address", "ip", "cidr", "network", "prefix", "address"}
+
+// FeedSpec describes a local threat-intel feed file
+type FeedSpec struct {
+	Name   string
+	Path   string
+	Format string // plain, cidr, csv or json, detected from the extension when empty
+}
+
+// ParseFeedSpec parses a feed given as name[:format]=path
+func ParseFeedSpec(s string) (FeedSpec, error) {
+	name, path, ok := strings.Cut(s, "=")
+	if !ok || name == "" || path == "" {
+		return FeedSpec{}, fmt.Errorf("invalid feed %q, expected name[:format]=path", s)
+	}
+
+	spec := FeedSpec{Name: name, Path: path}
+	if n, format, ok := strings.Cut(name, ":"); ok {
+		spec.Name, spec.Format = n, format
+	}
+
+	switch spec.format() {
+	case FormatPlain, FormatCIDR, FormatCSV, FormatJSON:
+		return spec, nil
+	default:
+		return FeedSpec{}, fmt.Errorf("unknown feed format %q", spec.Format)
+	}
+}
+
+// format returns the format of the feed, detected from the file extension
+// when not set
+func (s FeedSpec) format() string {
+	if s.Format != "" {
+		return strings.ToLower(s.Format)
+	}
+	switch strings.ToLower(filepath.Ext(s.Path)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatPlain
+	}
+}
+
+// FeedInfo describes the result of loading a feed
+type FeedInfo struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Entries  int       `json:"entries"`
+	Skipped  int       `json:"skipped"` // Entries that could not be parsed
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// LoadFeed reads the entries of a feed file into a prefix tree
+func LoadFeed(spec FeedSpec) (*iplist.Tree[struct{}], FeedInfo, error) {
+	info := FeedInfo{Name: spec.Name, Path: spec.Path}
+
+	file, err := os.Open(spec.Path)
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to open feed %q: %w", spec.Name, err)
+	}
+	defer file.Close()
+
+	tree := iplist.NewTree[struct{}]()
+	add := func(entry string) {
+		prefix, err := iplist.ParsePrefix(entry)
+		if err != nil {
+			info.Skipped++
+			return
+		}
+		tree.Insert(prefix, struct{}{})
+	}
+
+	switch spec.format() {
+	case FormatCSV:
+		err = readCSVFeed(file, add)
+	case FormatJSON:
+		err = readJSONFeed(file, add)
+	default:
+		err = readPlainFeed(file, add)
+	}
+	if err != nil {
+		return nil, info, fmt.Errorf("failed to read feed %q: %w", spec.Name, err)
+	}
+
+	info.Entries = tree.Len()
+	info.LoadedAt = time.Now()
+	return tree, info, nil
+}
+
+// readPlainFeed reads one address or CIDR range per line, ignoring comments
+// starting with # or ; as used by FireHOL and Spamhaus DROP lists
+func readPlainFeed(r io.Reader, add func(string)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		add(fields[0])
+	}
+	return scanner.Err()
+}
+
+// readCSVFeed reads the first cell of each row holding an address or CIDR
+// range, so header rows and other columns are skipped
+func readCSVFeed(r io.Reader, add func(string)) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, cell := range record {
+			if isAddress(cell) {
+				add(strings.TrimSpace(cell))
+				break
+			}
+		}
+	}
+}
+
+// readJSONFeed reads addresses from a JSON document holding strings or
+// objects with one of the jsonAddressFields, at any depth
+func readJSONFeed(r io.Reader, add func(string)) error {
+	var doc interface{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			add(v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, field := range jsonAddressFields {
+				if s, ok := v[field].(string); ok {
+					add(s)
+					return
+				}
+			}
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					walk(item)
+				}
+			}
+		}
+	}
+	walk(doc)
+
+	return nil
+}
+
+// isAddress reports whether s is an IP address or CIDR range
+func isAddress(s string) bool {
+	_, err := iplist.ParsePrefix(strings.TrimSpace(s))
+	return err == nil
+}
+
+// loadedFeed is a feed held in memory
+type loadedFeed struct {
+	tree    *iplist.Tree[struct{}]
+	info    FeedInfo
+	modTime time.Time
+}
+
+// Feeds matches addresses against a set of threat-intel feeds
+type Feeds struct {
+	mu     sync.RWMutex
+	specs  []FeedSpec
+	bump   int
+	loaded map[string]*loadedFeed
+}
+
+// NewFeeds creates a set of feeds raising the severity of matching events
+// by bump levels. The feeds are read by the first Refresh.
+func NewFeeds(specs []FeedSpec, bump int) *Feeds {
+	return &Feeds{
+		specs:  specs,
+		bump:   bump,
+		loaded: make(map[string]*loadedFeed),
+	}
+}
+
+// Refresh reloads the feeds whose files changed since they were last read,
+// or all feeds when force is set. It returns the feeds that were reloaded.
+// A feed that fails to load keeps its previous entries.
+func (f *Feeds) Refresh(force bool) ([]FeedInfo, error) {
+	var reloaded []FeedInfo
+	var errs []error
+
+	for _, spec := range f.specs {
+		stat, err := os.Stat(spec.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to stat feed %q: %w", spec.Name, err))
+			continue
+		}
+
+		f.mu.RLock()
+		current := f.loaded[spec.Name]
+		f.mu.RUnlock()
+		if !force && current != nil && current.modTime.Equal(stat.ModTime()) {
+			continue
+		}
+
+		tree, info, err := LoadFeed(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		f.mu.Lock()
+		f.loaded[spec.Name] = &loadedFeed{tree: tree, info: info, modTime: stat.ModTime()}
+		f.mu.Unlock()
+		reloaded = append(reloaded, info)
+	}
+
+	return reloaded, errors.Join(errs...)
+}
+
+// Info returns the state of the loaded feeds
+func (f *Feeds) Info() []FeedInfo {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	infos := make([]FeedInfo, 0, len(f.loaded))
+	for _, spec := range f.specs {
+		if feed, ok := f.loaded[spec.Name]; ok {
+			infos = append(infos, feed.info)
+		}
+	}
+	return infos
+}
+
+// Match returns the names of the feeds listing ip
+func (f *Feeds) Match(ip string) []string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var names []string
+	for _, spec := range f.specs {
+		feed, ok := f.loaded[spec.Name]
+		if !ok {
+			continue
+		}
+		if _, ok := feed.tree.Lookup(addr); ok {
+			names = append(names, spec.Name)
+		}
+	}
+	return names
+}
+
+// Enrich tags the event with the feeds listing its source and raises its
+// severity when there is a match
+func (f *Feeds) Enrich(event *models.ScanEvent) {
+	names := f.Match(event.SourceIP)
+	if len(names) == 0 {
+		return
+	}
+
+	for _, name := range names {
+		event.AddTag(name)
+	}
+	event.Severity = event.Severity.Raise(f.bump)
+}
@@ -0,0 +1,107 @@
+package intel
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Sighting aggregates the scan events seen from a single source address
+type Sighting struct {
+	IP        string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int
+	Ports     []int
+	Severity  models.Severity
+}
+
+// IsIPv6 reports whether the sighting's address is an IPv6 address
+func (s Sighting) IsIPv6() bool {
+	addr, err := netip.ParseAddr(s.IP)
+	return err == nil && addr.Unmap().Is6()
+}
+
+// Aggregate groups events per source address, leaving out addresses for
+// which exclude returns true. Sightings are ordered by address.
+func Aggregate(events []models.ScanEvent, exclude func(ip string) bool) []Sighting {
+	index := make(map[string]int)
+	seenPorts := make(map[string]map[int]bool)
+	var sightings []Sighting
+
+	for _, event := range events {
+		if exclude != nil && exclude(event.SourceIP) {
+			continue
+		}
+
+		i, ok := index[event.SourceIP]
+		if !ok {
+			i = len(sightings)
+			index[event.SourceIP] = i
+			seenPorts[event.SourceIP] = make(map[int]bool)
+			sightings = append(sightings, Sighting{
+				IP:        event.SourceIP,
+				FirstSeen: event.Timestamp,
+				LastSeen:  event.Timestamp,
+				Severity:  event.Severity,
+			})
+		}
+
+		s := &sightings[i]
+		s.Count++
+		if event.Timestamp.Before(s.FirstSeen) {
+			s.FirstSeen = event.Timestamp
+		}
+		if event.Timestamp.After(s.LastSeen) {
+			s.LastSeen = event.Timestamp
+		}
+		if event.Severity > s.Severity {
+			s.Severity = event.Severity
+		}
+		if !seenPorts[event.SourceIP][event.TargetPort] {
+			seenPorts[event.SourceIP][event.TargetPort] = true
+			s.Ports = append(s.Ports, event.TargetPort)
+		}
+	}
+
+	for i := range sightings {
+		sort.Ints(sightings[i].Ports)
+	}
+	sort.Slice(sightings, func(i, j int) bool {
+		return sightings[i].IP < sightings[j].IP
+	})
+
+	return sightings
+}
+
+// uuidV5 returns the name based (SHA-1) UUID of name within namespace
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	sum := h.Sum(nil)
+
+	var u [16]byte
+	copy(u[:], sum[:16])
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// parseUUID parses a UUID in its canonical string form
+func parseUUID(s string) [16]byte {
+	var u [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(u) {
+		panic("intel: invalid UUID " + s)
+	}
+	copy(u[:], b)
+	return u
+}
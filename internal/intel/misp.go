@@ -0,0 +1,106 @@
+package intel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// mispNamespace is the namespace used for the UUIDs of exported MISP objects
+var mispNamespace = parseUUID("0d8d3c4b-5b7e-5f8a-8c1e-2a9f6b3d4e51")
+
+// MISP threat levels
+const (
+	mispThreatHigh   = "1"
+	mispThreatMedium = "2"
+	mispThreatLow    = "3"
+)
+
+// mispAttribute is a single attribute of a MISP event
+type mispAttribute struct {
+	UUID      string `json:"uuid"`
+	Type      string `json:"type"`
+	Category  string `json:"category"`
+	Value     string `json:"value"`
+	ToIDS     bool   `json:"to_ids"`
+	Comment   string `json:"comment"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+	Timestamp string `json:"timestamp"`
+}
+
+// mispTag is a tag attached to a MISP event
+type mispTag struct {
+	Name string `json:"name"`
+}
+
+// mispEvent is a MISP event in the MISP core format
+type mispEvent struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Distribution  string          `json:"distribution"`
+	Published     bool            `json:"published"`
+	Timestamp     string          `json:"timestamp"`
+	Attribute     []mispAttribute `json:"Attribute"`
+	Tag           []mispTag       `json:"Tag"`
+}
+
+// MISPEvent builds a MISP event with one ip-src attribute per sighting
+func MISPEvent(sightings []Sighting, info string, created time.Time) map[string]interface{} {
+	timestamp := strconv.FormatInt(created.Unix(), 10)
+
+	event := mispEvent{
+		UUID:          uuidV5(mispNamespace, fmt.Sprintf("event:%s:%d", info, created.UnixNano())),
+		Info:          info,
+		Date:          created.UTC().Format("2006-01-02"),
+		ThreatLevelID: mispThreatLow,
+		Analysis:      "2", // Completed
+		Distribution:  "0", // Your organisation only
+		Published:     false,
+		Timestamp:     timestamp,
+		Attribute:     make([]mispAttribute, 0, len(sightings)),
+		Tag:           []mispTag{{Name: "portscammer"}, {Name: "port-scan"}},
+	}
+
+	maxSeverity := models.SeverityLow
+	for _, s := range sightings {
+		if s.Severity > maxSeverity {
+			maxSeverity = s.Severity
+		}
+		event.Attribute = append(event.Attribute, mispAttribute{
+			UUID:      uuidV5(mispNamespace, "attribute:"+s.IP),
+			Type:      "ip-src",
+			Category:  "Network activity",
+			Value:     s.IP,
+			ToIDS:     true,
+			Comment:   fmt.Sprintf("%d scan events against ports %s, severity %s", s.Count, utils.JoinPorts(s.Ports), s.Severity),
+			FirstSeen: s.FirstSeen.UTC().Format(time.RFC3339),
+			LastSeen:  s.LastSeen.UTC().Format(time.RFC3339),
+			Timestamp: timestamp,
+		})
+	}
+
+	switch {
+	case maxSeverity >= models.SeverityHigh:
+		event.ThreatLevelID = mispThreatHigh
+	case maxSeverity == models.SeverityMedium:
+		event.ThreatLevelID = mispThreatMedium
+	}
+
+	return map[string]interface{}{"Event": event}
+}
+
+// WriteMISP writes a MISP event of the sightings as indented JSON
+func WriteMISP(w io.Writer, sightings []Sighting, info string, created time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(MISPEvent(sightings, info, created))
+}
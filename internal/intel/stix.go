@@ -0,0 +1,120 @@
+package intel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// stixSCONamespace is the namespace defined by STIX 2.1 for deterministic
+// cyber-observable identifiers
+var stixSCONamespace = parseUUID("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// stixNamespace is the namespace used for the identifiers of the domain
+// objects portscammer creates, so repeated exports produce the same IDs
+var stixNamespace = parseUUID("6e3c1a4e-4f1b-5d1c-9a51-3c1b2f7d9e40")
+
+// stixTime formats a time as a STIX timestamp
+func stixTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// STIXBundle builds a STIX 2.1 bundle with an identity for the producer and,
+// per sighting, the address observable, an indicator, the observed data and
+// a sighting of the indicator
+func STIXBundle(sightings []Sighting, producer string, created time.Time) map[string]interface{} {
+	now := stixTime(created)
+
+	identityID := "identity--" + uuidV5(stixNamespace, "identity:"+producer)
+	objects := []interface{}{
+		map[string]interface{}{
+			"type":           "identity",
+			"spec_version":   "2.1",
+			"id":             identityID,
+			"created":        now,
+			"modified":       now,
+			"name":           producer,
+			"identity_class": "system",
+		},
+	}
+
+	for _, s := range sightings {
+		scoType := "ipv4-addr"
+		if s.IsIPv6() {
+			scoType = "ipv6-addr"
+		}
+
+		valueJSON, _ := json.Marshal(map[string]string{"value": s.IP})
+		scoID := scoType + "--" + uuidV5(stixSCONamespace, string(valueJSON))
+		indicatorID := "indicator--" + uuidV5(stixNamespace, "indicator:"+s.IP)
+		observedID := "observed-data--" + uuidV5(stixNamespace, fmt.Sprintf("observed-data:%s:%d", s.IP, s.LastSeen.UnixNano()))
+		sightingID := "sighting--" + uuidV5(stixNamespace, fmt.Sprintf("sighting:%s:%d", s.IP, s.LastSeen.UnixNano()))
+
+		objects = append(objects,
+			map[string]interface{}{
+				"type":         scoType,
+				"spec_version": "2.1",
+				"id":           scoID,
+				"value":        s.IP,
+			},
+			map[string]interface{}{
+				"type":            "indicator",
+				"spec_version":    "2.1",
+				"id":              indicatorID,
+				"created":         now,
+				"modified":        now,
+				"created_by_ref":  identityID,
+				"name":            "Port scanning source " + s.IP,
+				"description":     fmt.Sprintf("%d scan events against ports %s", s.Count, utils.JoinPorts(s.Ports)),
+				"indicator_types": []string{"anomalous-activity"},
+				"pattern":         fmt.Sprintf("[%s:value = '%s']", scoType, s.IP),
+				"pattern_type":    "stix",
+				"valid_from":      stixTime(s.FirstSeen),
+				"labels":          []string{"port-scan", strings.ToLower(s.Severity.String())},
+			},
+			map[string]interface{}{
+				"type":            "observed-data",
+				"spec_version":    "2.1",
+				"id":              observedID,
+				"created":         now,
+				"modified":        now,
+				"created_by_ref":  identityID,
+				"first_observed":  stixTime(s.FirstSeen),
+				"last_observed":   stixTime(s.LastSeen),
+				"number_observed": s.Count,
+				"object_refs":     []string{scoID},
+			},
+			map[string]interface{}{
+				"type":               "sighting",
+				"spec_version":       "2.1",
+				"id":                 sightingID,
+				"created":            now,
+				"modified":           now,
+				"created_by_ref":     identityID,
+				"first_seen":         stixTime(s.FirstSeen),
+				"last_seen":          stixTime(s.LastSeen),
+				"count":              s.Count,
+				"sighting_of_ref":    indicatorID,
+				"observed_data_refs": []string{observedID},
+				"where_sighted_refs": []string{identityID},
+			},
+		)
+	}
+
+	return map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--" + uuidV5(stixNamespace, fmt.Sprintf("bundle:%s:%d", producer, created.UnixNano())),
+		"objects": objects,
+	}
+}
+
+// WriteSTIX writes a STIX 2.1 bundle of the sightings as indented JSON
+func WriteSTIX(w io.Writer, sightings []Sighting, producer string, created time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(STIXBundle(sightings, producer, created))
+}
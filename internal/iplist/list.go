@@ -0,0 +1,205 @@
+package iplist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// List is a thread-safe set of IP addresses and CIDR ranges, optionally
+// backed by a file with one entry per line
+type List struct {
+	mu   sync.RWMutex
+	tree *Tree[struct{}]
+	path string
+
+	saveMu sync.Mutex // Serializes Save, so the file holds the latest entries
+}
+
+// New creates an empty list that is not backed by a file
+func New() *List {
+	return &List{tree: NewTree[struct{}]()}
+}
+
+// Load creates a list backed by the file at path. A missing file results in
+// an empty list, which is created on the first Save.
+func Load(path string) (*List, error) {
+	l := New()
+	l.path = path
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open list %q: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		prefix, err := ParsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		l.tree.Insert(prefix, struct{}{})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read list %q: %w", path, err)
+	}
+
+	return l, nil
+}
+
+// ParsePrefix parses a single IP address or CIDR range. A single address is
+// returned as a prefix covering only that address.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		return normalizePrefix(prefix), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q: %w", s, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Path returns the path of the file backing the list
+func (l *List) Path() string {
+	return l.path
+}
+
+// Len returns the number of entries in the list
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tree.Len()
+}
+
+// Add adds an IP address or CIDR range and reports whether it was new
+func (l *List) Add(entry string) (bool, error) {
+	prefix, err := ParsePrefix(entry)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.tree.Get(prefix); ok {
+		return false, nil
+	}
+	l.tree.Insert(prefix, struct{}{})
+	return true, nil
+}
+
+// Remove removes an IP address or CIDR range and reports whether it was present
+func (l *List) Remove(entry string) (bool, error) {
+	prefix, err := ParsePrefix(entry)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.tree.Delete(prefix), nil
+}
+
+// Contains checks if ip is covered by any entry in the list
+func (l *List) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	_, ok := l.tree.Lookup(addr)
+	return ok
+}
+
+// Entries returns all entries in the list in address order. Single addresses
+// are returned without a prefix length.
+func (l *List) Entries() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]string, 0, l.tree.Len())
+	l.tree.Walk(func(prefix netip.Prefix, _ struct{}) {
+		entries = append(entries, formatPrefix(prefix))
+	})
+	return entries
+}
+
+// Save writes the list to its backing file, if it has one
+func (l *List) Save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	// The entries are read under the lock, so a save that started after a
+	// change never renames its file over that of a later save
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	var b strings.Builder
+	for _, entry := range l.Entries() {
+		b.WriteString(entry + "\n")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write list %q: %w", l.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write list %q: %w", l.path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write list %q: %w", l.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write list %q: %w", l.path, err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to replace list %q: %w", l.path, err)
+	}
+	return nil
+}
+
+// formatPrefix formats a prefix, leaving out the length for single addresses
+func formatPrefix(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
@@ -0,0 +1,175 @@
+package iplist
+
+import (
+	"net/netip"
+)
+
+// Tree is a binary prefix tree mapping IPv4 and IPv6 prefixes to values.
+// Lookups return the value of the longest prefix containing an address.
+type Tree[V any] struct {
+	v4   *node[V]
+	v6   *node[V]
+	size int
+}
+
+// node is a single node in the prefix tree
+type node[V any] struct {
+	children [2]*node[V]
+	value    V
+	set      bool
+}
+
+// NewTree creates an empty prefix tree
+func NewTree[V any]() *Tree[V] {
+	return &Tree[V]{v4: &node[V]{}, v6: &node[V]{}}
+}
+
+// Len returns the number of prefixes in the tree
+func (t *Tree[V]) Len() int {
+	return t.size
+}
+
+// Insert adds or replaces the value stored for prefix
+func (t *Tree[V]) Insert(prefix netip.Prefix, value V) {
+	prefix = normalizePrefix(prefix)
+	n := t.root(prefix.Addr())
+	bits := prefix.Addr().AsSlice()
+
+	for i := 0; i < prefix.Bits(); i++ {
+		b := bit(bits, i)
+		if n.children[b] == nil {
+			n.children[b] = &node[V]{}
+		}
+		n = n.children[b]
+	}
+
+	if !n.set {
+		t.size++
+	}
+	n.value = value
+	n.set = true
+}
+
+// Get returns the value stored for exactly prefix
+func (t *Tree[V]) Get(prefix netip.Prefix) (V, bool) {
+	var zero V
+
+	n := t.find(normalizePrefix(prefix))
+	if n == nil || !n.set {
+		return zero, false
+	}
+	return n.value, true
+}
+
+// Delete removes prefix from the tree and reports whether it was present
+func (t *Tree[V]) Delete(prefix netip.Prefix) bool {
+	var zero V
+
+	n := t.find(normalizePrefix(prefix))
+	if n == nil || !n.set {
+		return false
+	}
+
+	n.value = zero
+	n.set = false
+	t.size--
+	return true
+}
+
+// Lookup returns the value of the longest prefix containing addr
+func (t *Tree[V]) Lookup(addr netip.Addr) (V, bool) {
+	var (
+		value V
+		found bool
+	)
+
+	addr = addr.Unmap()
+	if !addr.IsValid() {
+		return value, false
+	}
+
+	n := t.root(addr)
+	bits := addr.AsSlice()
+
+	for i := 0; n != nil; i++ {
+		if n.set {
+			value, found = n.value, true
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		n = n.children[bit(bits, i)]
+	}
+
+	return value, found
+}
+
+// Walk calls fn for every prefix in the tree, IPv4 before IPv6, in address order
+func (t *Tree[V]) Walk(fn func(prefix netip.Prefix, value V)) {
+	walk(t.v4, make([]byte, 4), 0, fn)
+	walk(t.v6, make([]byte, 16), 0, fn)
+}
+
+// walk recursively visits the nodes below n
+func walk[V any](n *node[V], bits []byte, depth int, fn func(netip.Prefix, V)) {
+	if n == nil {
+		return
+	}
+
+	if n.set {
+		addr, _ := netip.AddrFromSlice(bits)
+		fn(netip.PrefixFrom(addr, depth), n.value)
+	}
+
+	for b, child := range n.children {
+		if child == nil {
+			continue
+		}
+		next := make([]byte, len(bits))
+		copy(next, bits)
+		if b == 1 {
+			next[depth/8] |= 0x80 >> (depth % 8)
+		}
+		walk(child, next, depth+1, fn)
+	}
+}
+
+// find returns the node for exactly prefix, or nil
+func (t *Tree[V]) find(prefix netip.Prefix) *node[V] {
+	n := t.root(prefix.Addr())
+	bits := prefix.Addr().AsSlice()
+
+	for i := 0; i < prefix.Bits() && n != nil; i++ {
+		n = n.children[bit(bits, i)]
+	}
+	return n
+}
+
+// root returns the root node for the address family of addr
+func (t *Tree[V]) root(addr netip.Addr) *node[V] {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// normalizePrefix unmaps IPv4-mapped IPv6 prefixes and masks host bits
+func normalizePrefix(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	bits := prefix.Bits()
+
+	if addr.Is4In6() {
+		addr = addr.Unmap()
+		bits -= 96
+		if bits < 0 {
+			bits = 0
+		}
+	}
+
+	return netip.PrefixFrom(addr, bits).Masked()
+}
+
+// bit returns bit i of b counting from the most significant bit
+func bit(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
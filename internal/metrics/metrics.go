@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// namespace prefixes every metric name
+const namespace = "portscammer"
+
+// MaxPortSeries is the most target ports given their own series. The events
+// of the other ports are counted under port="other", so a sweep of the whole
+// port range doesn't create a series per port.
+const MaxPortSeries = 100
+
+// Metrics collects runtime counters and exposes them together with values
+// derived from models.ScanStats in the Prometheus text exposition format
+type Metrics struct {
+	connectionsAccepted atomic.Uint64
+	listenerErrors      atomic.Uint64
+	alertSinkFailures   atomic.Uint64
+
+	stats          func() models.ScanStats
+	blacklistSize  func() int
+	trackerEntries func() int
+}
+
+// New creates a new metrics collector reading scan statistics from stats
+func New(stats func() models.ScanStats) *Metrics {
+	return &Metrics{stats: stats}
+}
+
+// SetBlacklistSize sets the function reporting the number of blacklist entries
+func (m *Metrics) SetBlacklistSize(fn func() int) {
+	m.blacklistSize = fn
+}
+
+// SetTrackerEntries sets the function reporting the number of tracked connections
+func (m *Metrics) SetTrackerEntries(fn func() int) {
+	m.trackerEntries = fn
+}
+
+// IncConnectionsAccepted increments the accepted connections counter
+func (m *Metrics) IncConnectionsAccepted() {
+	m.connectionsAccepted.Add(1)
+}
+
+// IncListenerErrors increments the listener errors counter
+func (m *Metrics) IncListenerErrors() {
+	m.listenerErrors.Add(1)
+}
+
+// IncAlertSinkFailures increments the alert sink failures counter
+func (m *Metrics) IncAlertSinkFailures() {
+	m.alertSinkFailures.Add(1)
+}
+
+// Handler returns an http.Handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := m.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Write writes all metrics to w in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer) error {
+	var stats models.ScanStats
+	if m.stats != nil {
+		stats = m.stats()
+	}
+
+	bw := bufio.NewWriter(w)
+
+	writeMetric(bw, "connections_accepted_total", "counter",
+		"Total number of connections accepted by the listener.",
+		sample{value: float64(m.connectionsAccepted.Load())})
+
+	writeMetric(bw, "scan_events_total", "counter",
+		"Total number of detected scan events.",
+		sample{value: float64(stats.TotalScans)})
+
+	severities := make([]sample, 0, len(stats.SeverityCounts))
+	for _, severity := range []models.Severity{models.SeverityLow, models.SeverityMedium, models.SeverityHigh, models.SeverityCritical} {
+		severities = append(severities, sample{
+			labels: [][2]string{{"severity", strings.ToLower(severity.String())}},
+			value:  float64(stats.SeverityCounts[severity]),
+		})
+	}
+	writeMetric(bw, "scan_events_by_severity_total", "counter",
+		"Number of detected scan events by severity.", severities...)
+
+	types := make([]sample, 0, len(stats.ScansByType))
+	for _, scanType := range sortedKeys(stats.ScansByType) {
+		types = append(types, sample{
+			labels: [][2]string{{"type", scanType}},
+			value:  float64(stats.ScansByType[scanType]),
+		})
+	}
+	writeMetric(bw, "scan_events_by_type_total", "counter",
+		"Number of detected scan events by scan type.", types...)
+
+	portSamples := topPorts(stats.ScansByPort, MaxPortSeries)
+	writeMetric(bw, "scan_events_by_port_total", "counter",
+		"Number of detected scan events by target port, the most scanned ports only.", portSamples...)
+
+	writeMetric(bw, "unique_source_ips", "gauge",
+		"Number of unique source IP addresses seen scanning.",
+		sample{value: float64(stats.UniqueIPs)})
+
+	lastScan := 0.0
+	if !stats.LastScanTime.IsZero() {
+		lastScan = float64(stats.LastScanTime.UnixNano()) / 1e9
+	}
+	writeMetric(bw, "last_scan_timestamp_seconds", "gauge",
+		"Unix timestamp of the last detected scan event.",
+		sample{value: lastScan})
+
+	if m.blacklistSize != nil {
+		writeMetric(bw, "blacklist_entries", "gauge",
+			"Number of entries in the blacklist.",
+			sample{value: float64(m.blacklistSize())})
+	}
+
+	if m.trackerEntries != nil {
+		writeMetric(bw, "tracker_entries", "gauge",
+			"Number of connection tracking entries held in memory.",
+			sample{value: float64(m.trackerEntries())})
+	}
+
+	writeMetric(bw, "listener_errors_total", "counter",
+		"Total number of errors returned by the listener.",
+		sample{value: float64(m.listenerErrors.Load())})
+
+	writeMetric(bw, "alert_sink_failures_total", "counter",
+		"Total number of failures writing to alert sinks.",
+		sample{value: float64(m.alertSinkFailures.Load())})
+
+	return bw.Flush()
+}
+
+// topPorts returns a sample for each of the n most scanned ports, in port
+// order, followed by one for the other ports when there are any
+func topPorts(counts map[int]int, n int) []sample {
+	ports := make([]int, 0, len(counts))
+	for port := range counts {
+		ports = append(ports, port)
+	}
+	// Most scanned first, equal counts by port
+	sort.Slice(ports, func(i, j int) bool {
+		if counts[ports[i]] != counts[ports[j]] {
+			return counts[ports[i]] > counts[ports[j]]
+		}
+		return ports[i] < ports[j]
+	})
+
+	other := 0
+	for _, port := range ports[min(n, len(ports)):] {
+		other += counts[port]
+	}
+	ports = ports[:min(n, len(ports))]
+	sort.Ints(ports)
+
+	samples := make([]sample, 0, len(ports)+1)
+	for _, port := range ports {
+		samples = append(samples, sample{
+			labels: [][2]string{{"port", strconv.Itoa(port)}},
+			value:  float64(counts[port]),
+		})
+	}
+	if other > 0 {
+		samples = append(samples, sample{labels: [][2]string{{"port", "other"}}, value: float64(other)})
+	}
+	return samples
+}
+
+// sample is a single metric value with its labels
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+// writeMetric writes the HELP and TYPE lines followed by the samples of a metric
+func writeMetric(w *bufio.Writer, name, metricType, help string, samples ...sample) {
+	fullName := namespace + "_" + name
+
+	fmt.Fprintf(w, "# HELP %s %s\n", fullName, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", fullName, metricType)
+
+	for _, s := range samples {
+		w.WriteString(fullName)
+		if len(s.labels) > 0 {
+			pairs := make([]string, 0, len(s.labels))
+			for _, label := range s.labels {
+				pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label[0], escapeLabelValue(label[1])))
+			}
+			w.WriteString("{" + strings.Join(pairs, ",") + "}")
+		}
+		w.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+	}
+}
+
+// escapeLabelValue escapes a label value as required by the text format
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
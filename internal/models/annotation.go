@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Annotation acknowledges scan events and attaches a note to them. Fields
+// left nil are not changed, so the acknowledgement and the note can be set
+// on their own.
+type Annotation struct {
+	EventIDs     []string  `json:"event_ids"`
+	Acknowledged *bool     `json:"acknowledged,omitempty"`
+	Note         *string   `json:"note,omitempty"` // An empty note removes the note
+	Time         time.Time `json:"time"`
+}
+
+// Covers checks if the annotation applies to the event with id
+func (a Annotation) Covers(id string) bool {
+	for _, eventID := range a.EventIDs {
+		if eventID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the fields given by the annotation on event if it covers it,
+// and reports whether it did
+func (a Annotation) Apply(event *ScanEvent) bool {
+	if !a.Covers(event.ID) {
+		return false
+	}
+	a.set(event)
+	return true
+}
+
+// ApplyTo applies the annotation to the events it covers and returns how
+// many it did
+func (a Annotation) ApplyTo(events []ScanEvent) int {
+	ids := make(map[string]bool, len(a.EventIDs))
+	for _, id := range a.EventIDs {
+		ids[id] = true
+	}
+
+	applied := 0
+	for i := range events {
+		if ids[events[i].ID] {
+			a.set(&events[i])
+			applied++
+		}
+	}
+	return applied
+}
+
+// set sets the fields given by the annotation on event
+func (a Annotation) set(event *ScanEvent) {
+	if a.Acknowledged != nil {
+		event.Acknowledged = *a.Acknowledged
+	}
+	if a.Note != nil {
+		event.Note = *a.Note
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// EventFilter selects scan events. Zero values match every event.
+type EventFilter struct {
+	Since       time.Time    // Only events at or after this time
+	Until       time.Time    // Only events before this time
+	Network     netip.Prefix // Only events from source IPs within this prefix
+	Port        int          // Only events targeting this port
+	MinSeverity Severity     // Only events with at least this severity
+	ScanType    string       // Only events of this scan type
+	Protocol    string       // Only events using this protocol, ignoring case
+}
+
+// Match checks if the event is selected by the filter
+func (f EventFilter) Match(event ScanEvent) bool {
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Timestamp.Before(f.Until) {
+		return false
+	}
+	if f.Network.IsValid() {
+		addr, err := netip.ParseAddr(event.SourceIP)
+		if err != nil || !f.Network.Contains(addr.Unmap()) {
+			return false
+		}
+	}
+	if f.Port != 0 && event.TargetPort != f.Port {
+		return false
+	}
+	if event.Severity < f.MinSeverity {
+		return false
+	}
+	if f.ScanType != "" && event.ScanType != f.ScanType {
+		return false
+	}
+	if f.Protocol != "" && !strings.EqualFold(event.Protocol, f.Protocol) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the events selected by the filter, preserving their order
+func (f EventFilter) Apply(events []ScanEvent) []ScanEvent {
+	result := make([]ScanEvent, 0, len(events))
+	for _, event := range events {
+		if f.Match(event) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// ParseEventFilter builds an event filter from the query parameters since,
+// until, ip, port, severity, type and protocol, as used by the API and the
+// query command
+func ParseEventFilter(query map[string][]string, now time.Time) (EventFilter, error) {
+	var filter EventFilter
+
+	get := func(key string) string {
+		if values := query[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	if value := get("since"); value != "" {
+		since, err := utils.ParseSince(value, now)
+		if err != nil {
+			return filter, err
+		}
+		filter.Since = since
+	}
+	if value := get("until"); value != "" {
+		until, err := utils.ParseSince(value, now)
+		if err != nil {
+			return filter, err
+		}
+		filter.Until = until
+	}
+	if value := get("ip"); value != "" {
+		prefix, err := iplist.ParsePrefix(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Network = prefix
+	}
+	if value := get("port"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return filter, fmt.Errorf("invalid port %q", value)
+		}
+		filter.Port = port
+	}
+	if value := get("severity"); value != "" {
+		severity, err := ParseSeverity(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.MinSeverity = severity
+	}
+	filter.ScanType = get("type")
+	filter.Protocol = get("protocol")
+
+	return filter, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Group keys supported by GroupEvents
+const (
+	GroupByIP   = "ip"
+	GroupByPort = "port"
+	GroupByType = "type"
+	GroupByHour = "hour"
+)
+
+// EventGroup summarises the events sharing a group key
+type EventGroup struct {
+	Key         string    `json:"key"`
+	Count       int       `json:"count"`
+	UniqueIPs   int       `json:"unique_ips"`
+	UniquePorts int       `json:"unique_ports"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	MaxSeverity Severity  `json:"max_severity"`
+}
+
+// GroupEvents groups events by ip, port, type or hour. Groups are ordered by
+// event count, largest first, and hours are formatted in UTC.
+func GroupEvents(events []ScanEvent, by string) ([]EventGroup, error) {
+	var keyFn func(ScanEvent) string
+
+	switch by {
+	case GroupByIP:
+		keyFn = func(e ScanEvent) string { return e.SourceIP }
+	case GroupByPort:
+		keyFn = func(e ScanEvent) string { return strconv.Itoa(e.TargetPort) }
+	case GroupByType:
+		keyFn = func(e ScanEvent) string { return e.ScanType }
+	case GroupByHour:
+		keyFn = func(e ScanEvent) string { return e.Timestamp.UTC().Truncate(time.Hour).Format("2006-01-02 15:00") }
+	default:
+		return nil, fmt.Errorf("unknown group key %q: must be one of ip, port, type, hour", by)
+	}
+
+	index := make(map[string]int)
+	ips := make(map[string]map[string]bool)
+	ports := make(map[string]map[int]bool)
+	var groups []EventGroup
+
+	for _, event := range events {
+		key := keyFn(event)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, EventGroup{Key: key, FirstSeen: event.Timestamp, LastSeen: event.Timestamp, MaxSeverity: event.Severity})
+			ips[key] = make(map[string]bool)
+			ports[key] = make(map[int]bool)
+		}
+
+		group := &groups[i]
+		group.Count++
+		if event.Timestamp.Before(group.FirstSeen) {
+			group.FirstSeen = event.Timestamp
+		}
+		if event.Timestamp.After(group.LastSeen) {
+			group.LastSeen = event.Timestamp
+		}
+		if event.Severity > group.MaxSeverity {
+			group.MaxSeverity = event.Severity
+		}
+		ips[key][event.SourceIP] = true
+		ports[key][event.TargetPort] = true
+	}
+
+	for i := range groups {
+		groups[i].UniqueIPs = len(ips[groups[i].Key])
+		groups[i].UniquePorts = len(ports[groups[i].Key])
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// StatsMinute counts the scan events of one minute
+type StatsMinute struct {
+	Start          time.Time        `json:"start"`
+	Events         int              `json:"events"`
+	SeverityCounts map[Severity]int `json:"severity_counts"`
+}
+
+// StatsHistory keeps per-minute event counts over a sliding window, for
+// charts of the recent activity
+type StatsHistory struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	minutes map[int64]*StatsMinute // Keyed by the Unix time of the minute
+	latest  int64
+}
+
+// NewStatsHistory creates a history covering window
+func NewStatsHistory(window time.Duration) *StatsHistory {
+	if window < time.Minute {
+		window = time.Minute
+	}
+	return &StatsHistory{window: window, minutes: make(map[int64]*StatsMinute)}
+}
+
+// Add counts an event in the minute of its timestamp. Minutes that fall
+// out of the window are dropped.
+func (h *StatsHistory) Add(event ScanEvent) {
+	start := event.Timestamp.Truncate(time.Minute)
+	key := start.Unix()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	minute, ok := h.minutes[key]
+	if !ok {
+		minute = &StatsMinute{Start: start, SeverityCounts: make(map[Severity]int)}
+		h.minutes[key] = minute
+		if key > h.latest {
+			h.latest = key
+			for k := range h.minutes {
+				if h.latest-k >= int64(h.window/time.Second) {
+					delete(h.minutes, k)
+				}
+			}
+		}
+	}
+	minute.Events++
+	minute.SeverityCounts[event.Severity]++
+}
+
+// Minutes returns the counts of every minute in the window ending at now,
+// oldest first. Minutes without events are included with zero counts.
+func (h *StatsHistory) Minutes(now time.Time) []StatsMinute {
+	end := now.Truncate(time.Minute)
+	n := int(h.window / time.Minute)
+	minutes := make([]StatsMinute, n)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range minutes {
+		start := end.Add(-time.Duration(n-1-i) * time.Minute)
+		minutes[i] = StatsMinute{Start: start, SeverityCounts: make(map[Severity]int)}
+		if minute, ok := h.minutes[start.Unix()]; ok {
+			minutes[i].Events = minute.Events
+			for severity, count := range minute.SeverityCounts {
+				minutes[i].SeverityCounts[severity] = count
+			}
+		}
+	}
+	return minutes
+}
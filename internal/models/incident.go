@@ -0,0 +1,73 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// Incident groups scan events from a single source IP that occur close
+// together in time
+type Incident struct {
+	ID         string    `json:"id"`
+	SourceIP   string    `json:"source_ip"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	EventCount int       `json:"event_count"`
+	Ports      []int     `json:"ports"`
+	Severity   Severity  `json:"severity"`
+	EventIDs   []string  `json:"event_ids"`
+
+	Acknowledged int      `json:"acknowledged"`    // Number of acknowledged events
+	Notes        []string `json:"notes,omitempty"` // Distinct notes of the events, in event order
+}
+
+// GroupIncidents groups events into incidents per source IP. A new incident
+// is started when more than gap has passed since the previous event from the
+// same source. Incidents are returned ordered by their first event.
+func GroupIncidents(events []ScanEvent, gap time.Duration) []Incident {
+	sorted := make([]ScanEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var incidents []Incident
+	open := make(map[string]int) // source IP to index of its latest incident
+	seenPorts := make(map[int]map[int]bool)
+
+	for _, event := range sorted {
+		idx, ok := open[event.SourceIP]
+		if !ok || event.Timestamp.Sub(incidents[idx].LastSeen) > gap {
+			incidents = append(incidents, Incident{
+				ID:        event.SourceIP + "@" + event.Timestamp.UTC().Format("20060102T150405Z"),
+				SourceIP:  event.SourceIP,
+				FirstSeen: event.Timestamp,
+				Severity:  event.Severity,
+			})
+			idx = len(incidents) - 1
+			open[event.SourceIP] = idx
+			seenPorts[idx] = make(map[int]bool)
+		}
+
+		incident := &incidents[idx]
+		incident.LastSeen = event.Timestamp
+		incident.EventCount++
+		incident.EventIDs = append(incident.EventIDs, event.ID)
+		if event.Acknowledged {
+			incident.Acknowledged++
+		}
+		if event.Note != "" && !slices.Contains(incident.Notes, event.Note) {
+			incident.Notes = append(incident.Notes, event.Note)
+		}
+		if event.Severity > incident.Severity {
+			incident.Severity = event.Severity
+		}
+		if !seenPorts[idx][event.TargetPort] {
+			seenPorts[idx][event.TargetPort] = true
+			incident.Ports = append(incident.Ports, event.TargetPort)
+		}
+	}
+
+	return incidents
+}
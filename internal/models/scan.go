@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +19,38 @@ type ScanEvent struct {
 	Severity    Severity  `json:"severity"`
 	UserAgent   string    `json:"user_agent,omitempty"`
 	Description string    `json:"description"`
+	Tags        []string  `json:"tags,omitempty"`
+	Country     string    `json:"country,omitempty"` // ISO 3166-1 alpha-2 code of the source
+	City        string    `json:"city,omitempty"`
+	ASN         uint      `json:"asn,omitempty"`
+	Org         string    `json:"org,omitempty"`      // Organisation owning the source's network
+	Hostname    string    `json:"hostname,omitempty"` // Reverse DNS name of the source
+	Payload     string    `json:"payload,omitempty"`  // First bytes sent by the source, if captured
+
+	AnomalyScore float64 `json:"anomaly_score,omitempty"` // Deviation from the baseline, for anomaly events
+
+	Acknowledged bool   `json:"acknowledged,omitempty"` // Whether an analyst has dealt with the event
+	Note         string `json:"note,omitempty"`         // Free-text note attached by an analyst
+}
+
+// AddTag adds tag to the event unless it is already present
+func (e *ScanEvent) AddTag(tag string) {
+	for _, t := range e.Tags {
+		if t == tag {
+			return
+		}
+	}
+	e.Tags = append(e.Tags, tag)
+}
+
+// HasTag reports whether the event carries tag
+func (e ScanEvent) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // Severity represents the severity level of a scan event
@@ -44,6 +79,18 @@ func (s Severity) String() string {
 	}
 }
 
+// Raise returns the severity raised by n levels, capped at critical
+func (s Severity) Raise(n int) Severity {
+	raised := s + Severity(n)
+	if raised > SeverityCritical {
+		return SeverityCritical
+	}
+	if raised < SeverityLow {
+		return SeverityLow
+	}
+	return raised
+}
+
 // ScanStats represents statistics about detected scans
 type ScanStats struct {
 	TotalScans     int              `json:"total_scans"`
@@ -53,6 +100,8 @@ type ScanStats struct {
 	ScansByPort    map[int]int      `json:"scans_by_port"`
 	ScansByType    map[string]int   `json:"scans_by_type"`
 	SeverityCounts map[Severity]int `json:"severity_counts"`
+	ScansByCountry map[string]int   `json:"scans_by_country,omitempty"`
+	ScansByASN     map[uint]int     `json:"scans_by_asn,omitempty"`
 }
 
 // NewScanEvent creates a new scan event with the current timestamp
@@ -70,7 +119,112 @@ func NewScanEvent(sourceIP string, sourcePort, targetPort int, protocol, scanTyp
 	}
 }
 
+// eventCounter makes event IDs generated within the same second unique
+var eventCounter atomic.Uint64
+
 // generateEventID generates a unique ID for the event
 func generateEventID() string {
-	return time.Now().Format("20060102150405") + "-" + time.Now().Format("000000")
+	return time.Now().Format("20060102150405") + "-" + fmt.Sprintf("%06d", eventCounter.Add(1)%1000000)
+}
+
+// ParseSeverity parses the string representation of a severity, ignoring case
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "LOW":
+		return SeverityLow, nil
+	case "MEDIUM":
+		return SeverityMedium, nil
+	case "HIGH":
+		return SeverityHigh, nil
+	case "CRITICAL":
+		return SeverityCritical, nil
+	default:
+		return SeverityLow, fmt.Errorf("unknown severity %q", s)
+	}
+}
+
+// MergeStats combines two sets of statistics, e.g. stored history and the
+// statistics of the running scanner. Unique IPs are recounted from the
+// merged per-IP counts when those are available.
+func MergeStats(a, b ScanStats) ScanStats {
+	merged := ScanStats{
+		TotalScans:     a.TotalScans + b.TotalScans,
+		LastScanTime:   a.LastScanTime,
+		ScansByIP:      make(map[string]int, len(a.ScansByIP)+len(b.ScansByIP)),
+		ScansByPort:    make(map[int]int, len(a.ScansByPort)+len(b.ScansByPort)),
+		ScansByType:    make(map[string]int, len(a.ScansByType)+len(b.ScansByType)),
+		SeverityCounts: make(map[Severity]int, len(a.SeverityCounts)+len(b.SeverityCounts)),
+		ScansByCountry: make(map[string]int, len(a.ScansByCountry)+len(b.ScansByCountry)),
+		ScansByASN:     make(map[uint]int, len(a.ScansByASN)+len(b.ScansByASN)),
+	}
+
+	if b.LastScanTime.After(merged.LastScanTime) {
+		merged.LastScanTime = b.LastScanTime
+	}
+
+	for _, stats := range []ScanStats{a, b} {
+		for k, v := range stats.ScansByIP {
+			merged.ScansByIP[k] += v
+		}
+		for k, v := range stats.ScansByPort {
+			merged.ScansByPort[k] += v
+		}
+		for k, v := range stats.ScansByType {
+			merged.ScansByType[k] += v
+		}
+		for k, v := range stats.SeverityCounts {
+			merged.SeverityCounts[k] += v
+		}
+		for k, v := range stats.ScansByCountry {
+			merged.ScansByCountry[k] += v
+		}
+		for k, v := range stats.ScansByASN {
+			merged.ScansByASN[k] += v
+		}
+	}
+
+	if len(merged.ScansByIP) > 0 {
+		merged.UniqueIPs = len(merged.ScansByIP)
+	} else {
+		merged.UniqueIPs = a.UniqueIPs + b.UniqueIPs
+	}
+
+	return merged
+}
+
+// Add counts an event in the statistics
+func (s *ScanStats) Add(event ScanEvent) {
+	if s.ScansByIP == nil {
+		s.ScansByIP = make(map[string]int)
+		s.ScansByPort = make(map[int]int)
+		s.ScansByType = make(map[string]int)
+		s.SeverityCounts = make(map[Severity]int)
+		s.ScansByCountry = make(map[string]int)
+		s.ScansByASN = make(map[uint]int)
+	}
+
+	s.TotalScans++
+	if event.Timestamp.After(s.LastScanTime) {
+		s.LastScanTime = event.Timestamp
+	}
+	s.ScansByIP[event.SourceIP]++
+	s.UniqueIPs = len(s.ScansByIP)
+	s.ScansByPort[event.TargetPort]++
+	s.ScansByType[event.ScanType]++
+	s.SeverityCounts[event.Severity]++
+	if event.Country != "" {
+		s.ScansByCountry[event.Country]++
+	}
+	if event.ASN != 0 {
+		s.ScansByASN[event.ASN]++
+	}
+}
+
+// StatsFromEvents counts a set of events, e.g. those of a report period
+func StatsFromEvents(events []ScanEvent) ScanStats {
+	var stats ScanStats
+	for _, event := range events {
+		stats.Add(event)
+	}
+	return stats
 }
@@ -0,0 +1,42 @@
+package models
+
+import "strings"
+
+// toolSignatures map substrings of user agents and payloads to the scanning
+// tools that send them, most specific first
+var toolSignatures = []struct {
+	signature string
+	tool      string
+}{
+	{"nmap scripting engine", "nmap"},
+	{"nmap", "nmap"},
+	{"masscan", "masscan"},
+	{"zgrab", "zgrab"},
+	{"censysinspect", "censys"},
+	{"expanse", "expanse"},
+	{"internet-measurement", "driftnet"},
+	{"nuclei", "nuclei"},
+	{"nikto", "nikto"},
+	{"sqlmap", "sqlmap"},
+	{"python-requests", "python"},
+	{"go-http-client", "go"},
+	{"ssh-2.0-go", "go"},
+	{"libssh", "libssh"},
+	{"curl/", "curl"},
+	{"wget/", "wget"},
+}
+
+// Tool returns the name of the scanning tool the event's user agent or
+// payload points to, or an empty string when it is not recognised
+func (e ScanEvent) Tool() string {
+	text := strings.ToLower(e.UserAgent + "\n" + e.Payload)
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	for _, s := range toolSignatures {
+		if strings.Contains(text, s.signature) {
+			return s.tool
+		}
+	}
+	return ""
+}
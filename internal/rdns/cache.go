@@ -0,0 +1,85 @@
+package rdns
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached lookup result
+type cacheEntry struct {
+	ip       string
+	hostname string // Empty when the lookup failed
+	expires  time.Time
+}
+
+// Cache is a thread-safe LRU cache of lookup results, each expiring after
+// its own TTL
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used at the front
+	entries map[string]*list.Element
+}
+
+// NewCache creates a cache holding at most size results
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached hostname of ip. The second result reports whether
+// there is an unexpired result, which may be an empty hostname for a failed
+// lookup.
+func (c *Cache) Get(ip string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[ip]
+	if !ok {
+		return "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, ip)
+		return "", false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.hostname, true
+}
+
+// Put caches the hostname of ip for ttl, evicting the least recently used
+// result when the cache is full
+func (c *Cache) Put(ip, hostname string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if elem, ok := c.entries[ip]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.hostname, entry.expires = hostname, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[ip] = c.order.PushFront(&cacheEntry{ip: ip, hostname: hostname, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).ip)
+	}
+}
+
+// Len returns the number of cached results, including expired ones not yet
+// evicted
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
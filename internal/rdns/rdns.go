@@ -0,0 +1,188 @@
+package rdns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Defaults used for zero Options fields
+const (
+	DefaultTimeout     = time.Second * 2
+	DefaultConcurrency = 4
+	DefaultCacheSize   = 4096
+	DefaultTTL         = time.Hour
+	DefaultNegativeTTL = time.Minute * 5
+	DefaultQueueSize   = 256
+)
+
+// Options configures a Resolver
+type Options struct {
+	Server      string        // DNS server as host:port, empty uses the system resolver
+	Timeout     time.Duration // Timeout of a single lookup
+	Concurrency int           // Maximum number of lookups in flight
+	CacheSize   int           // Maximum number of cached results
+	TTL         time.Duration // How long a hostname is cached
+	NegativeTTL time.Duration // How long a failed lookup is cached
+	QueueSize   int           // Pending lookups beyond this are dropped
+
+	// OnResolved is called with the hostname found by a background lookup,
+	// e.g. to update the events published before it was known
+	OnResolved func(ip, hostname string)
+}
+
+// Resolver looks up the hostnames of addresses in the background. Callers
+// only ever read the cache, so they are never blocked by DNS.
+type Resolver struct {
+	opts     Options
+	resolver *net.Resolver
+	cache    *Cache
+	queue    chan string
+
+	mu      sync.Mutex
+	pending map[string]bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// New creates a resolver and starts its lookup workers
+func New(opts Options) *Resolver {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = DefaultCacheSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = DefaultNegativeTTL
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+
+	r := &Resolver{
+		opts:     opts,
+		resolver: net.DefaultResolver,
+		cache:    NewCache(opts.CacheSize),
+		queue:    make(chan string, opts.QueueSize),
+		pending:  make(map[string]bool),
+	}
+
+	if opts.Server != "" {
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, opts.Server)
+			},
+		}
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	return r
+}
+
+// Resolve looks up the hostname of ip, waiting for the answer
+func (r *Resolver) Resolve(ctx context.Context, ip string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+
+	names, err := r.resolver.LookupAddr(ctx, ip)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return strings.TrimSuffix(names[0], "."), nil
+}
+
+// Cached returns the cached hostname of ip without queuing a lookup on a
+// cache miss, for reading the hostnames of events already seen
+func (r *Resolver) Cached(ip string) (string, bool) {
+	hostname, _ := r.cache.Get(ip)
+	return hostname, hostname != ""
+}
+
+// Lookup returns the cached hostname of ip. On a cache miss a background
+// lookup is queued and Lookup returns immediately; later calls see the
+// result.
+func (r *Resolver) Lookup(ip string) (string, bool) {
+	if hostname, ok := r.cache.Get(ip); ok {
+		return hostname, hostname != ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.pending[ip] {
+		return "", false
+	}
+
+	select {
+	case r.queue <- ip:
+		r.pending[ip] = true
+	default:
+		// The queue is full, the address is queued again on a later event
+	}
+
+	return "", false
+}
+
+// Enrich adds the cached hostname of the event's source, queuing a lookup
+// if there is none yet
+func (r *Resolver) Enrich(event *models.ScanEvent) {
+	if hostname, ok := r.Lookup(event.SourceIP); ok {
+		event.Hostname = hostname
+	}
+}
+
+// Close stops the lookup workers and waits for lookups in flight
+func (r *Resolver) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// work performs queued lookups until the queue is closed
+func (r *Resolver) work() {
+	defer r.wg.Done()
+
+	for ip := range r.queue {
+		hostname, err := r.Resolve(context.Background(), ip)
+
+		ttl := r.opts.TTL
+		if err != nil || hostname == "" {
+			hostname, ttl = "", r.opts.NegativeTTL
+		}
+		r.cache.Put(ip, hostname, ttl)
+		if hostname != "" && r.opts.OnResolved != nil {
+			r.opts.OnResolved(ip, hostname)
+		}
+
+		r.mu.Lock()
+		delete(r.pending, ip)
+		r.mu.Unlock()
+	}
+}
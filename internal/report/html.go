@@ -0,0 +1,162 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// severityColors are the chart colours per severity
+var severityColors = map[models.Severity]string{
+	models.SeverityLow:      "#5fafd7",
+	models.SeverityMedium:   "#d7af5f",
+	models.SeverityHigh:     "#d7875f",
+	models.SeverityCritical: "#d75f5f",
+}
+
+// HTML writes the report as a self-contained HTML page with inline SVG charts
+func (r Report) HTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, struct {
+		Report
+		HourlyChart   template.HTML
+		SeverityChart template.HTML
+	}{
+		Report:        r,
+		HourlyChart:   r.hourlyChart(),
+		SeverityChart: r.severityChart(),
+	})
+}
+
+// hourlyChart renders the hourly histogram as an SVG bar chart
+func (r Report) hourlyChart() template.HTML {
+	const (
+		height    = 160
+		barWidth  = 16
+		gap       = 4
+		labelArea = 20
+	)
+
+	if len(r.Hourly) == 0 {
+		return ""
+	}
+
+	max := r.maxHourCount()
+	width := len(r.Hourly) * (barWidth + gap)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="Events per hour">`, width, height+labelArea)
+	for i, h := range r.Hourly {
+		barHeight := h.Count * height / max
+		x := i * (barWidth + gap)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#af5fd7"><title>%s: %d</title></rect>`,
+			x, height-barHeight, barWidth, barHeight, template.HTMLEscapeString(h.Hour.Format("2006-01-02 15:00")), h.Count)
+		if h.Hour.Hour()%6 == 0 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10">%02d</text>`, x, height+14, h.Hour.Hour())
+		}
+	}
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+// severityChart renders the severity breakdown as a stacked SVG bar
+func (r Report) severityChart() template.HTML {
+	const (
+		width  = 600
+		height = 24
+	)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="Severity breakdown">`, width, height)
+	if r.TotalEvents == 0 {
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#e4e4e4"/>`, width, height)
+	}
+
+	x := 0
+	for _, s := range r.Severities {
+		if s.Count == 0 {
+			continue
+		}
+		segment := s.Count * width / r.TotalEvents
+		fmt.Fprintf(&b, `<rect x="%d" width="%d" height="%d" fill="%s"><title>%s: %d</title></rect>`,
+			x, segment, height, severityColors[s.Severity], s.Severity, s.Count)
+		x += segment
+	}
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+// htmlTemplate is the HTML report layout, using no external assets
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time":  func(t time.Time) string { return t.Format(time.RFC3339) },
+	"ports": utils.JoinPorts,
+	"color": func(s models.Severity) template.CSS {
+		return template.CSS(severityColors[s])
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Port Scammer Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #262626; }
+h1 { color: #d7005f; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #d0d0d0; padding: 0.3em 0.8em; text-align: left; }
+th { background: #eeeeee; }
+.swatch { display: inline-block; width: 0.8em; height: 0.8em; margin-right: 0.4em; }
+</style>
+</head>
+<body>
+<h1>Port Scammer Report</h1>
+<p>Period: {{time .Start}} to {{time .End}}<br>Generated: {{time .GeneratedAt}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Scan events</th><td>{{.TotalEvents}}</td></tr>
+<tr><th>Unique sources</th><td>{{.UniqueSources}}</td></tr>
+<tr><th>New sources</th><td>{{len .NewSources}}</td></tr>
+<tr><th>Repeat sources</th><td>{{len .RepeatSources}}</td></tr>
+<tr><th>Incidents</th><td>{{len .Incidents}}</td></tr>
+</table>
+
+<h2>Severity Breakdown</h2>
+{{.SeverityChart}}
+<table>
+<tr><th>Severity</th><th>Events</th></tr>
+{{range .Severities}}<tr><td><span class="swatch" style="background: {{color .Severity}}"></span>{{.Severity}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Hourly Activity</h2>
+{{.HourlyChart}}
+
+<h2>Top Ports</h2>
+{{if .TopPorts}}<table>
+<tr><th>Port</th><th>Events</th><th>Sources</th></tr>
+{{range .TopPorts}}<tr><td>{{.Key}}</td><td>{{.Count}}</td><td>{{.UniqueIPs}}</td></tr>
+{{end}}</table>{{else}}<p>No events.</p>{{end}}
+
+<h2>Top Sources</h2>
+{{if .TopSources}}<table>
+<tr><th>Source</th><th>Events</th><th>Ports</th><th>Last Seen</th></tr>
+{{range .TopSources}}<tr><td>{{.Key}}</td><td>{{.Count}}</td><td>{{.UniquePorts}}</td><td>{{time .LastSeen}}</td></tr>
+{{end}}</table>{{else}}<p>No events.</p>{{end}}
+
+<h2>New and Repeat Sources</h2>
+<p>New: {{range $i, $ip := .NewSources}}{{if $i}}, {{end}}{{$ip}}{{else}}none{{end}}</p>
+<p>Repeat: {{range $i, $ip := .RepeatSources}}{{if $i}}, {{end}}{{$ip}}{{else}}none{{end}}</p>
+
+<h2>Incident Timeline</h2>
+{{if .Incidents}}<table>
+<tr><th>First Seen</th><th>Last Seen</th><th>Source</th><th>Events</th><th>Ports</th><th>Severity</th></tr>
+{{range .Incidents}}<tr><td>{{time .FirstSeen}}</td><td>{{time .LastSeen}}</td><td>{{.SourceIP}}</td><td>{{.EventCount}}</td><td>{{ports .Ports}}</td><td>{{.Severity}}</td></tr>
+{{end}}</table>{{else}}<p>No incidents.</p>{{end}}
+</body>
+</html>
+`))
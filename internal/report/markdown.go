@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// Markdown writes the report as Markdown
+func (r Report) Markdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# Port Scammer Report\n\n")
+	fmt.Fprintf(b, "Period: %s to %s\n\n", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+
+	fmt.Fprintf(b, "## Summary\n\n")
+	fmt.Fprintf(b, "| Metric | Value |\n| --- | --- |\n")
+	fmt.Fprintf(b, "| Scan events | %d |\n", r.TotalEvents)
+	fmt.Fprintf(b, "| Unique sources | %d |\n", r.UniqueSources)
+	fmt.Fprintf(b, "| New sources | %d |\n", len(r.NewSources))
+	fmt.Fprintf(b, "| Repeat sources | %d |\n", len(r.RepeatSources))
+	fmt.Fprintf(b, "| Incidents | %d |\n\n", len(r.Incidents))
+
+	fmt.Fprintf(b, "## Severity Breakdown\n\n")
+	fmt.Fprintf(b, "| Severity | Events |\n| --- | --- |\n")
+	for _, s := range r.Severities {
+		fmt.Fprintf(b, "| %s | %d |\n", s.Severity, s.Count)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(b, "## Top Ports\n\n")
+	if len(r.TopPorts) == 0 {
+		b.WriteString("No events.\n\n")
+	} else {
+		fmt.Fprintf(b, "| Port | Events | Sources |\n| --- | --- | --- |\n")
+		for _, g := range r.TopPorts {
+			fmt.Fprintf(b, "| %s | %d | %d |\n", g.Key, g.Count, g.UniqueIPs)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(b, "## Top Sources\n\n")
+	if len(r.TopSources) == 0 {
+		b.WriteString("No events.\n\n")
+	} else {
+		fmt.Fprintf(b, "| Source | Events | Ports | Last Seen |\n| --- | --- | --- | --- |\n")
+		for _, g := range r.TopSources {
+			fmt.Fprintf(b, "| %s | %d | %d | %s |\n", g.Key, g.Count, g.UniquePorts, g.LastSeen.Format(time.RFC3339))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(b, "## New and Repeat Sources\n\n")
+	fmt.Fprintf(b, "- New: %s\n", listOrNone(r.NewSources))
+	fmt.Fprintf(b, "- Repeat: %s\n\n", listOrNone(r.RepeatSources))
+
+	fmt.Fprintf(b, "## Incident Timeline\n\n")
+	if len(r.Incidents) == 0 {
+		b.WriteString("No incidents.\n\n")
+	} else {
+		fmt.Fprintf(b, "| First Seen | Last Seen | Source | Events | Ports | Severity |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, inc := range r.Incidents {
+			fmt.Fprintf(b, "| %s | %s | %s | %d | %s | %s |\n",
+				inc.FirstSeen.Format(time.RFC3339), inc.LastSeen.Format(time.RFC3339),
+				inc.SourceIP, inc.EventCount, utils.JoinPorts(inc.Ports), inc.Severity)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(b, "## Hourly Activity\n\n")
+	fmt.Fprintf(b, "```text\n")
+	max := r.maxHourCount()
+	for _, h := range r.Hourly {
+		bar := strings.Repeat("#", h.Count*40/max)
+		fmt.Fprintf(b, "%s %5d %s\n", h.Hour.Format("2006-01-02 15:00"), h.Count, bar)
+	}
+	fmt.Fprintf(b, "```\n")
+
+	return b.Flush()
+}
+
+// listOrNone joins a list of strings, or returns "none" for an empty list
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
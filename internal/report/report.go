@@ -0,0 +1,171 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/store"
+)
+
+// topN is the number of entries shown in the top ports and sources lists
+const topN = 10
+
+// Report summarises the scan activity within a period
+type Report struct {
+	Start       time.Time
+	End         time.Time
+	GeneratedAt time.Time
+
+	TotalEvents   int
+	UniqueSources int
+	NewSources    []string // Sources first seen within the period
+	RepeatSources []string // Sources also seen before the period
+
+	TopPorts   []models.EventGroup
+	TopSources []models.EventGroup
+	Severities []SeverityCount
+	Incidents  []models.Incident
+	Hourly     []HourCount
+
+	Stats models.ScanStats // Statistics of the events within the period
+}
+
+// SeverityCount is the number of events with a severity
+type SeverityCount struct {
+	Severity models.Severity
+	Count    int
+}
+
+// HourCount is the number of events within the hour starting at Hour
+type HourCount struct {
+	Hour  time.Time
+	Count int
+}
+
+// Build creates a report for the events within [start, end). known holds the
+// sources seen before start and is used to tell new from repeat sources.
+func Build(events []models.ScanEvent, known map[string]bool, start, end time.Time, incidentGap time.Duration) Report {
+	filter := models.EventFilter{Since: start, Until: end}
+	events = filter.Apply(events)
+
+	r := Report{
+		Start:       start,
+		End:         end,
+		GeneratedAt: time.Now(),
+		TotalEvents: len(events),
+		Stats:       models.StatsFromEvents(events),
+	}
+
+	sources := make(map[string]bool)
+	for _, event := range events {
+		if sources[event.SourceIP] {
+			continue
+		}
+		sources[event.SourceIP] = true
+		if known[event.SourceIP] {
+			r.RepeatSources = append(r.RepeatSources, event.SourceIP)
+		} else {
+			r.NewSources = append(r.NewSources, event.SourceIP)
+		}
+	}
+	r.UniqueSources = len(sources)
+	sort.Strings(r.NewSources)
+	sort.Strings(r.RepeatSources)
+
+	r.TopPorts, _ = models.GroupEvents(events, models.GroupByPort)
+	if len(r.TopPorts) > topN {
+		r.TopPorts = r.TopPorts[:topN]
+	}
+	r.TopSources, _ = models.GroupEvents(events, models.GroupByIP)
+	if len(r.TopSources) > topN {
+		r.TopSources = r.TopSources[:topN]
+	}
+
+	counts := make(map[models.Severity]int)
+	for _, event := range events {
+		counts[event.Severity]++
+	}
+	for _, severity := range []models.Severity{models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow} {
+		r.Severities = append(r.Severities, SeverityCount{Severity: severity, Count: counts[severity]})
+	}
+
+	r.Incidents = models.GroupIncidents(events, incidentGap)
+
+	hours := make(map[time.Time]int)
+	for _, event := range events {
+		hours[event.Timestamp.UTC().Truncate(time.Hour)]++
+	}
+	for hour := start.UTC().Truncate(time.Hour); hour.Before(end); hour = hour.Add(time.Hour) {
+		r.Hourly = append(r.Hourly, HourCount{Hour: hour, Count: hours[hour]})
+	}
+
+	return r
+}
+
+// FromStore builds a report for the period ending at end from a store
+func FromStore(st *store.Store, period time.Duration, end time.Time, incidentGap time.Duration) (Report, error) {
+	start := end.Add(-period)
+
+	events, err := st.Events(models.EventFilter{Since: start, Until: end})
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Only the sources of the period are looked up in the index
+	var ips []string
+	for _, event := range events {
+		ips = append(ips, event.SourceIP)
+	}
+	sort.Strings(ips)
+	known, err := st.SeenBefore(slices.Compact(ips), start)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Build(events, known, start, end, incidentGap), nil
+}
+
+// NextRun returns the end of the first report period after now. Periods are
+// aligned to local midnight, so daily reports cover whole days and hourly
+// reports whole hours.
+func NextRun(now time.Time, interval time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	periods := now.Sub(midnight)/interval + 1
+	return midnight.Add(periods * interval)
+}
+
+// Write writes the report in the given format, md or html
+func (r Report) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "md", "markdown":
+		return r.Markdown(w)
+	case "html":
+		return r.HTML(w)
+	default:
+		return fmt.Errorf("unknown report format %q: must be md or html", format)
+	}
+}
+
+// Extension returns the file extension used for a report format
+func Extension(format string) string {
+	if strings.ToLower(format) == "html" {
+		return ".html"
+	}
+	return ".md"
+}
+
+// maxHourCount returns the largest hourly count, at least 1
+func (r Report) maxHourCount() int {
+	max := 1
+	for _, h := range r.Hourly {
+		if h.Count > max {
+			max = h.Count
+		}
+	}
+	return max
+}
@@ -0,0 +1,572 @@
+package rules
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// DefaultWindow is the window of aggregates in a comparison without "within"
+const DefaultWindow = time.Minute
+
+// evalContext is what a condition is evaluated against
+type evalContext struct {
+	event   models.ScanEvent
+	history *history
+}
+
+// expr is a compiled boolean condition
+type expr interface {
+	eval(ctx evalContext) bool
+	// window returns the longest aggregate window used, or 0 without aggregates
+	window() time.Duration
+}
+
+// operand is a compiled value in a condition
+type operand interface {
+	typ() valueType
+	value(ctx evalContext, window time.Duration) interface{}
+	aggregated() bool
+}
+
+// logical combines two conditions with and or or
+type logical struct {
+	and         bool
+	left, right expr
+}
+
+func (e *logical) eval(ctx evalContext) bool {
+	if e.and {
+		return e.left.eval(ctx) && e.right.eval(ctx)
+	}
+	return e.left.eval(ctx) || e.right.eval(ctx)
+}
+
+func (e *logical) window() time.Duration {
+	return maxDuration(e.left.window(), e.right.window())
+}
+
+// negation inverts a condition
+type negation struct {
+	inner expr
+}
+
+func (e *negation) eval(ctx evalContext) bool { return !e.inner.eval(ctx) }
+
+func (e *negation) window() time.Duration { return e.inner.window() }
+
+// comparison compares two operands
+type comparison struct {
+	op          string
+	left, right operand
+	within      time.Duration
+}
+
+func (e *comparison) eval(ctx evalContext) bool {
+	l, r := e.left.value(ctx, e.within), e.right.value(ctx, e.within)
+
+	if ln, ok := l.(float64); ok {
+		rn := r.(float64)
+		switch e.op {
+		case "==":
+			return ln == rn
+		case "!=":
+			return ln != rn
+		case ">":
+			return ln > rn
+		case ">=":
+			return ln >= rn
+		case "<":
+			return ln < rn
+		case "<=":
+			return ln <= rn
+		}
+		return false
+	}
+
+	equal := strings.EqualFold(fmt.Sprint(l), fmt.Sprint(r))
+	if e.op == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+func (e *comparison) window() time.Duration {
+	if e.left.aggregated() || e.right.aggregated() {
+		return e.within
+	}
+	return 0
+}
+
+// membership tests whether a value is in a list, or an address in one of
+// a list of CIDR ranges
+type membership struct {
+	left     operand
+	list     operand
+	prefixes []netip.Prefix // Set when matching an address against ranges
+	negated  bool
+}
+
+func (e *membership) eval(ctx evalContext) bool {
+	return e.contains(ctx) != e.negated
+}
+
+func (e *membership) contains(ctx evalContext) bool {
+	value := e.left.value(ctx, DefaultWindow)
+
+	if e.prefixes != nil {
+		addr, err := netip.ParseAddr(value.(string))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range e.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, item := range e.list.value(ctx, DefaultWindow).([]interface{}) {
+		if s, ok := value.(string); ok {
+			if strings.EqualFold(s, fmt.Sprint(item)) {
+				return true
+			}
+		} else if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *membership) window() time.Duration {
+	if e.left.aggregated() {
+		return DefaultWindow
+	}
+	return 0
+}
+
+// substring tests whether a string contains another
+type substring struct {
+	left, right operand
+}
+
+func (e *substring) eval(ctx evalContext) bool {
+	return strings.Contains(strings.ToLower(e.left.value(ctx, 0).(string)), strings.ToLower(e.right.value(ctx, 0).(string)))
+}
+
+func (e *substring) window() time.Duration { return 0 }
+
+// match tests a string against a regular expression
+type match struct {
+	left operand
+	re   *regexp.Regexp
+}
+
+func (e *match) eval(ctx evalContext) bool {
+	return e.re.MatchString(e.left.value(ctx, 0).(string))
+}
+
+func (e *match) window() time.Duration { return 0 }
+
+// truth uses a boolean operand, such as a function call, as condition
+type truth struct {
+	operand operand
+}
+
+func (e *truth) eval(ctx evalContext) bool {
+	return e.operand.value(ctx, DefaultWindow).(bool)
+}
+
+func (e *truth) window() time.Duration { return 0 }
+
+// fieldRef reads an event field
+type fieldRef struct {
+	name string
+	def  field
+}
+
+func (o *fieldRef) typ() valueType { return o.def.typ }
+
+func (o *fieldRef) value(ctx evalContext, _ time.Duration) interface{} { return o.def.get(ctx.event) }
+
+func (o *fieldRef) aggregated() bool { return false }
+
+// aggregateRef computes a per-source aggregate over the comparison's window
+type aggregateRef struct {
+	name string
+	fn   aggregate
+}
+
+func (o *aggregateRef) typ() valueType { return typeNumber }
+
+func (o *aggregateRef) value(ctx evalContext, window time.Duration) interface{} {
+	if ctx.history == nil {
+		return float64(0)
+	}
+	return o.fn(ctx.history, ctx.event.Timestamp, window)
+}
+
+func (o *aggregateRef) aggregated() bool { return true }
+
+// literal is a constant value
+type literal struct {
+	t valueType
+	v interface{}
+}
+
+func (o *literal) typ() valueType { return o.t }
+
+func (o *literal) value(evalContext, time.Duration) interface{} { return o.v }
+
+func (o *literal) aggregated() bool { return false }
+
+// call is a function call
+type call struct {
+	name string
+	arg  operand
+}
+
+func (o *call) typ() valueType { return typeBool }
+
+func (o *call) value(ctx evalContext, window time.Duration) interface{} {
+	switch o.name {
+	case "private":
+		return utils.IsPrivateIP(o.arg.value(ctx, window).(string))
+	default:
+		return false
+	}
+}
+
+func (o *call) aggregated() bool { return false }
+
+// functions are the functions usable in conditions with their argument type
+var functions = map[string]valueType{
+	"private": typeIP,
+}
+
+// parser is a recursive descent parser for conditions
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// compile parses and type checks a condition
+func compile(condition string) (expr, error) {
+	tokens, err := lex(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+	return e, nil
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next returns the current token and advances
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the current token is the keyword word, consuming
+// it if so
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes a token of the given kind
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at position %d, got %s", what, t.pos, t)
+	}
+	return t, nil
+}
+
+// parseOr parses conditions joined by or
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses conditions joined by and
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseNot parses an optionally negated condition
+func (p *parser) parseNot() (expr, error) {
+	if p.keyword("not") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{inner: inner}, nil
+	}
+	return p.parseCondition()
+}
+
+// parseCondition parses a parenthesised condition, a comparison, a
+// membership test, a match or a boolean function call
+func (p *parser) parseCondition() (expr, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "\")\""); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	start := p.peek()
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokOp:
+		p.next()
+		return p.parseComparison(left, t)
+
+	case p.keyword("in"):
+		return p.parseMembership(left, false)
+
+	case t.kind == tokIdent && strings.EqualFold(t.text, "not") && p.tokens[p.pos+1].kind == tokIdent && strings.EqualFold(p.tokens[p.pos+1].text, "in"):
+		p.pos += 2
+		return p.parseMembership(left, true)
+
+	case p.keyword("matches"):
+		re, err := p.expect(tokRegex, "regular expression")
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != typeString {
+			return nil, fmt.Errorf("matches needs a string at position %d, got %s", start.pos, left.typ())
+		}
+		compiled, err := regexp.Compile(re.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", re.pos, err)
+		}
+		return &match{left: left, re: compiled}, nil
+
+	case p.keyword("contains"):
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() == typeList {
+			return &membership{left: right, list: left}, nil
+		}
+		if left.typ() != typeString || right.typ() != typeString {
+			return nil, fmt.Errorf("contains needs strings or a list at position %d", start.pos)
+		}
+		return &substring{left: left, right: right}, nil
+	}
+
+	if left.typ() != typeBool {
+		return nil, fmt.Errorf("expected a condition at position %d, got %s %s", start.pos, left.typ(), start)
+	}
+	return &truth{operand: left}, nil
+}
+
+// parseComparison parses the right hand side of a comparison and an
+// optional window
+func (p *parser) parseComparison(left operand, op token) (expr, error) {
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	lt, rt := left.typ(), right.typ()
+	if lt == typeIP {
+		lt = typeString
+	}
+	if rt == typeIP {
+		rt = typeString
+	}
+	if lt != rt || lt == typeList || lt == typeBool {
+		return nil, fmt.Errorf("cannot compare %s with %s at position %d", left.typ(), right.typ(), op.pos)
+	}
+	if lt == typeString && op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("strings only support == and != at position %d", op.pos)
+	}
+
+	c := &comparison{op: op.text, left: left, right: right, within: DefaultWindow}
+	if p.keyword("within") {
+		t, err := p.expect(tokDuration, "duration")
+		if err != nil {
+			return nil, err
+		}
+		if !left.aggregated() && !right.aggregated() {
+			return nil, fmt.Errorf("within needs an aggregate such as events or distinct_ports at position %d", t.pos)
+		}
+		if c.within, err = utils.ParseDuration(t.text); err != nil || c.within <= 0 {
+			return nil, fmt.Errorf("invalid duration %q at position %d", t.text, t.pos)
+		}
+	}
+	return c, nil
+}
+
+// parseMembership parses the list of a membership test
+func (p *parser) parseMembership(left operand, negated bool) (expr, error) {
+	at := p.peek().pos
+	list, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if list.typ() != typeList {
+		return nil, fmt.Errorf("in needs a list at position %d, got %s", at, list.typ())
+	}
+
+	m := &membership{left: left, list: list, negated: negated}
+	if left.typ() == typeIP {
+		lit, ok := list.(*literal)
+		if !ok {
+			return nil, fmt.Errorf("an address can only be matched against a list of ranges at position %d", at)
+		}
+		m.prefixes = []netip.Prefix{}
+		for _, item := range lit.v.([]interface{}) {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected address or CIDR range at position %d, got %v", at, item)
+			}
+			prefix, err := iplist.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("at position %d: %w", at, err)
+			}
+			m.prefixes = append(m.prefixes, prefix)
+		}
+	}
+	return m, nil
+}
+
+// parseOperand parses a field, aggregate, literal, list or function call
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return &literal{t: typeNumber, v: n}, nil
+
+	case tokString:
+		return &literal{t: typeString, v: t.text}, nil
+
+	case tokLBracket:
+		var items []interface{}
+		for p.peek().kind != tokRBracket {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			lit, ok := item.(*literal)
+			if !ok || lit.t == typeList {
+				return nil, fmt.Errorf("lists may only hold numbers and strings, at position %d", t.pos)
+			}
+			items = append(items, lit.v)
+			if p.peek().kind == tokComma {
+				p.next()
+			} else if p.peek().kind != tokRBracket {
+				return nil, fmt.Errorf("expected \",\" or \"]\" at position %d, got %s", p.peek().pos, p.peek())
+			}
+		}
+		p.next()
+		return &literal{t: typeList, v: items}, nil
+
+	case tokIdent:
+		name := strings.ToLower(t.text)
+		if argType, ok := functions[name]; ok {
+			if _, err := p.expect(tokLParen, "\"(\""); err != nil {
+				return nil, err
+			}
+			arg, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if arg.typ() != argType {
+				return nil, fmt.Errorf("%s needs an %s at position %d, got %s", name, argType, t.pos, arg.typ())
+			}
+			if _, err := p.expect(tokRParen, "\")\""); err != nil {
+				return nil, err
+			}
+			return &call{name: name, arg: arg}, nil
+		}
+		if f, ok := fields[name]; ok {
+			return &fieldRef{name: name, def: f}, nil
+		}
+		if fn, ok := aggregates[name]; ok {
+			return &aggregateRef{name: name, fn: fn}, nil
+		}
+		if s, ok := severities[name]; ok {
+			return &literal{t: typeNumber, v: float64(s)}, nil
+		}
+		return nil, fmt.Errorf("unknown field %q at position %d", t.text, t.pos)
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+}
+
+// maxDuration returns the longer of two durations
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+package rules
+
+import (
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// valueType is the static type of an operand
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeString
+	typeIP
+	typeBool
+	typeList
+)
+
+// String returns the name of the type for error messages
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	case typeIP:
+		return "address"
+	case typeBool:
+		return "boolean"
+	case typeList:
+		return "list"
+	default:
+		return "unknown"
+	}
+}
+
+// field is an event field usable in conditions
+type field struct {
+	typ valueType
+	get func(event models.ScanEvent) interface{}
+}
+
+// fields are the event fields usable in conditions
+var fields = map[string]field{
+	"src":         {typeIP, func(e models.ScanEvent) interface{} { return e.SourceIP }},
+	"src_port":    {typeNumber, func(e models.ScanEvent) interface{} { return float64(e.SourcePort) }},
+	"port":        {typeNumber, func(e models.ScanEvent) interface{} { return float64(e.TargetPort) }},
+	"protocol":    {typeString, func(e models.ScanEvent) interface{} { return e.Protocol }},
+	"type":        {typeString, func(e models.ScanEvent) interface{} { return e.ScanType }},
+	"severity":    {typeNumber, func(e models.ScanEvent) interface{} { return float64(e.Severity) }},
+	"description": {typeString, func(e models.ScanEvent) interface{} { return e.Description }},
+	"user_agent":  {typeString, func(e models.ScanEvent) interface{} { return e.UserAgent }},
+	"payload":     {typeString, func(e models.ScanEvent) interface{} { return e.Payload }},
+	"country":     {typeString, func(e models.ScanEvent) interface{} { return e.Country }},
+	"city":        {typeString, func(e models.ScanEvent) interface{} { return e.City }},
+	"asn":         {typeNumber, func(e models.ScanEvent) interface{} { return float64(e.ASN) }},
+	"org":         {typeString, func(e models.ScanEvent) interface{} { return e.Org }},
+	"hostname":    {typeString, func(e models.ScanEvent) interface{} { return e.Hostname }},
+	"tags": {typeList, func(e models.ScanEvent) interface{} {
+		tags := make([]interface{}, len(e.Tags))
+		for i, tag := range e.Tags {
+			tags[i] = tag
+		}
+		return tags
+	}},
+}
+
+// aggregate is a per-source field computed over a time window
+type aggregate func(h *history, at time.Time, window time.Duration) float64
+
+// aggregates are the per-source fields usable in conditions
+var aggregates = map[string]aggregate{
+	"events":         (*history).count,
+	"distinct_ports": (*history).distinctPorts,
+}
+
+// severities are the severity names usable as constants in conditions
+var severities = map[string]models.Severity{
+	"low":      models.SeverityLow,
+	"medium":   models.SeverityMedium,
+	"high":     models.SeverityHigh,
+	"critical": models.SeverityCritical,
+}
+
+// observation is an event remembered for per-source aggregates
+type observation struct {
+	at   time.Time
+	port int
+}
+
+// history holds the recent events of a single source
+type history struct {
+	observations []observation
+}
+
+// add remembers an event and forgets those older than keep
+func (h *history) add(at time.Time, port int, keep time.Duration) {
+	h.observations = append(h.observations, observation{at: at, port: port})
+	h.prune(at.Add(-keep))
+}
+
+// prune forgets the events before cutoff
+func (h *history) prune(cutoff time.Time) {
+	i := 0
+	for i < len(h.observations) && h.observations[i].at.Before(cutoff) {
+		i++
+	}
+	h.observations = h.observations[i:]
+}
+
+// count returns the number of events in the window ending at at
+func (h *history) count(at time.Time, window time.Duration) float64 {
+	cutoff := at.Add(-window)
+	n := 0
+	for _, o := range h.observations {
+		if !o.at.Before(cutoff) && !o.at.After(at) {
+			n++
+		}
+	}
+	return float64(n)
+}
+
+// distinctPorts returns the number of distinct ports in the window ending at at
+func (h *history) distinctPorts(at time.Time, window time.Duration) float64 {
+	cutoff := at.Add(-window)
+	ports := make(map[int]bool)
+	for _, o := range h.observations {
+		if !o.at.Before(cutoff) && !o.at.After(at) {
+			ports[o.port] = true
+		}
+	}
+	return float64(len(ports))
+}
@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a token in a condition
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokRegex
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+// token is a lexical token of a condition
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// String returns a description of the token for error messages
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of condition"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits a condition into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+		start := i
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+
+		case c == '(' || c == ')' || c == '[' || c == ']' || c == ',':
+			kinds := map[rune]tokenKind{'(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket, ',': tokComma}
+			tokens = append(tokens, token{kind: kinds[c], text: string(c), pos: start})
+			i++
+
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			i++
+			if i < len(input) && input[i] == '=' {
+				i++
+			}
+			op := input[start:i]
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("unexpected %q at position %d", op, start)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+
+		case c == '"' || c == '\'':
+			i++
+			var b strings.Builder
+			for i < len(input) && rune(input[i]) != c {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+
+		case c == '/':
+			// A slash only starts a regular expression after "matches"
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != tokIdent || tokens[len(tokens)-1].text != "matches" {
+				return nil, fmt.Errorf("unexpected \"/\" at position %d", start)
+			}
+			i++
+			var b strings.Builder
+			for i < len(input) && input[i] != '/' {
+				if input[i] == '\\' && i+1 < len(input) && input[i+1] == '/' {
+					i++
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated regular expression at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokRegex, text: b.String(), pos: start})
+
+		case unicode.IsDigit(c):
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.') {
+				i++
+			}
+			kind := tokNumber
+			if i < len(input) && unicode.IsLetter(rune(input[i])) {
+				for i < len(input) && unicode.IsLetter(rune(input[i])) {
+					i++
+				}
+				kind = tokDuration
+			}
+			tokens = append(tokens, token{kind: kind, text: input[start:i], pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) || input[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], pos: start})
+
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, start)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
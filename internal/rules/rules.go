@@ -0,0 +1,227 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what happens to an event matching a rule
+type Action struct {
+	Severity string   `yaml:"severity,omitempty" json:"severity,omitempty"` // Set the event's severity
+	Tags     []string `yaml:"tags,omitempty" json:"tags,omitempty"`         // Add tags to the event
+	Alert    bool     `yaml:"alert,omitempty" json:"alert,omitempty"`       // Raise an alert
+	Block    bool     `yaml:"block,omitempty" json:"block,omitempty"`       // Add the source to the blacklist
+	Suppress bool     `yaml:"suppress,omitempty" json:"suppress,omitempty"` // Drop the event
+}
+
+// Rule is a user-defined detection rule
+type Rule struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Condition   string `yaml:"condition"`
+	Action      Action `yaml:"action"`
+	Disabled    bool   `yaml:"disabled,omitempty"`
+
+	expr     expr
+	severity *models.Severity
+}
+
+// file is the layout of a rules file
+type file struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Parse parses and validates rules in YAML
+func Parse(data []byte) ([]*Rule, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	names := make(map[string]bool)
+	var errs []error
+	for i, rule := range f.Rules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("rule %d: missing name", i+1))
+			continue
+		}
+		if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("rule %q: duplicate name", rule.Name))
+		}
+		names[rule.Name] = true
+
+		if err := rule.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", rule.Name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return f.Rules, nil
+}
+
+// LoadFile reads and validates the rules in the YAML file at path
+func LoadFile(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// compile compiles the rule's condition and validates its action
+func (r *Rule) compile() error {
+	if r.Condition == "" {
+		return errors.New("missing condition")
+	}
+
+	e, err := compile(r.Condition)
+	if err != nil {
+		return fmt.Errorf("invalid condition: %w", err)
+	}
+	r.expr = e
+
+	a := r.Action
+	if a.Severity == "" && len(a.Tags) == 0 && !a.Alert && !a.Block && !a.Suppress {
+		return errors.New("missing action")
+	}
+	if a.Severity != "" {
+		severity, err := models.ParseSeverity(a.Severity)
+		if err != nil {
+			return fmt.Errorf("invalid action: %w", err)
+		}
+		r.severity = &severity
+	}
+
+	return nil
+}
+
+// Result is the outcome of evaluating the rules against an event
+type Result struct {
+	Matched  []string         `json:"matched"`
+	Severity *models.Severity `json:"severity,omitempty"`
+	Tags     []string         `json:"tags,omitempty"`
+	Alert    bool             `json:"alert,omitempty"`
+	Block    bool             `json:"block,omitempty"`
+	Suppress bool             `json:"suppress,omitempty"`
+}
+
+// Apply sets the severity and adds the tags of the matched rules to event
+func (r Result) Apply(event *models.ScanEvent) {
+	if r.Severity != nil {
+		event.Severity = *r.Severity
+	}
+	for _, tag := range r.Tags {
+		event.AddTag(tag)
+	}
+}
+
+// Engine evaluates rules against a stream of events, keeping the recent
+// events of each source for aggregates. The rules can be replaced while it
+// is in use.
+type Engine struct {
+	mu        sync.Mutex
+	rules     []*Rule
+	keep      time.Duration // Longest aggregate window of the rules
+	sources   map[string]*history
+	lastSweep time.Time
+}
+
+// NewEngine creates an engine evaluating rules
+func NewEngine(rules []*Rule) *Engine {
+	e := &Engine{sources: make(map[string]*history)}
+	e.SetRules(rules)
+	return e
+}
+
+// SetRules replaces the rules, keeping the per-source history
+func (e *Engine) SetRules(rules []*Rule) {
+	var keep time.Duration
+	for _, rule := range rules {
+		keep = maxDuration(keep, rule.expr.window())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.keep = keep
+}
+
+// Rules returns the current rules
+func (e *Engine) Rules() []*Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rules
+}
+
+// Evaluate records the event in its source's history and returns the
+// combined actions of the rules matching it. Rules are applied in order, so
+// a later severity wins.
+func (e *Engine) Evaluate(event models.ScanEvent) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var result Result
+
+	h := e.sources[event.SourceIP]
+	if e.keep > 0 {
+		if h == nil {
+			h = &history{}
+			e.sources[event.SourceIP] = h
+		}
+		h.add(event.Timestamp, event.TargetPort, e.keep)
+		e.sweep(event.Timestamp)
+	}
+
+	ctx := evalContext{event: event, history: h}
+	for _, rule := range e.rules {
+		if rule.Disabled || !rule.expr.eval(ctx) {
+			continue
+		}
+
+		result.Matched = append(result.Matched, rule.Name)
+		if rule.severity != nil {
+			severity := *rule.severity
+			result.Severity = &severity
+		}
+		result.Tags = append(result.Tags, rule.Action.Tags...)
+		result.Alert = result.Alert || rule.Action.Alert
+		result.Block = result.Block || rule.Action.Block
+		result.Suppress = result.Suppress || rule.Action.Suppress
+	}
+
+	return result
+}
+
+// sweep forgets sources without events in the longest window, at most
+// once per window
+func (e *Engine) sweep(now time.Time) {
+	if now.Sub(e.lastSweep) < e.keep {
+		return
+	}
+	e.lastSweep = now
+
+	cutoff := now.Add(-e.keep)
+	for ip, h := range e.sources {
+		h.prune(cutoff)
+		if len(h.observations) == 0 {
+			delete(e.sources, ip)
+		}
+	}
+}
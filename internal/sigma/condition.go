@@ -0,0 +1,216 @@
+package sigma
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// node is a node of a compiled detection condition
+type node interface {
+	eval(event models.ScanEvent) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) eval(event models.ScanEvent) bool {
+	return n.left.eval(event) && n.right.eval(event)
+}
+
+type orNode struct{ left, right node }
+
+func (n *orNode) eval(event models.ScanEvent) bool {
+	return n.left.eval(event) || n.right.eval(event)
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(event models.ScanEvent) bool {
+	return !n.operand.eval(event)
+}
+
+// searchNode refers to a search identifier
+type searchNode struct{ search search }
+
+func (n *searchNode) eval(event models.ScanEvent) bool {
+	return n.search.match(event)
+}
+
+// ofNode matches when one or all of a group of searches do
+type ofNode struct {
+	all      bool
+	searches []search
+}
+
+func (n *ofNode) eval(event models.ScanEvent) bool {
+	for _, s := range n.searches {
+		matched := s.match(event)
+		if matched && !n.all {
+			return true
+		}
+		if !matched && n.all {
+			return false
+		}
+	}
+	return n.all
+}
+
+// conditionParser is a recursive-descent parser for detection conditions.
+// Operators bind in the order not, and, or.
+type conditionParser struct {
+	tokens   []string
+	pos      int
+	searches map[string]search
+}
+
+// parseCondition compiles a detection condition referring to searches.
+// Aggregations after a pipe are not supported.
+func parseCondition(condition string, searches map[string]search) (node, error) {
+	if strings.Contains(condition, "|") {
+		return nil, errors.New("aggregations are not supported")
+	}
+
+	p := &conditionParser{tokens: tokenize(condition), searches: searches}
+	if len(p.tokens) == 0 {
+		return nil, errors.New("empty condition")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return n, nil
+}
+
+// tokenize splits a condition into words and parentheses
+func tokenize(condition string) []string {
+	condition = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(condition)
+	return strings.Fields(condition)
+}
+
+// peek returns the lower-cased next token, or "" at the end
+func (p *conditionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return strings.ToLower(p.tokens[p.pos])
+}
+
+func (p *conditionParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (node, error) {
+	if p.peek() == "not" {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (node, error) {
+	token := p.peek()
+	switch token {
+	case "":
+		return nil, errors.New("unexpected end of condition")
+	case "(":
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return n, nil
+	case ")", "and", "or", "of", "them":
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	case "1", "any", "all":
+		p.pos++
+		if p.peek() != "of" {
+			return nil, fmt.Errorf("expected of after %q", token)
+		}
+		p.pos++
+		return p.parseOf(token == "all")
+	}
+
+	name := p.tokens[p.pos]
+	p.pos++
+	s, ok := p.searches[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown search %q", name)
+	}
+	return &searchNode{s}, nil
+}
+
+// parseOf parses the target of "1 of" and "all of", which is "them" or a
+// search name pattern with wildcards
+func (p *conditionParser) parseOf(all bool) (node, error) {
+	pattern := p.peek()
+	if pattern == "" {
+		return nil, errors.New("unexpected end of condition")
+	}
+	pattern = p.tokens[p.pos]
+	p.pos++
+	if strings.ToLower(pattern) == "them" {
+		pattern = "*"
+	}
+
+	var names []string
+	for name := range p.searches {
+		if ok, err := path.Match(pattern, name); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		} else if ok && !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no search matches %q", pattern)
+	}
+	sort.Strings(names)
+
+	n := &ofNode{all: all}
+	for _, name := range names {
+		n.searches = append(n.searches, p.searches[name])
+	}
+	return n, nil
+}
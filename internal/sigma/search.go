@@ -0,0 +1,321 @@
+package sigma
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// field reads a Sigma field from a scan event
+type field func(event models.ScanEvent) string
+
+// fields maps the Sigma field names of the firewall and network_connection
+// taxonomies onto scan event fields. Names are matched case-insensitively.
+var fields = map[string]field{
+	"src_ip":          func(e models.ScanEvent) string { return e.SourceIP },
+	"sourceip":        func(e models.ScanEvent) string { return e.SourceIP },
+	"source.ip":       func(e models.ScanEvent) string { return e.SourceIP },
+	"src_port":        func(e models.ScanEvent) string { return strconv.Itoa(e.SourcePort) },
+	"sourceport":      func(e models.ScanEvent) string { return strconv.Itoa(e.SourcePort) },
+	"source.port":     func(e models.ScanEvent) string { return strconv.Itoa(e.SourcePort) },
+	"dst_port":        func(e models.ScanEvent) string { return strconv.Itoa(e.TargetPort) },
+	"dest_port":       func(e models.ScanEvent) string { return strconv.Itoa(e.TargetPort) },
+	"destinationport": func(e models.ScanEvent) string { return strconv.Itoa(e.TargetPort) },
+	"destination.port": func(e models.ScanEvent) string {
+		return strconv.Itoa(e.TargetPort)
+	},
+	"protocol":          func(e models.ScanEvent) string { return e.Protocol },
+	"network.transport": func(e models.ScanEvent) string { return e.Protocol },
+	"sourcehostname":    func(e models.ScanEvent) string { return e.Hostname },
+	"src_host":          func(e models.ScanEvent) string { return e.Hostname },
+	"source.domain":     func(e models.ScanEvent) string { return e.Hostname },
+	"user_agent":        func(e models.ScanEvent) string { return e.UserAgent },
+	"useragent":         func(e models.ScanEvent) string { return e.UserAgent },
+	"payload":           func(e models.ScanEvent) string { return e.Payload },
+	"message":           func(e models.ScanEvent) string { return e.Description },
+	"scan_type":         func(e models.ScanEvent) string { return e.ScanType },
+	"src_country":       func(e models.ScanEvent) string { return e.Country },
+	"source.geo.country_iso_code": func(e models.ScanEvent) string {
+		return e.Country
+	},
+	"src_asn": func(e models.ScanEvent) string { return strconv.FormatUint(uint64(e.ASN), 10) },
+	"source.as.number": func(e models.ScanEvent) string {
+		return strconv.FormatUint(uint64(e.ASN), 10)
+	},
+}
+
+// search is a named search identifier of a detection section
+type search interface {
+	match(event models.ScanEvent) bool
+}
+
+// allOf matches when all of its field matchers do
+type allOf []*fieldMatcher
+
+func (s allOf) match(event models.ScanEvent) bool {
+	for _, m := range s {
+		if !m.match(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyOf matches when any of its searches does
+type anyOf []search
+
+func (s anyOf) match(event models.ScanEvent) bool {
+	for _, m := range s {
+		if m.match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywords matches when any keyword is contained in the free-text fields
+type keywords []*value
+
+func (k keywords) match(event models.ScanEvent) bool {
+	for _, text := range []string{event.Description, event.Payload, event.UserAgent} {
+		for _, v := range k {
+			if v.match(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldMatcher matches one field against a list of values
+type fieldMatcher struct {
+	get    field
+	values []*value
+	all    bool // All values must match instead of any
+}
+
+func (m *fieldMatcher) match(event models.ScanEvent) bool {
+	actual := m.get(event)
+	for _, v := range m.values {
+		matched := v.match(actual)
+		if matched && !m.all {
+			return true
+		}
+		if !matched && m.all {
+			return false
+		}
+	}
+	return m.all
+}
+
+// value is a single value a field is matched against
+type value struct {
+	null    bool
+	text    string // Lower-cased value for string comparisons
+	pattern *regexp.Regexp
+	network *net.IPNet
+	compare string // Numeric comparison modifier
+	number  float64
+}
+
+func (v *value) match(actual string) bool {
+	switch {
+	case v.null:
+		return actual == "" || actual == "0"
+	case v.pattern != nil:
+		return v.pattern.MatchString(actual)
+	case v.network != nil:
+		ip := net.ParseIP(actual)
+		return ip != nil && v.network.Contains(ip)
+	case v.compare != "":
+		n, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false
+		}
+		switch v.compare {
+		case "lt":
+			return n < v.number
+		case "lte":
+			return n <= v.number
+		case "gt":
+			return n > v.number
+		default:
+			return n >= v.number
+		}
+	default:
+		return strings.ToLower(actual) == v.text
+	}
+}
+
+// compileSearch compiles a search identifier, which is a map of fields, a
+// list of such maps or a list of keywords
+func compileSearch(definition interface{}) (search, error) {
+	switch d := definition.(type) {
+	case map[string]interface{}:
+		return compileFields(d)
+	case []interface{}:
+		if len(d) == 0 {
+			return nil, errors.New("empty search")
+		}
+		if _, ok := d[0].(map[string]interface{}); ok {
+			var alternatives anyOf
+			for _, item := range d {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("mixed maps and keywords")
+				}
+				s, err := compileFields(m)
+				if err != nil {
+					return nil, err
+				}
+				alternatives = append(alternatives, s)
+			}
+			return alternatives, nil
+		}
+		var k keywords
+		for _, item := range d {
+			v, err := compileValue(item, []string{"contains"})
+			if err != nil {
+				return nil, err
+			}
+			k = append(k, v)
+		}
+		return k, nil
+	default:
+		return nil, errors.New("search must be a map or a list")
+	}
+}
+
+// compileFields compiles a map of field names, with optional modifiers, to
+// the values they are matched against
+func compileFields(definition map[string]interface{}) (allOf, error) {
+	var matchers allOf
+	for key, raw := range definition {
+		parts := strings.Split(key, "|")
+		get, ok := fields[strings.ToLower(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("unsupported field %q", parts[0])
+		}
+
+		m := &fieldMatcher{get: get}
+		var modifiers []string
+		for _, modifier := range parts[1:] {
+			modifier = strings.ToLower(modifier)
+			if modifier == "all" {
+				m.all = true
+				continue
+			}
+			modifiers = append(modifiers, modifier)
+		}
+
+		items, ok := raw.([]interface{})
+		if !ok {
+			items = []interface{}{raw}
+		}
+		for _, item := range items {
+			v, err := compileValue(item, modifiers)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", key, err)
+			}
+			m.values = append(m.values, v)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// compileValue compiles a value with the modifiers of its field
+func compileValue(raw interface{}, modifiers []string) (*value, error) {
+	if raw == nil {
+		if len(modifiers) > 0 {
+			return nil, errors.New("modifiers on null value")
+		}
+		return &value{null: true}, nil
+	}
+
+	var text string
+	switch r := raw.(type) {
+	case string:
+		text = r
+	case int, float64, bool:
+		text = fmt.Sprint(r)
+	default:
+		return nil, fmt.Errorf("unsupported value %v", raw)
+	}
+
+	if len(modifiers) > 1 {
+		return nil, fmt.Errorf("unsupported modifiers %s", strings.Join(modifiers, "|"))
+	}
+
+	v := &value{}
+	modifier := ""
+	if len(modifiers) == 1 {
+		modifier = modifiers[0]
+	}
+
+	switch modifier {
+	case "re":
+		pattern, err := regexp.Compile(text)
+		if err != nil {
+			return nil, err
+		}
+		v.pattern = pattern
+	case "cidr":
+		_, network, err := net.ParseCIDR(text)
+		if err != nil {
+			return nil, err
+		}
+		v.network = network
+	case "lt", "lte", "gt", "gte":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a number", modifier)
+		}
+		v.compare, v.number = modifier, n
+	case "contains":
+		v.pattern = wildcard("*" + text + "*")
+	case "startswith":
+		v.pattern = wildcard(text + "*")
+	case "endswith":
+		v.pattern = wildcard("*" + text)
+	case "":
+		if strings.ContainsAny(text, "*?") {
+			v.pattern = wildcard(text)
+		} else {
+			v.text = strings.ToLower(text)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported modifier %q", modifier)
+	}
+
+	return v, nil
+}
+
+// wildcard compiles a Sigma string with * and ? wildcards into a
+// case-insensitive regular expression. A backslash escapes a wildcard.
+func wildcard(text string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune(`*?\`, runes[i+1]):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
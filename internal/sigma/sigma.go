@@ -0,0 +1,237 @@
+package sigma
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Categories are the logsource categories whose rules can be loaded
+var Categories = []string{"firewall", "network_connection"}
+
+// LogSource is the logsource section of a Sigma rule
+type LogSource struct {
+	Category string `yaml:"category"`
+	Product  string `yaml:"product"`
+	Service  string `yaml:"service"`
+}
+
+// Rule is a Sigma rule compiled for matching scan events
+type Rule struct {
+	Title       string    `yaml:"title"`
+	ID          string    `yaml:"id"`
+	Status      string    `yaml:"status"`
+	Description string    `yaml:"description"`
+	Level       string    `yaml:"level"`
+	Tags        []string  `yaml:"tags"`
+	LogSource   LogSource `yaml:"logsource"`
+
+	Detection map[string]interface{} `yaml:"detection"`
+
+	Path      string          `yaml:"-"` // File the rule was loaded from
+	severity  models.Severity // Severity derived from the level
+	searches  map[string]search
+	condition node
+}
+
+// Parse parses and compiles a Sigma rule
+func Parse(data []byte) (*Rule, error) {
+	var rule Rule
+	if err := yaml.Unmarshal(data, &rule); err != nil {
+		return nil, fmt.Errorf("failed to parse Sigma rule: %w", err)
+	}
+	if err := rule.compile(); err != nil {
+		name := rule.Title
+		if name == "" {
+			name = rule.ID
+		}
+		return nil, fmt.Errorf("Sigma rule %q: %w", name, err)
+	}
+	return &rule, nil
+}
+
+// LoadFile reads and compiles the Sigma rule in the file at path
+func LoadFile(path string) (*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Sigma rule: %w", err)
+	}
+	rule, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rule.Path = path
+	return rule, nil
+}
+
+// LoadPaths loads the Sigma rules in the given files, and the .yml and .yaml
+// files in the given directories. Rules that fail to load are skipped and
+// their errors returned in skipped; an error is only returned when a path
+// cannot be read or no rule loads.
+func LoadPaths(paths []string) (rules []*Rule, skipped []error, err error) {
+	var files []string
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Sigma rules: %w", err)
+		}
+		if !stat.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		for _, pattern := range []string{"*.yml", "*.yaml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		rule, err := LoadFile(file)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		if len(skipped) > 0 {
+			return nil, nil, errors.Join(skipped...)
+		}
+		return nil, nil, errors.New("no Sigma rules found")
+	}
+	return rules, skipped, nil
+}
+
+// compile checks the logsource and compiles the detection section
+func (r *Rule) compile() error {
+	if r.Title == "" {
+		return errors.New("missing title")
+	}
+
+	category := strings.ToLower(r.LogSource.Category)
+	supported := false
+	for _, c := range Categories {
+		if category == c {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("unsupported logsource category %q, must be one of %s", r.LogSource.Category, strings.Join(Categories, ", "))
+	}
+
+	var err error
+	if r.severity, err = levelSeverity(r.Level); err != nil {
+		return err
+	}
+
+	condition, ok := r.Detection["condition"]
+	if !ok {
+		return errors.New("missing detection condition")
+	}
+
+	r.searches = make(map[string]search)
+	for name, definition := range r.Detection {
+		if name == "condition" || name == "timeframe" {
+			continue
+		}
+		s, err := compileSearch(definition)
+		if err != nil {
+			return fmt.Errorf("search %q: %w", name, err)
+		}
+		r.searches[name] = s
+	}
+
+	var conditions []string
+	switch c := condition.(type) {
+	case string:
+		conditions = []string{c}
+	case []interface{}:
+		for _, item := range c {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("invalid detection condition")
+			}
+			conditions = append(conditions, s)
+		}
+	default:
+		return errors.New("invalid detection condition")
+	}
+	if len(conditions) == 0 {
+		return errors.New("empty detection condition")
+	}
+
+	// A list of conditions matches when any of them does
+	for _, c := range conditions {
+		n, err := parseCondition(c, r.searches)
+		if err != nil {
+			return fmt.Errorf("condition %q: %w", c, err)
+		}
+		if r.condition == nil {
+			r.condition = n
+		} else {
+			r.condition = &orNode{r.condition, n}
+		}
+	}
+
+	return nil
+}
+
+// levelSeverity maps a Sigma level onto a severity
+func levelSeverity(level string) (models.Severity, error) {
+	switch strings.ToLower(level) {
+	case "", "informational", "low":
+		return models.SeverityLow, nil
+	case "medium":
+		return models.SeverityMedium, nil
+	case "high":
+		return models.SeverityHigh, nil
+	case "critical":
+		return models.SeverityCritical, nil
+	default:
+		return models.SeverityLow, fmt.Errorf("unknown level %q", level)
+	}
+}
+
+// Match reports whether the event matches the rule
+func (r *Rule) Match(event models.ScanEvent) bool {
+	return r.condition.eval(event)
+}
+
+// Tag returns the tag added to matching events
+func (r *Rule) Tag() string {
+	if r.ID != "" {
+		return "sigma:" + r.ID
+	}
+	return "sigma:" + r.Title
+}
+
+// Apply matches event against the rules, tagging it for each matching rule
+// and raising its severity to the highest matching level. It returns the
+// matching rules.
+func Apply(rules []*Rule, event *models.ScanEvent) []*Rule {
+	var matched []*Rule
+	for _, rule := range rules {
+		if !rule.Match(*event) {
+			continue
+		}
+		matched = append(matched, rule)
+		event.AddTag(rule.Tag())
+		if rule.severity > event.Severity {
+			event.Severity = rule.severity
+		}
+	}
+	return matched
+}
@@ -0,0 +1,758 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// Default store settings
+const (
+	DefaultSegmentSize = 8 << 20 // 8 MiB
+	DefaultMaxAge      = 30 * 24 * time.Hour
+	DefaultMaxSize     = 1 << 30 // 1 GiB
+)
+
+const (
+	segmentPrefix = "events-"
+	segmentSuffix = ".log"
+
+	kindEvent      = "event"
+	kindStats      = "stats"
+	kindAnnotation = "annotation"
+	kindHostname   = "hostname"
+)
+
+// ErrReadOnly is returned when writing to a store opened read-only
+var ErrReadOnly = errors.New("store is opened read-only")
+
+// Options holds the store settings
+type Options struct {
+	Dir         string        // Directory holding the segment files
+	SegmentSize int64         // Size at which a new segment is started
+	MaxAge      time.Duration // Segments with only older records are removed, 0 disables
+	MaxSize     int64         // Oldest segments are removed beyond this total size, 0 disables
+	ReadOnly    bool          // Open for reading only, e.g. while a daemon is writing
+}
+
+// Store persists scan events and statistics snapshots in an append-only log
+// split into numbered segment files. Each line of a segment is one JSON
+// record. Segments are only ever appended to, and removed as a whole by
+// retention, which makes it safe to read a store while it is being written.
+type Store struct {
+	mu        sync.RWMutex
+	opts      Options
+	segments  []*segment
+	byIP      map[string][]recordRef
+	notes     map[string]eventNote // Acknowledgement and note by event ID
+	hostnames map[string]string    // Hostname resolved after its events were stored, by source IP
+	active    *os.File
+	latest    *Snapshot
+	nextSeq   int
+}
+
+// segment holds the metadata and time index of a single segment file
+type segment struct {
+	seq      int
+	path     string
+	size     int64
+	minTime  time.Time
+	maxTime  time.Time
+	events   int
+	complete int64 // Offset after the last complete line
+}
+
+// recordRef locates a record within a segment
+type recordRef struct {
+	seq    int
+	offset int64
+}
+
+// eventNote is the acknowledgement and note of an event, folded from its
+// annotations
+type eventNote struct {
+	acknowledged bool
+	note         string
+}
+
+// record is a single line in a segment file
+type record struct {
+	Kind       string             `json:"kind"`
+	Time       time.Time          `json:"time"`
+	Event      *models.ScanEvent  `json:"event,omitempty"`
+	Stats      *models.ScanStats  `json:"stats,omitempty"`
+	Annotation *models.Annotation `json:"annotation,omitempty"`
+	Hostname   *hostnameUpdate    `json:"hostname,omitempty"`
+}
+
+// hostnameUpdate is the hostname of a source, resolved after the first of
+// its events was stored
+type hostnameUpdate struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+// Snapshot is a statistics snapshot taken at a point in time
+type Snapshot struct {
+	Time  time.Time        `json:"time"`
+	Stats models.ScanStats `json:"stats"`
+}
+
+// Open opens the store in opts.Dir, creating the directory unless read-only,
+// and builds the in-memory indexes from the existing segments
+func Open(opts Options) (*Store, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+
+	if !opts.ReadOnly {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+	}
+
+	s := &Store{
+		opts:      opts,
+		byIP:      make(map[string][]recordRef),
+		notes:     make(map[string]eventNote),
+		hostnames: make(map[string]string),
+		nextSeq:   1,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if !opts.ReadOnly {
+		if err := s.ApplyRetention(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// load scans all segment files and builds the indexes
+func (s *Store) load() error {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read store directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		seq, ok := parseSegmentName(entry.Name())
+		if !ok {
+			continue
+		}
+		s.segments = append(s.segments, &segment{
+			seq:  seq,
+			path: filepath.Join(s.opts.Dir, entry.Name()),
+		})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+
+	for _, seg := range s.segments {
+		if err := s.indexSegment(seg); err != nil {
+			return err
+		}
+		s.nextSeq = seg.seq + 1
+	}
+
+	return nil
+}
+
+// indexSegment reads a segment from its last indexed offset and adds its
+// records to the indexes
+func (s *Store) indexSegment(seg *segment) error {
+	file, err := os.Open(seg.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Removed by retention in another process
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(seg.complete, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek segment: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	offset := seg.complete
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing line without newline is still being written
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read segment %s: %w", seg.path, err)
+		}
+
+		var rec record
+		if jsonErr := json.Unmarshal(line, &rec); jsonErr == nil {
+			s.indexRecord(seg, rec, offset)
+		}
+		offset += int64(len(line))
+	}
+
+	seg.complete = offset
+	seg.size = offset
+	return nil
+}
+
+// indexRecord adds a single record at offset to the indexes
+func (s *Store) indexRecord(seg *segment, rec record, offset int64) {
+	if seg.minTime.IsZero() || rec.Time.Before(seg.minTime) {
+		seg.minTime = rec.Time
+	}
+	if rec.Time.After(seg.maxTime) {
+		seg.maxTime = rec.Time
+	}
+
+	switch rec.Kind {
+	case kindEvent:
+		if rec.Event == nil {
+			return
+		}
+		seg.events++
+		ip := normalizeIP(rec.Event.SourceIP)
+		s.byIP[ip] = append(s.byIP[ip], recordRef{seq: seg.seq, offset: offset})
+	case kindStats:
+		if rec.Stats == nil {
+			return
+		}
+		if s.latest == nil || !rec.Time.Before(s.latest.Time) {
+			s.latest = &Snapshot{Time: rec.Time, Stats: *rec.Stats}
+		}
+	case kindAnnotation:
+		if rec.Annotation == nil {
+			return
+		}
+		for _, id := range rec.Annotation.EventIDs {
+			note := s.notes[id]
+			if rec.Annotation.Acknowledged != nil {
+				note.acknowledged = *rec.Annotation.Acknowledged
+			}
+			if rec.Annotation.Note != nil {
+				note.note = *rec.Annotation.Note
+			}
+			s.notes[id] = note
+		}
+	case kindHostname:
+		if rec.Hostname == nil {
+			return
+		}
+		s.hostnames[normalizeIP(rec.Hostname.IP)] = rec.Hostname.Hostname
+	}
+}
+
+// Refresh picks up records and segments written by another process since
+// the store was opened or last refreshed
+func (s *Store) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[int]bool, len(s.segments))
+	for _, seg := range s.segments {
+		known[seg.seq] = true
+	}
+
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read store directory: %w", err)
+	}
+	for _, entry := range entries {
+		if seq, ok := parseSegmentName(entry.Name()); ok && !known[seq] {
+			s.segments = append(s.segments, &segment{seq: seq, path: filepath.Join(s.opts.Dir, entry.Name())})
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+
+	for _, seg := range s.segments {
+		if err := s.indexSegment(seg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append persists a scan event
+func (s *Store) Append(event models.ScanEvent) error {
+	return s.write(record{Kind: kindEvent, Time: event.Timestamp, Event: &event})
+}
+
+// Annotate persists the acknowledgement and note of events. They are
+// applied to the events returned by Events from then on.
+func (s *Store) Annotate(annotation models.Annotation) error {
+	if annotation.Time.IsZero() {
+		annotation.Time = time.Now()
+	}
+	return s.write(record{Kind: kindAnnotation, Time: annotation.Time, Annotation: &annotation})
+}
+
+// SetHostname persists the hostname of a source resolved after its events
+// were stored. It fills in the hostname of the events without one returned
+// by Events from then on. An unchanged hostname is not written again.
+func (s *Store) SetHostname(ip, hostname string, at time.Time) error {
+	s.mu.RLock()
+	known := s.hostnames[normalizeIP(ip)] == hostname
+	s.mu.RUnlock()
+	if known {
+		return nil
+	}
+	return s.write(record{Kind: kindHostname, Time: at, Hostname: &hostnameUpdate{IP: ip, Hostname: hostname}})
+}
+
+// AppendStats persists a statistics snapshot taken at the given time
+func (s *Store) AppendStats(at time.Time, stats models.ScanStats) error {
+	return s.write(record{Kind: kindStats, Time: at, Stats: &stats})
+}
+
+// write appends a record to the active segment, starting a new one if needed
+func (s *Store) write(rec record) error {
+	if s.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg, err := s.activeSegment(int64(len(line)))
+	if err != nil {
+		return err
+	}
+
+	offset := seg.size
+	if _, err := s.active.Write(line); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	seg.size += int64(len(line))
+	seg.complete = seg.size
+	s.indexRecord(seg, rec, offset)
+
+	return nil
+}
+
+// activeSegment returns the segment to append n bytes to, opening or
+// rolling over the segment file as needed
+func (s *Store) activeSegment(n int64) (*segment, error) {
+	var seg *segment
+	if len(s.segments) > 0 {
+		seg = s.segments[len(s.segments)-1]
+	}
+
+	if seg != nil && seg.size > 0 && seg.size+n > s.opts.SegmentSize {
+		if s.active != nil {
+			s.active.Close()
+			s.active = nil
+		}
+		seg = nil
+	}
+
+	if seg == nil {
+		seg = &segment{seq: s.nextSeq, path: filepath.Join(s.opts.Dir, segmentName(s.nextSeq))}
+		s.segments = append(s.segments, seg)
+		s.nextSeq++
+	}
+
+	if s.active == nil {
+		file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open segment: %w", err)
+		}
+		// Drop a partial line left behind by a crash
+		if err := file.Truncate(seg.complete); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate segment: %w", err)
+		}
+		seg.size = seg.complete
+		s.active = file
+	}
+
+	return seg, nil
+}
+
+// Events returns the stored events matching filter in chronological order.
+// The source IP index is used when filtering on a single address and the
+// per-segment time range is used to skip segments outside the time filter.
+func (s *Store) Events(filter models.EventFilter) ([]models.ScanEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.ScanEvent
+
+	if filter.Network.IsValid() && filter.Network.IsSingleIP() {
+		refs := s.byIP[normalizeIP(filter.Network.Addr().String())]
+		bySeq := make(map[int]*segment, len(s.segments))
+		for _, seg := range s.segments {
+			bySeq[seg.seq] = seg
+		}
+
+		files := make(segmentFiles)
+		defer files.close()
+		for _, ref := range refs {
+			seg := bySeq[ref.seq]
+			if seg == nil || !overlaps(seg, filter) {
+				continue
+			}
+			rec, err := files.readRecordAt(seg, ref.offset)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			if rec.Event != nil && filter.Match(*rec.Event) {
+				events = append(events, *rec.Event)
+			}
+		}
+	} else {
+		for _, seg := range s.segments {
+			if seg.events == 0 || !overlaps(seg, filter) {
+				continue
+			}
+			err := readSegment(seg.path, seg.complete, func(rec record) {
+				if rec.Kind == kindEvent && rec.Event != nil && filter.Match(*rec.Event) {
+					events = append(events, *rec.Event)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i := range events {
+		if note, ok := s.notes[events[i].ID]; ok {
+			events[i].Acknowledged = note.acknowledged
+			events[i].Note = note.note
+		}
+		if events[i].Hostname == "" {
+			events[i].Hostname = s.hostnames[normalizeIP(events[i].SourceIP)]
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events, nil
+}
+
+// SeenBefore reports which of ips have a stored event before t. It uses the
+// source IP index and the time range of each segment, and only reads a
+// record when its segment spans t.
+func (s *Store) SeenBefore(ips []string, t time.Time) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bySeq := make(map[int]*segment, len(s.segments))
+	for _, seg := range s.segments {
+		bySeq[seg.seq] = seg
+	}
+
+	files := make(segmentFiles)
+	defer files.close()
+
+	seen := make(map[string]bool)
+	for _, ip := range ips {
+		// References are in append order, so the earliest events come first
+		for _, ref := range s.byIP[normalizeIP(ip)] {
+			seg := bySeq[ref.seq]
+			if seg == nil {
+				continue
+			}
+			if !seg.minTime.Before(t) {
+				break
+			}
+			if seg.maxTime.Before(t) {
+				seen[ip] = true
+				break
+			}
+			rec, err := files.readRecordAt(seg, ref.offset)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			if rec.Event != nil && rec.Event.Timestamp.Before(t) {
+				seen[ip] = true
+				break
+			}
+		}
+	}
+	return seen, nil
+}
+
+// LatestStats returns the most recent statistics snapshot, if any
+func (s *Store) LatestStats() (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.latest == nil {
+		return Snapshot{}, false
+	}
+	return *s.latest, true
+}
+
+// Snapshots returns the statistics snapshots taken within [since, until)
+func (s *Store) Snapshots(since, until time.Time) ([]Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filter := models.EventFilter{Since: since, Until: until}
+
+	var snapshots []Snapshot
+	for _, seg := range s.segments {
+		if !overlaps(seg, filter) {
+			continue
+		}
+		err := readSegment(seg.path, seg.complete, func(rec record) {
+			if rec.Kind != kindStats || rec.Stats == nil {
+				return
+			}
+			if (!since.IsZero() && rec.Time.Before(since)) || (!until.IsZero() && !rec.Time.Before(until)) {
+				return
+			}
+			snapshots = append(snapshots, Snapshot{Time: rec.Time, Stats: *rec.Stats})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshots, nil
+}
+
+// Size returns the total size in bytes of all segments
+func (s *Store) Size() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total
+}
+
+// ApplyRetention removes whole segments older than MaxAge, then the oldest
+// segments until the store is within MaxSize. The active segment is never
+// removed by size, so the newest records are always kept.
+func (s *Store) ApplyRetention(now time.Time) error {
+	if s.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+
+	removed := 0
+	for removed < len(s.segments) {
+		seg := s.segments[removed]
+		last := removed == len(s.segments)-1
+
+		expired := s.opts.MaxAge > 0 && !seg.maxTime.IsZero() && now.Sub(seg.maxTime) > s.opts.MaxAge
+		oversized := s.opts.MaxSize > 0 && total > s.opts.MaxSize && !last
+		if !expired && !oversized {
+			break
+		}
+
+		if last && s.active != nil {
+			s.active.Close()
+			s.active = nil
+		}
+		if err := s.dropNotes(seg); err != nil {
+			return err
+		}
+		if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove segment: %w", err)
+		}
+		total -= seg.size
+		removed++
+	}
+
+	if removed == 0 {
+		return nil
+	}
+
+	gone := make(map[int]bool, removed)
+	for _, seg := range s.segments[:removed] {
+		gone[seg.seq] = true
+	}
+	s.segments = s.segments[removed:]
+
+	for ip, refs := range s.byIP {
+		kept := refs[:0]
+		for _, ref := range refs {
+			if !gone[ref.seq] {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.byIP, ip)
+			delete(s.hostnames, ip)
+		} else {
+			s.byIP[ip] = kept
+		}
+	}
+
+	return nil
+}
+
+// dropNotes forgets the acknowledgements and notes of the events in seg,
+// which is about to be removed. Annotations follow their events, so those
+// of the events in later segments are kept.
+func (s *Store) dropNotes(seg *segment) error {
+	if len(s.notes) == 0 || seg.events == 0 {
+		return nil
+	}
+	return readSegment(seg.path, seg.complete, func(rec record) {
+		if rec.Kind == kindEvent && rec.Event != nil {
+			delete(s.notes, rec.Event.ID)
+		}
+	})
+}
+
+// Close closes the active segment file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// overlaps checks if the time range of a segment overlaps the filter
+func overlaps(seg *segment, filter models.EventFilter) bool {
+	if !filter.Since.IsZero() && seg.maxTime.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !seg.minTime.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// readSegment calls fn for every complete record in the first limit bytes
+// of a segment. A segment removed in the meantime is silently skipped.
+func readSegment(path string, limit int64, fn func(record)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(io.LimitReader(file, limit))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && err == nil {
+			var rec record
+			if json.Unmarshal(line, &rec) == nil {
+				fn(rec)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read segment: %w", err)
+		}
+	}
+}
+
+// segmentFiles keeps the segments read by record offset open, so each is
+// opened once however many of its records are read
+type segmentFiles map[int]*os.File
+
+// readRecordAt reads the single record starting at offset in a segment
+func (f segmentFiles) readRecordAt(seg *segment, offset int64) (record, error) {
+	var rec record
+
+	file, ok := f[seg.seq]
+	if !ok {
+		var err error
+		if file, err = os.Open(seg.path); err != nil {
+			return rec, err
+		}
+		f[seg.seq] = file
+	}
+
+	line, err := bufio.NewReader(io.NewSectionReader(file, offset, seg.complete-offset)).ReadBytes('\n')
+	if err != nil {
+		return rec, fmt.Errorf("failed to read record: %w", err)
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return rec, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return rec, nil
+}
+
+// close closes the open segments
+func (f segmentFiles) close() {
+	for _, file := range f {
+		file.Close()
+	}
+}
+
+// segmentName returns the file name of the segment with the given sequence number
+func segmentName(seq int) string {
+	return fmt.Sprintf("%s%08d%s", segmentPrefix, seq, segmentSuffix)
+}
+
+// parseSegmentName returns the sequence number of a segment file name
+func parseSegmentName(name string) (int, bool) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+		return 0, false
+	}
+	seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+	if err != nil || seq <= 0 {
+		return 0, false
+	}
+	return seq, true
+}
+
+// normalizeIP returns the canonical form of an IP address used as index key
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().String()
+}
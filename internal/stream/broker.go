@@ -0,0 +1,171 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// DropPolicy decides which event is discarded when a subscriber's buffer is full
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest buffered event to make room for the new one
+	DropOldest DropPolicy = iota
+	// DropNewest discards the new event and keeps the buffered ones
+	DropNewest
+)
+
+// Default sizes used when a zero value is passed
+const (
+	DefaultBufferSize  = 64
+	DefaultHistorySize = 1000
+)
+
+// Broker fans out published scan events to any number of subscribers. Each
+// subscriber has a bounded buffer, so a slow subscriber never blocks the
+// publisher. The most recent events are kept so subscribers can resume from
+// the last event they have seen.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	history     []models.ScanEvent
+	historySize int
+	policy      DropPolicy
+}
+
+// NewBroker creates a new broker keeping historySize events for resuming
+func NewBroker(historySize int, policy DropPolicy) *Broker {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+		history:     make([]models.ScanEvent, 0, historySize),
+		historySize: historySize,
+		policy:      policy,
+	}
+}
+
+// Subscription receives the events matching its filter on C
+type Subscription struct {
+	C <-chan models.ScanEvent
+
+	ch      chan models.ScanEvent
+	filter  models.EventFilter
+	broker  *Broker
+	dropped atomic.Uint64
+	closed  bool
+}
+
+// Subscribe registers a new subscriber with the given buffer size. If lastID
+// is not empty, retained events published after the event with that ID are
+// replayed first; if the ID is no longer retained, all retained events are
+// replayed.
+func (b *Broker) Subscribe(filter models.EventFilter, bufferSize int, lastID string) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	ch := make(chan models.ScanEvent, bufferSize)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		filter: filter,
+		broker: b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lastID != "" {
+		for _, event := range b.replayFrom(lastID) {
+			sub.send(event, b.policy)
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Publish sends an event to every subscriber whose filter matches it
+func (b *Broker) Publish(event models.ScanEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.history) == b.historySize {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:len(b.history)-1]
+	}
+	b.history = append(b.history, event)
+
+	for sub := range b.subscribers {
+		sub.send(event, b.policy)
+	}
+}
+
+// Subscribers returns the number of active subscribers
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// replayFrom returns the retained events published after lastID
+func (b *Broker) replayFrom(lastID string) []models.ScanEvent {
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID == lastID {
+			return b.history[i+1:]
+		}
+	}
+	return b.history
+}
+
+// send delivers an event to the subscriber applying the drop policy. It must
+// be called with the broker lock held.
+func (s *Subscription) send(event models.ScanEvent, policy DropPolicy) {
+	if s.closed || !s.filter.Match(event) {
+		return
+	}
+
+	for {
+		select {
+		case s.ch <- event:
+			return
+		default:
+		}
+
+		if policy == DropNewest {
+			s.dropped.Add(1)
+			return
+		}
+
+		// Discard the oldest buffered event and try again
+		select {
+		case <-s.ch:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unregisters the subscription and closes its channel
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(b.subscribers, s)
+	close(s.ch)
+}
@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Annotator is a Source that stores the acknowledgement and notes of
+// events. Events can only be annotated when the source is one.
+type Annotator interface {
+	Annotate(annotation models.Annotation) error
+}
+
+// selectedEvents returns the IDs of the events the annotation keys act on:
+// the event in the detail view, the selected event in the Events tab or the
+// events of the selected incident. It also reports whether they are all
+// acknowledged, and their first note.
+func (m Model) selectedEvents() (ids []string, acknowledged bool, note string) {
+	switch {
+	case m.detail != nil:
+		return []string{m.detail.ID}, m.detail.Acknowledged, m.detail.Note
+	case m.tab == tabEvents:
+		if cursor := m.table.Cursor(); cursor >= 0 && cursor < len(m.rows) {
+			event := m.rows[cursor]
+			return []string{event.ID}, event.Acknowledged, event.Note
+		}
+	case m.tab == tabIncidents:
+		if cursor := m.incidentsTable.Cursor(); cursor >= 0 && cursor < len(m.incidents) {
+			incident := m.incidents[cursor]
+			if len(incident.Notes) > 0 {
+				note = incident.Notes[0]
+			}
+			return incident.EventIDs, incident.Acknowledged == incident.EventCount, note
+		}
+	}
+	return nil, false, ""
+}
+
+// acknowledge toggles the acknowledgement of the selected events. An
+// incident is acknowledged unless all its events already are.
+func (m *Model) acknowledge() {
+	ids, acknowledged, _ := m.selectedEvents()
+	if len(ids) == 0 {
+		return
+	}
+	acknowledged = !acknowledged
+	m.annotate(models.Annotation{EventIDs: ids, Acknowledged: &acknowledged})
+}
+
+// startNote opens the note prompt for the selected events, filled in with
+// their current note
+func (m *Model) startNote() tea.Cmd {
+	ids, _, note := m.selectedEvents()
+	if len(ids) == 0 {
+		return nil
+	}
+	m.noting = true
+	m.noteIDs = ids
+	m.status = ""
+	m.noteInput.SetValue(note)
+	m.noteInput.CursorEnd()
+	return m.noteInput.Focus()
+}
+
+// updateNote handles keys while a note is typed
+func (m Model) updateNote(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.noting = false
+		m.noteInput.Blur()
+		return m, nil
+	case "enter":
+		m.noting = false
+		m.noteInput.Blur()
+		note := strings.TrimSpace(m.noteInput.Value())
+		m.annotate(models.Annotation{EventIDs: m.noteIDs, Note: &note})
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+// annotate has the source store the annotation and applies it to the events
+// shown. Only the views of the events are rebuilt, so a paused view stays
+// as it is otherwise.
+func (m *Model) annotate(annotation models.Annotation) {
+	annotator, ok := m.source.(Annotator)
+	if !ok {
+		m.status = "Annotations are not available"
+		return
+	}
+	annotation.Time = time.Now()
+	if err := annotator.Annotate(annotation); err != nil {
+		m.status = fmt.Sprintf("Failed: %v", err)
+		return
+	}
+
+	annotation.ApplyTo(m.events)
+	annotation.ApplyTo(m.pending)
+	if m.detail != nil {
+		annotation.Apply(m.detail)
+		m.detailView.SetContent(m.renderDetail(*m.detail))
+	}
+	m.updateRows()
+	m.updateIncidentRows()
+
+	events := fmt.Sprintf("%d events", len(annotation.EventIDs))
+	if len(annotation.EventIDs) == 1 {
+		events = "1 event"
+	}
+	switch {
+	case annotation.Acknowledged != nil && *annotation.Acknowledged:
+		m.status = "Acknowledged " + events
+	case annotation.Acknowledged != nil:
+		m.status = "Unacknowledged " + events
+	case *annotation.Note == "":
+		m.status = "Removed the note of " + events
+	default:
+		m.status = "Saved the note of " + events
+	}
+}
+
+// viewPrompt renders the note or export prompt while one is typed in, or
+// else the outcome of the last action
+func (m Model) viewPrompt() string {
+	switch {
+	case m.noting:
+		return m.noteInput.View() + "\n"
+	case m.exporting:
+		return m.exportInput.View() + "\n"
+	case m.status != "":
+		return m.styles.text.Render(m.status) + "\n"
+	}
+	return ""
+}
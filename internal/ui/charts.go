@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// HistorySource is a Source that also keeps per-minute event counts. When
+// the source doesn't, the TUI counts the events it has.
+type HistorySource interface {
+	GetStatsHistory() []models.StatsMinute
+}
+
+// chartsHeight is the number of lines taken by the charts below the header
+const chartsHeight = 4
+
+// chartLabelWidth is the width of the labels in front of the charts
+const chartLabelWidth = 11
+
+// sparkBlocks are the bars of the sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// updateHistory takes the per-minute counts from the source, or counts the
+// events when the source keeps no history
+func (m *Model) updateHistory() {
+	if source, ok := m.source.(HistorySource); ok {
+		m.history = source.GetStatsHistory()
+		return
+	}
+
+	history := models.NewStatsHistory(time.Hour)
+	for _, event := range m.events {
+		history.Add(event)
+	}
+	m.history = history.Minutes(m.lastUpdate)
+}
+
+// renderCharts renders the events per minute, the severities of the last
+// hour and the scanned port ranges
+func (m Model) renderCharts() string {
+	label := func(s string) string {
+		return m.styles.text.Render(fmt.Sprintf("%-*s", chartLabelWidth, s))
+	}
+
+	counts := make([]int, len(m.history))
+	severities := make(map[models.Severity]int)
+	total, peak := 0, 0
+	for i, minute := range m.history {
+		counts[i] = minute.Events
+		total += minute.Events
+		if minute.Events > peak {
+			peak = minute.Events
+		}
+		for severity, count := range minute.SeverityCounts {
+			severities[severity] += count
+		}
+	}
+
+	lines := []string{
+		label("Events/min") + m.styles.sparkline(counts) + fmt.Sprintf(" %d in the last hour, peak %d/min", total, peak),
+		label("Severity") + m.styles.severityBar(severities, len(counts)) + " " + m.styles.severityLegend(severities),
+		label("Ports") + m.styles.portMap(m.stats.ScansByPort, m.tableWidth()-chartLabelWidth-8) + " 0-65535",
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sparkline draws a bar per value, scaled to the largest value
+func (s styles) sparkline(values []int) string {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		if v == 0 {
+			b.WriteString(s.empty.Render(string(sparkBlocks[0])))
+			continue
+		}
+		level := int(math.Ceil(float64(v) / float64(max) * float64(len(sparkBlocks)-1)))
+		b.WriteString(s.text.Render(string(sparkBlocks[level])))
+	}
+	return b.String()
+}
+
+// severityBar draws the share of each severity as a bar of width. Without
+// colors the severities are told apart by shade.
+func (s styles) severityBar(counts map[models.Severity]int, width int) string {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 || width <= 0 {
+		return s.empty.Render(strings.Repeat("·", width))
+	}
+
+	var b strings.Builder
+	cumulative, drawn := 0, 0
+	for severity := models.SeverityLow; severity <= models.SeverityCritical; severity++ {
+		cumulative += counts[severity]
+		// Round the end of each segment so the segments add up to width
+		end := int(math.Round(float64(cumulative) * float64(width) / float64(total)))
+		block := "█"
+		if s.monochrome {
+			block = string(shades[severity])
+		}
+		b.WriteString(s.severity[severity].Render(strings.Repeat(block, end-drawn)))
+		drawn = end
+	}
+	return b.String()
+}
+
+// severityLegend lists the count of each severity in its color
+func (s styles) severityLegend(counts map[models.Severity]int) string {
+	parts := make([]string, 0, len(s.severity))
+	for severity := models.SeverityLow; severity <= models.SeverityCritical; severity++ {
+		parts = append(parts, s.severity[severity].Render(fmt.Sprintf("%s %d", strings.ToLower(severity.String()), counts[severity])))
+	}
+	return strings.Join(parts, " ")
+}
+
+// portMap draws the scanned ports as width cells, each covering an equal
+// range of the 0-65535 port space and colored by its number of events
+func (s styles) portMap(ports map[int]int, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	size := (65536 + width - 1) / width
+	cells := make([]int, width)
+	max := 0
+	for port, count := range ports {
+		if port < 0 || port > 65535 {
+			continue
+		}
+		cells[port/size] += count
+		if cells[port/size] > max {
+			max = cells[port/size]
+		}
+	}
+
+	var b strings.Builder
+	for _, count := range cells {
+		if count == 0 {
+			b.WriteString(s.empty.Render("·"))
+			continue
+		}
+		b.WriteString(s.heatCell(heatLevel(count, max, len(s.heat))))
+	}
+	return b.String()
+}
@@ -0,0 +1,293 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultColumns are the table columns shown unless configured otherwise
+var DefaultColumns = []string{"time", "source", "country", "port", "type", "severity", "ack", "description"}
+
+// column is a table column that can be shown and sorted on
+type column struct {
+	title string
+	width int // Minimum width
+	flex  int // Share of the remaining terminal width, 0 for a fixed width
+	value func(row tableRow) string
+	less  func(a, b tableRow) bool
+}
+
+// tableRow is an event with the values computed for the table
+type tableRow struct {
+	event    models.ScanEvent
+	incident string // ID of the incident the event belongs to
+}
+
+// compareStrings orders rows by a string value
+func compareStrings(value func(row tableRow) string) func(a, b tableRow) bool {
+	return func(a, b tableRow) bool { return value(a) < value(b) }
+}
+
+// columns are the available table columns by name
+var columns = map[string]column{
+	"time": {"Time", 19, 0,
+		func(r tableRow) string { return r.event.Timestamp.Format("2006-01-02 15:04:05") },
+		func(a, b tableRow) bool { return a.event.Timestamp.Before(b.event.Timestamp) }},
+	"source": {"Source IP", 15, 1,
+		func(r tableRow) string { return r.event.SourceIP },
+		func(a, b tableRow) bool { return compareIPs(a.event.SourceIP, b.event.SourceIP) < 0 }},
+	"source_port": {"Src Port", 8, 0,
+		func(r tableRow) string { return strconv.Itoa(r.event.SourcePort) },
+		func(a, b tableRow) bool { return a.event.SourcePort < b.event.SourcePort }},
+	"hostname": {"Hostname", 12, 2,
+		func(r tableRow) string { return r.event.Hostname },
+		compareStrings(func(r tableRow) string { return r.event.Hostname })},
+	"country": {"Country", 7, 0,
+		func(r tableRow) string { return r.event.Country },
+		compareStrings(func(r tableRow) string { return r.event.Country })},
+	"port": {"Port", 6, 0,
+		func(r tableRow) string { return strconv.Itoa(r.event.TargetPort) },
+		func(a, b tableRow) bool { return a.event.TargetPort < b.event.TargetPort }},
+	"protocol": {"Proto", 5, 0,
+		func(r tableRow) string { return r.event.Protocol },
+		compareStrings(func(r tableRow) string { return r.event.Protocol })},
+	"type": {"Type", 12, 0,
+		func(r tableRow) string { return r.event.ScanType },
+		compareStrings(func(r tableRow) string { return r.event.ScanType })},
+	"severity": {"Severity", 8, 0,
+		func(r tableRow) string { return r.event.Severity.String() },
+		func(a, b tableRow) bool { return a.event.Severity < b.event.Severity }},
+	"ack": {"Ack", 3, 0,
+		func(r tableRow) string {
+			if r.event.Acknowledged {
+				return "✓"
+			}
+			return ""
+		},
+		func(a, b tableRow) bool { return !a.event.Acknowledged && b.event.Acknowledged }},
+	"note": {"Note", 8, 2,
+		func(r tableRow) string { return r.event.Note },
+		compareStrings(func(r tableRow) string { return r.event.Note })},
+	"tool": {"Tool", 8, 0,
+		func(r tableRow) string { return r.event.Tool() },
+		compareStrings(func(r tableRow) string { return r.event.Tool() })},
+	"incident": {"Incident", 20, 1,
+		func(r tableRow) string { return r.incident },
+		compareStrings(func(r tableRow) string { return r.incident })},
+	"tags": {"Tags", 8, 2,
+		func(r tableRow) string { return strings.Join(r.event.Tags, ",") },
+		compareStrings(func(r tableRow) string { return strings.Join(r.event.Tags, ",") })},
+	"description": {"Description", 12, 4,
+		func(r tableRow) string { return r.event.Description },
+		compareStrings(func(r tableRow) string { return r.event.Description })},
+}
+
+// ColumnNames returns the names of the available table columns
+func ColumnNames() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckColumns checks that names are known, unique table columns
+func CheckColumns(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("no columns given")
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("unknown column %q, must be one of %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// columnWidths shares the table width between the columns. Fixed columns
+// get their width, and flexible ones their minimum plus a share of what is
+// left, so the columns fill the table.
+func columnWidths(names []string, tableWidth int) []int {
+	widths := make([]int, len(names))
+	// Every cell is padded by one space on each side
+	remaining := tableWidth - 2*len(names)
+	totalFlex := 0
+	for i, name := range names {
+		widths[i] = columns[name].width
+		remaining -= widths[i]
+		totalFlex += columns[name].flex
+	}
+	if remaining <= 0 || totalFlex == 0 {
+		return widths
+	}
+
+	extra := remaining
+	last := -1
+	for i, name := range names {
+		if flex := columns[name].flex; flex > 0 {
+			share := remaining * flex / totalFlex
+			widths[i] += share
+			extra -= share
+			last = i
+		}
+	}
+	// Give the rounding remainder to the last flexible column
+	widths[last] += extra
+	return widths
+}
+
+// tableColumns returns the bubbles table columns for the configured columns,
+// marking the sort column
+func (m Model) tableColumns() []table.Column {
+	widths := columnWidths(m.columns, m.tableWidth())
+	result := make([]table.Column, len(m.columns))
+	for i, name := range m.columns {
+		title := columns[name].title
+		if name == m.sortColumn {
+			if m.sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		result[i] = table.Column{Title: title, Width: widths[i]}
+	}
+	return result
+}
+
+// tableWidth returns the width available to the table
+func (m Model) tableWidth() int {
+	if m.width == 0 {
+		return 80
+	}
+	return m.width - 4
+}
+
+// scrollTable moves the rows shown by the events table so the cursor stays
+// in view, after the cursor, the rows or the height changed
+func (m *Model) scrollTable() {
+	height := max(m.table.Height(), 1)
+	cursor := m.table.Cursor()
+	m.tableTop = min(m.tableTop, cursor)
+	m.tableTop = max(m.tableTop, cursor-height+1)
+	m.tableTop = max(min(m.tableTop, len(m.rows)-height), 0)
+}
+
+// renderTable renders the events table. The bubbles table styles all rows
+// alike, so it only keeps the cursor and the rows are rendered here, each
+// in the color of its event's severity.
+func (m Model) renderTable() string {
+	columns := m.tableColumns()
+	cells := func(values []string, style lipgloss.Style) string {
+		rendered := make([]string, 0, len(columns))
+		for i, value := range values {
+			if i >= len(columns) {
+				break
+			}
+			width := columns[i].Width
+			cell := lipgloss.NewStyle().Width(width).MaxWidth(width).Inline(true)
+			rendered = append(rendered, style.Render(cell.Render(runewidth.Truncate(value, width, "…"))))
+		}
+		return lipgloss.JoinHorizontal(lipgloss.Left, rendered...)
+	}
+
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.Title
+	}
+
+	rows := m.table.Rows()
+	height := m.table.Height()
+	lines := make([]string, 0, height)
+	for i := m.tableTop; i < min(m.tableTop+height, len(rows), len(m.rows)); i++ {
+		row := cells(rows[i], m.styles.table.Cell)
+		switch {
+		case i == m.table.Cursor():
+			row = m.styles.table.Selected.Render(row)
+		case !m.styles.monochrome:
+			row = m.styles.severity[m.rows[i].Severity].Render(row)
+		}
+		lines = append(lines, row)
+	}
+
+	width := m.table.Width()
+	body := lipgloss.NewStyle().Width(width).MaxWidth(width).Height(height).MaxHeight(height).
+		Render(strings.Join(lines, "\n"))
+	return cells(titles, m.styles.table.Header) + "\n" + body
+}
+
+// sortBy sorts on the column at index, reversing the order when the table
+// is already sorted on it
+func (m *Model) sortBy(index int) {
+	if index < 0 || index >= len(m.columns) {
+		return
+	}
+	name := m.columns[index]
+	if name == m.sortColumn {
+		m.sortDesc = !m.sortDesc
+	} else {
+		m.sortColumn = name
+		m.sortDesc = name == "time"
+	}
+	m.table.SetColumns(m.tableColumns())
+	m.updateRows()
+}
+
+// sortNext sorts on the column after the sort column, wrapping around, so
+// every column can be reached however many are configured
+func (m *Model) sortNext() {
+	index := slices.Index(m.columns, m.sortColumn)
+	m.sortBy((index + 1) % len(m.columns))
+}
+
+// reverseSort reverses the order of the rows
+func (m *Model) reverseSort() {
+	m.sortDesc = !m.sortDesc
+	m.table.SetColumns(m.tableColumns())
+	m.updateRows()
+}
+
+// sortRows orders the rows on the sort column. Rows with equal values keep
+// their newest first order.
+func (m Model) sortRows(rows []tableRow) {
+	c, ok := columns[m.sortColumn]
+	if !ok {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if m.sortDesc {
+			return c.less(rows[j], rows[i])
+		}
+		return c.less(rows[i], rows[j])
+	})
+}
+
+// compareIPs orders addresses numerically, with invalid ones last
+func compareIPs(a, b string) int {
+	pa, errA := parsePrefix(a)
+	pb, errB := parsePrefix(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	return pa.Addr().Compare(pb.Addr())
+}
@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Actions carries out the actions on a source offered by the detail view
+type Actions interface {
+	Whitelist(ip string) error // Add the source to the allow list
+	Blacklist(ip string) error // Add the source to the deny list
+	Block(ip string) error     // Deny the source and have it banned, e.g. by fail2ban
+}
+
+// defaultIncidentGap groups a source's events into incidents in the detail
+// view unless set with WithIncidentGap
+const defaultIncidentGap = time.Minute * 5
+
+// renderDetail renders every field of event, the enrichment of its source
+// and the source's history, incidents and payload
+func (m Model) renderDetail(event models.ScanEvent) string {
+	var b strings.Builder
+
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "  %s %s\n", m.styles.text.Render(fmt.Sprintf("%-13s", label+":")), value)
+	}
+
+	b.WriteString(m.styles.section.Render("Event") + "\n")
+	field("ID", event.ID)
+	field("Time", event.Timestamp.Format("2006-01-02 15:04:05 MST"))
+	field("Source", fmt.Sprintf("%s:%d", event.SourceIP, event.SourcePort))
+	field("Target Port", strconv.Itoa(event.TargetPort))
+	field("Protocol", event.Protocol)
+	field("Type", event.ScanType)
+	field("Severity", event.Severity.String())
+	field("Description", event.Description)
+	field("User Agent", event.UserAgent)
+	field("Tags", strings.Join(event.Tags, ", "))
+	if event.AnomalyScore != 0 {
+		field("Anomaly Score", fmt.Sprintf("%.2f", event.AnomalyScore))
+	}
+	acknowledged := "no"
+	if event.Acknowledged {
+		acknowledged = "yes"
+	}
+	field("Acknowledged", acknowledged)
+	field("Note", event.Note)
+
+	b.WriteString("\n" + m.styles.section.Render("Enrichment") + "\n")
+	field("Hostname", event.Hostname)
+	location := event.City
+	if event.Country != "" {
+		location = strings.TrimPrefix(location+", "+event.Country, ", ")
+	}
+	field("Location", location)
+	asn := ""
+	if event.ASN != 0 {
+		asn = fmt.Sprintf("AS%d %s", event.ASN, event.Org)
+	}
+	field("Network", strings.TrimSpace(asn))
+
+	history := m.sourceHistory(event.SourceIP)
+	b.WriteString("\n" + m.styles.section.Render(fmt.Sprintf("Source History (%d events)", len(history))) + "\n")
+	if len(history) > 0 {
+		field("First Seen", history[0].Timestamp.Format("2006-01-02 15:04:05"))
+		field("Last Seen", history[len(history)-1].Timestamp.Format("2006-01-02 15:04:05"))
+		field("Ports", utils.JoinPorts(touchedPorts(history)))
+
+		b.WriteString("\n  Timeline:\n")
+		for _, e := range history {
+			marker := " "
+			if e.ID == event.ID {
+				marker = "▶"
+			}
+			fmt.Fprintf(&b, "  %s %s  port %-5d %-4s %-8s %s\n", marker,
+				e.Timestamp.Format("2006-01-02 15:04:05"), e.TargetPort, e.Protocol, e.Severity, e.ScanType)
+		}
+	}
+
+	incidents := models.GroupIncidents(history, m.incidentGap)
+	b.WriteString("\n" + m.styles.section.Render(fmt.Sprintf("Incidents (%d)", len(incidents))) + "\n")
+	for _, incident := range incidents {
+		marker := " "
+		for _, id := range incident.EventIDs {
+			if id == event.ID {
+				marker = "▶"
+			}
+		}
+		fmt.Fprintf(&b, "  %s %s  %s – %s  %d events  ports %s  %s\n", marker, incident.ID,
+			incident.FirstSeen.Format("15:04:05"), incident.LastSeen.Format("15:04:05"),
+			incident.EventCount, utils.JoinPorts(incident.Ports), incident.Severity)
+	}
+
+	b.WriteString("\n" + m.styles.section.Render(fmt.Sprintf("Payload (%d bytes)", len(event.Payload))) + "\n")
+	if event.Payload == "" {
+		b.WriteString("  No payload captured.\n")
+	} else {
+		for _, line := range strings.Split(strings.TrimRight(hex.Dump([]byte(event.Payload)), "\n"), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// sourceHistory returns the events from ip in time order
+func (m Model) sourceHistory(ip string) []models.ScanEvent {
+	var history []models.ScanEvent
+	for _, e := range m.events {
+		if e.SourceIP == ip {
+			history = append(history, e)
+		}
+	}
+	return history
+}
+
+// touchedPorts returns the ports of events in the order they were first hit
+func touchedPorts(events []models.ScanEvent) []int {
+	seen := make(map[int]bool)
+	var ports []int
+	for _, e := range events {
+		if !seen[e.TargetPort] {
+			seen[e.TargetPort] = true
+			ports = append(ports, e.TargetPort)
+		}
+	}
+	return ports
+}
+
+// updateDetail handles keys while the detail view is open
+func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ip := m.detail.SourceIP
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+		return m, nil
+	case key.Matches(msg, m.keys.Back):
+		m.detail = nil
+		m.status = ""
+		return m, nil
+	case key.Matches(msg, m.keys.Acknowledge):
+		m.acknowledge()
+		return m, nil
+	case key.Matches(msg, m.keys.Note):
+		return m, m.startNote()
+	case key.Matches(msg, m.keys.Whitelist):
+		m.status = m.runAction("Whitelisted", ip, func(a Actions) error { return a.Whitelist(ip) })
+		return m, nil
+	case key.Matches(msg, m.keys.Blacklist):
+		m.status = m.runAction("Blacklisted", ip, func(a Actions) error { return a.Blacklist(ip) })
+		return m, nil
+	case key.Matches(msg, m.keys.Block):
+		m.status = m.runAction("Blocked", ip, func(a Actions) error { return a.Block(ip) })
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.detailView, cmd = m.detailView.Update(msg)
+	return m, cmd
+}
+
+// runAction runs an action on a source and describes the outcome
+func (m Model) runAction(done, ip string, action func(Actions) error) string {
+	if m.actions == nil {
+		return "Source actions are not available"
+	}
+	if err := action(m.actions); err != nil {
+		if m.debug {
+			log.Printf("[DEBUG] Action on %s failed: %v", ip, err)
+		}
+		return fmt.Sprintf("Failed: %v", err)
+	}
+	return fmt.Sprintf("%s %s", done, ip)
+}
+
+// viewDetail renders the detail view below the header
+func (m Model) viewDetail() string {
+	var doc strings.Builder
+
+	doc.WriteString(fmt.Sprintf("Details for %s:\n", m.detail.SourceIP))
+	doc.WriteString(m.detailView.View())
+	doc.WriteString("\n")
+
+	if m.noting {
+		return doc.String() + m.noteInput.View()
+	}
+	if m.status != "" {
+		doc.WriteString(m.styles.text.Render(m.status) + "  ")
+	}
+	doc.WriteString(m.help.View(m.helpKeys()))
+
+	return doc.String()
+}
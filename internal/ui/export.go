@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exportFormats are the formats the events table is exported in, by the
+// file extension selecting them
+var exportFormats = []struct{ ext, format string }{
+	{".json", "json"},
+	{".csv", "csv"},
+	{".md", "markdown"},
+}
+
+// WithExportDir sets the directory the export prompt suggests writing to
+func (m Model) WithExportDir(dir string) Model {
+	if dir != "" {
+		m.exportDir = dir
+	}
+	return m
+}
+
+// startExport opens the export prompt with a new file in the export
+// directory
+func (m *Model) startExport() tea.Cmd {
+	name := "portscammer-" + time.Now().Format("20060102-150405") + exportFormats[0].ext
+	m.exporting = true
+	m.status = ""
+	m.exportInput.SetValue(filepath.Join(m.exportDir, name))
+	m.exportInput.CursorEnd()
+	return m.exportInput.Focus()
+}
+
+// updateExport handles keys while the export path is typed. Tab switches to
+// the next format by changing the extension.
+func (m Model) updateExport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.exporting = false
+		m.exportInput.Blur()
+		return m, nil
+	case "tab":
+		m.exportInput.SetValue(nextExportExt(m.exportInput.Value()))
+		m.exportInput.CursorEnd()
+		return m, nil
+	case "enter":
+		m.exporting = false
+		m.exportInput.Blur()
+		path := strings.TrimSpace(m.exportInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		if err := writeExport(path, m.rows); err != nil {
+			m.status = fmt.Sprintf("Failed: %v", err)
+		} else {
+			m.status = fmt.Sprintf("Exported %d events to %s", len(m.rows), path)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.exportInput, cmd = m.exportInput.Update(msg)
+	return m, cmd
+}
+
+// nextExportExt replaces the extension of path with that of the next export
+// format, or adds the first when path has none of them
+func nextExportExt(path string) string {
+	ext := filepath.Ext(path)
+	for i, f := range exportFormats {
+		if strings.EqualFold(ext, f.ext) {
+			return strings.TrimSuffix(path, ext) + exportFormats[(i+1)%len(exportFormats)].ext
+		}
+	}
+	return path + exportFormats[0].ext
+}
+
+// writeExport writes events to path with the export formatter selected by
+// its extension, creating the directory if needed
+func writeExport(path string, events []models.ScanEvent) error {
+	var format string
+	exts := make([]string, len(exportFormats))
+	for i, f := range exportFormats {
+		exts[i] = f.ext
+		if strings.EqualFold(filepath.Ext(path), f.ext) {
+			format = f.format
+		}
+	}
+	if format == "" {
+		return fmt.Errorf("unknown extension %q, must be one of %s", filepath.Ext(path), strings.Join(exts, ", "))
+	}
+
+	formatter, err := export.Get(format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := formatter.FormatEvents(file, events); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
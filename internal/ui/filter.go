@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filter selects the events shown in the table and the activity log. It
+// combines an event filter with free-text terms.
+type filter struct {
+	models.EventFilter
+	terms        []string // Lower-cased terms that must all occur in an event
+	acknowledged *bool    // Only acknowledged or unacknowledged events, nil for both
+}
+
+// parseFilter parses a search query. Terms of the form key:value set the
+// severity (minimum), proto, port, ip (address or CIDR range), type and
+// ack (yes or no) filters; a bare address or range filters on the source.
+// Other terms must occur in the event's source, hostname, description,
+// type, protocol, location, organisation, tags or note.
+func parseFilter(query string) (filter, error) {
+	var f filter
+	for _, term := range strings.Fields(query) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			if prefix, err := parsePrefix(term); err == nil {
+				f.Network = prefix
+			} else {
+				f.terms = append(f.terms, strings.ToLower(term))
+			}
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "severity", "sev":
+			severity, err := models.ParseSeverity(value)
+			if err != nil {
+				return filter{}, err
+			}
+			f.MinSeverity = severity
+		case "protocol", "proto":
+			f.Protocol = strings.ToLower(value)
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return filter{}, fmt.Errorf("invalid port %q", value)
+			}
+			f.Port = port
+		case "ip", "src":
+			prefix, err := parsePrefix(value)
+			if err != nil {
+				return filter{}, err
+			}
+			f.Network = prefix
+		case "type":
+			f.ScanType = value
+		case "ack", "acknowledged":
+			var acknowledged bool
+			switch strings.ToLower(value) {
+			case "yes", "true":
+				acknowledged = true
+			case "no", "false":
+			default:
+				return filter{}, fmt.Errorf("invalid ack %q, must be yes or no", value)
+			}
+			f.acknowledged = &acknowledged
+		default:
+			// An IPv6 address also contains colons
+			if prefix, err := parsePrefix(term); err == nil {
+				f.Network = prefix
+			} else {
+				f.terms = append(f.terms, strings.ToLower(term))
+			}
+		}
+	}
+	return f, nil
+}
+
+// parsePrefix parses an IP address or CIDR range
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid range %q", s)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// active reports whether the filter selects anything less than every event
+func (f filter) active() bool {
+	return f.String() != ""
+}
+
+// match checks if the event is selected by the filter
+func (f filter) match(event models.ScanEvent) bool {
+	if !f.EventFilter.Match(event) {
+		return false
+	}
+	if f.acknowledged != nil && event.Acknowledged != *f.acknowledged {
+		return false
+	}
+	if len(f.terms) == 0 {
+		return true
+	}
+
+	text := strings.ToLower(strings.Join([]string{
+		event.SourceIP, event.Hostname, event.Description, event.ScanType, event.Protocol,
+		event.Country, event.City, event.Org, strings.Join(event.Tags, " "), event.Note,
+	}, " "))
+	for _, term := range f.terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the events selected by the filter, preserving their order
+func (f filter) apply(events []models.ScanEvent) []models.ScanEvent {
+	if !f.active() {
+		return events
+	}
+	result := make([]models.ScanEvent, 0, len(events))
+	for _, event := range events {
+		if f.match(event) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// String returns the filter as a search query, empty when it is inactive
+func (f filter) String() string {
+	var parts []string
+	if f.MinSeverity > models.SeverityLow {
+		parts = append(parts, "severity:"+strings.ToLower(f.MinSeverity.String()))
+	}
+	if f.Protocol != "" {
+		parts = append(parts, "proto:"+f.Protocol)
+	}
+	if f.Port != 0 {
+		parts = append(parts, "port:"+strconv.Itoa(f.Port))
+	}
+	if f.Network.IsValid() {
+		network := f.Network.String()
+		if f.Network.IsSingleIP() {
+			network = f.Network.Addr().String()
+		}
+		parts = append(parts, "ip:"+network)
+	}
+	if f.ScanType != "" {
+		parts = append(parts, "type:"+f.ScanType)
+	}
+	if f.acknowledged != nil {
+		if *f.acknowledged {
+			parts = append(parts, "ack:yes")
+		} else {
+			parts = append(parts, "ack:no")
+		}
+	}
+	parts = append(parts, f.terms...)
+	return strings.Join(parts, " ")
+}
+
+// quickProtocols are the protocols cycled through by the protocol filter key
+var quickProtocols = []string{"", "tcp", "udp"}
+
+// nextProtocol returns the protocol following p in the quick filter cycle
+func nextProtocol(p string) string {
+	for i, protocol := range quickProtocols {
+		if protocol == p {
+			return quickProtocols[(i+1)%len(quickProtocols)]
+		}
+	}
+	return quickProtocols[0]
+}
+
+// updateSearch handles keys while the search prompt has focus. The filter
+// follows the query as it is typed.
+func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.searching = false
+		m.status = ""
+		m.filter = m.prevFilter
+		m.search.Blur()
+		m.updateRows()
+		return m, nil
+	case "enter":
+		f, err := parseFilter(m.search.Value())
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m.searching = false
+		m.status = ""
+		m.filter = f
+		m.search.Blur()
+		m.updateRows()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	if f, err := parseFilter(m.search.Value()); err == nil {
+		m.status = ""
+		m.filter = f
+		m.updateRows()
+	} else {
+		m.status = err.Error()
+	}
+	return m, cmd
+}
+
+// quickFilter changes the filter for a quick filter key, by default: s
+// raises the minimum severity, t cycles the protocol, o and i toggle the port
+// and source of the selected event and c clears the filter
+func (m *Model) quickFilter(msg tea.KeyMsg) {
+	var selected *models.ScanEvent
+	if cursor := m.table.Cursor(); cursor >= 0 && cursor < len(m.rows) {
+		selected = &m.rows[cursor]
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Severity):
+		m.filter.MinSeverity = (m.filter.MinSeverity + 1) % (models.SeverityCritical + 1)
+	case key.Matches(msg, m.keys.Protocol):
+		m.filter.Protocol = nextProtocol(m.filter.Protocol)
+	case key.Matches(msg, m.keys.Port):
+		if m.filter.Port != 0 {
+			m.filter.Port = 0
+		} else if selected != nil {
+			m.filter.Port = selected.TargetPort
+		}
+	case key.Matches(msg, m.keys.IP):
+		if m.filter.Network.IsValid() {
+			m.filter.Network = netip.Prefix{}
+		} else if selected != nil {
+			if prefix, err := parsePrefix(selected.SourceIP); err == nil {
+				m.filter.Network = prefix
+			}
+		}
+	case key.Matches(msg, m.keys.Clear):
+		m.filter = filter{}
+	}
+
+	m.updateRows()
+}
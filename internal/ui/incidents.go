@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// incidentTableColumns returns the columns of the Incidents tab
+func (m Model) incidentTableColumns() []table.Column {
+	return fillLast([]table.Column{
+		{Title: "First Seen", Width: 19},
+		{Title: "Last Seen", Width: 8},
+		{Title: "Source IP", Width: 15},
+		{Title: "Events", Width: 6},
+		{Title: "Severity", Width: 8},
+		{Title: "Ack", Width: 5},
+		{Title: "Ports", Width: 20},
+	}, m.tableWidth())
+}
+
+// updateIncidentRows groups the events into incidents, newest first
+func (m *Model) updateIncidentRows() {
+	incidents := models.GroupIncidents(m.events, m.incidentGap)
+	m.incidents = make([]models.Incident, 0, len(incidents))
+	rows := make([]table.Row, 0, len(incidents))
+	for i := len(incidents) - 1; i >= 0; i-- {
+		incident := incidents[i]
+		m.incidents = append(m.incidents, incident)
+
+		// Partly acknowledged incidents show how many of their events are
+		acknowledged := ""
+		switch {
+		case incident.Acknowledged == incident.EventCount:
+			acknowledged = "✓"
+		case incident.Acknowledged > 0:
+			acknowledged = fmt.Sprintf("%d/%d", incident.Acknowledged, incident.EventCount)
+		}
+		rows = append(rows, table.Row{
+			incident.FirstSeen.Format("2006-01-02 15:04:05"),
+			incident.LastSeen.Format("15:04:05"),
+			incident.SourceIP,
+			strconv.Itoa(incident.EventCount),
+			incident.Severity.String(),
+			acknowledged,
+			utils.JoinPorts(incident.Ports),
+		})
+	}
+	m.incidentsTable.SetRows(rows)
+}
+
+// updateIncidents handles keys in the Incidents tab. Enter opens the detail
+// view of the last event of the selected incident, and the annotation keys
+// act on all its events.
+func (m Model) updateIncidents(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Details):
+		if cursor := m.incidentsTable.Cursor(); cursor >= 0 && cursor < len(m.incidents) {
+			ids := m.incidents[cursor].EventIDs
+			for _, event := range m.events {
+				if event.ID == ids[len(ids)-1] {
+					m.openDetail(event)
+					break
+				}
+			}
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Acknowledge):
+		m.acknowledge()
+		return m, nil
+	case key.Matches(msg, m.keys.Note):
+		return m, m.startNote()
+	}
+
+	var cmd tea.Cmd
+	m.incidentsTable, cmd = m.incidentsTable.Update(msg)
+	return m, cmd
+}
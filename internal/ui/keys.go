@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// KeyMap holds the key bindings of the TUI
+type KeyMap struct {
+	// Global
+	Quit    key.Binding
+	Refresh key.Binding
+	NextTab key.Binding
+	PrevTab key.Binding
+	Help    key.Binding
+	Pause   key.Binding
+
+	// Tables and the detail view
+	Details     key.Binding
+	Back        key.Binding
+	Sort        key.Binding
+	SortColumn  key.Binding
+	SortReverse key.Binding
+
+	// Events and Incidents tabs and the detail view
+	Acknowledge key.Binding
+	Note        key.Binding
+
+	// Events tab
+	Search   key.Binding
+	Severity key.Binding
+	Protocol key.Binding
+	Port     key.Binding
+	IP       key.Binding
+	Clear    key.Binding
+	Export   key.Binding
+
+	// Detail view
+	Whitelist key.Binding
+	Blacklist key.Binding
+	Block     key.Binding
+
+	// Ports tab
+	Order key.Binding
+
+	// Blocklist tab
+	SwitchList key.Binding
+	Add        key.Binding
+	Remove     key.Binding
+}
+
+// DefaultKeyMap returns the default key bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+		Refresh: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+		NextTab: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next view")),
+		PrevTab: key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous view")),
+		Help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+		Pause:   key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pause")),
+
+		Details: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "details")),
+		Back:    key.NewBinding(key.WithKeys("esc", "backspace", "enter"), key.WithHelp("esc", "back")),
+		Sort: key.NewBinding(key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+			key.WithHelp("1-9", "sort")),
+		SortColumn:  key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "next sort column")),
+		SortReverse: key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "reverse sort")),
+
+		Acknowledge: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "acknowledge")),
+		Note:        key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "note")),
+
+		Search:   key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		Severity: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "severity")),
+		Protocol: key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "protocol")),
+		Port:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "port")),
+		IP:       key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "IP")),
+		Clear:    key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "clear")),
+		Export:   key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "export")),
+
+		Whitelist: key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "whitelist")),
+		Blacklist: key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "blacklist")),
+		Block:     key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "block")),
+
+		Order: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "order by port/count")),
+
+		SwitchList: key.NewBinding(key.WithKeys("left", "right", "h", "l"), key.WithHelp("←/→", "allow/deny list")),
+		Add:        key.NewBinding(key.WithKeys("n", "a"), key.WithHelp("n", "add")),
+		Remove:     key.NewBinding(key.WithKeys("x", "delete"), key.WithHelp("x", "remove")),
+	}
+}
+
+// bindings returns the remappable bindings by the action name used in the
+// configuration. The number keys pick a sort column by number and are not
+// remappable.
+func (k *KeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":         &k.Quit,
+		"refresh":      &k.Refresh,
+		"next_tab":     &k.NextTab,
+		"prev_tab":     &k.PrevTab,
+		"help":         &k.Help,
+		"pause":        &k.Pause,
+		"details":      &k.Details,
+		"acknowledge":  &k.Acknowledge,
+		"note":         &k.Note,
+		"back":         &k.Back,
+		"sort_column":  &k.SortColumn,
+		"sort_reverse": &k.SortReverse,
+		"search":       &k.Search,
+		"severity":     &k.Severity,
+		"protocol":     &k.Protocol,
+		"port":         &k.Port,
+		"ip":           &k.IP,
+		"clear":        &k.Clear,
+		"export":       &k.Export,
+		"whitelist":    &k.Whitelist,
+		"blacklist":    &k.Blacklist,
+		"block":        &k.Block,
+		"order":        &k.Order,
+		"switch_list":  &k.SwitchList,
+		"add":          &k.Add,
+		"remove":       &k.Remove,
+	}
+}
+
+// KeyActions returns the names of the actions that can be remapped
+func KeyActions() []string {
+	var k KeyMap
+	names := make([]string, 0, len(k.bindings()))
+	for name := range k.bindings() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseKeyMap returns the default key bindings with the remappings in specs
+// applied. Each spec is action=keys, with the keys separated by commas, e.g.
+// quit=ctrl+q or search=f,/.
+func ParseKeyMap(specs []string) (KeyMap, error) {
+	keys := DefaultKeyMap()
+	bindings := keys.bindings()
+	for _, spec := range specs {
+		action, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return keys, fmt.Errorf("invalid key binding %q, must be action=keys", spec)
+		}
+		binding, ok := bindings[strings.TrimSpace(action)]
+		if !ok {
+			return keys, fmt.Errorf("unknown key action %q, must be one of %s", action, strings.Join(KeyActions(), ", "))
+		}
+
+		var names []string
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return keys, fmt.Errorf("no keys given for %q", action)
+		}
+		binding.SetKeys(names...)
+		binding.SetHelp(strings.Join(names, "/"), binding.Help().Desc)
+	}
+	return keys, nil
+}
+
+// helpKeys are the bindings shown in the footer and the help overlay
+type helpKeys struct {
+	short []key.Binding
+	full  [][]key.Binding
+}
+
+// ShortHelp returns the bindings shown in the footer
+func (h helpKeys) ShortHelp() []key.Binding {
+	return h.short
+}
+
+// FullHelp returns the bindings shown in the help overlay, by column
+func (h helpKeys) FullHelp() [][]key.Binding {
+	return h.full
+}
+
+// helpKeys returns the bindings of the current view
+func (m Model) helpKeys() helpKeys {
+	k := m.keys
+	var view []key.Binding
+	switch {
+	case m.detail != nil:
+		view = []key.Binding{k.Acknowledge, k.Note, k.Whitelist, k.Blacklist, k.Block, k.Back}
+	case m.tab == tabSources:
+		sort := k.Sort
+		sort.SetHelp(fmt.Sprintf("1-%d", len(sourceColumns)), "sort")
+		view = []key.Binding{k.Details, sort, k.SortColumn, k.SortReverse}
+	case m.tab == tabPorts:
+		view = []key.Binding{k.Order}
+	case m.tab == tabIncidents:
+		view = []key.Binding{k.Details, k.Acknowledge, k.Note}
+	case m.tab == tabLists:
+		view = []key.Binding{k.SwitchList, k.Add, k.Remove}
+	default:
+		view = []key.Binding{k.Details, k.Acknowledge, k.Note, k.Search, k.Severity, k.Protocol, k.Port, k.IP, k.Clear, k.Sort, k.SortColumn, k.SortReverse, k.Export}
+	}
+
+	pause := k.Pause
+	if m.paused {
+		pause.SetHelp(pause.Help().Key, "resume")
+	}
+
+	nav := table.DefaultKeyMap()
+	return helpKeys{
+		short: append(view, pause, k.NextTab, k.Help, k.Quit),
+		full: [][]key.Binding{
+			view,
+			{nav.LineUp, nav.LineDown, nav.PageUp, nav.PageDown, nav.GotoTop, nav.GotoBottom},
+			{k.NextTab, k.PrevTab, pause, k.Refresh, k.Help, k.Quit},
+		},
+	}
+}
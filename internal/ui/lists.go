@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Names of the managed lists
+const (
+	ListAllow = "allow"
+	ListDeny  = "deny"
+)
+
+// Lists manages the allow and deny lists shown in the Blocklist tab
+type Lists interface {
+	Entries(list string) []string
+	Add(list, entry string) error
+	Remove(list, entry string) error
+}
+
+// listNames are the managed lists in the order they are switched through
+var listNames = []string{ListAllow, ListDeny}
+
+// listTableColumns returns the columns of the Blocklist tab
+func (m Model) listTableColumns() []table.Column {
+	return fillLast([]table.Column{{Title: "Entry", Width: 43}}, m.tableWidth())
+}
+
+// updateListRows shows the entries of the selected list
+func (m *Model) updateListRows() {
+	m.listEntries = m.listCache[listNames[m.listIndex]]
+	rows := make([]table.Row, len(m.listEntries))
+	for i, entry := range m.listEntries {
+		rows[i] = table.Row{entry}
+	}
+	m.listsTable.SetRows(rows)
+	if cursor := m.listsTable.Cursor(); cursor >= len(rows) && len(rows) > 0 {
+		m.listsTable.SetCursor(len(rows) - 1)
+	}
+}
+
+// renderListTabs renders the list selector of the Blocklist tab
+func (m Model) renderListTabs() string {
+	titles := map[string]string{ListAllow: "Allow list", ListDeny: "Deny list"}
+	out := ""
+	for i, name := range listNames {
+		title := fmt.Sprintf("%s (%d)", titles[name], len(m.listCache[name]))
+		if i == m.listIndex {
+			out += m.styles.activeTab.Render(title)
+		} else {
+			out += m.styles.inactiveTab.Render(title)
+		}
+		out += " "
+	}
+	return out
+}
+
+// updateLists handles keys in the Blocklist tab
+func (m Model) updateLists(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.lists == nil {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.SwitchList):
+		m.listIndex = (m.listIndex + 1) % len(listNames)
+		m.status = ""
+		m.listsTable.SetCursor(0)
+		m.updateListRows()
+		return m, nil
+	case key.Matches(msg, m.keys.Add):
+		m.listAdding = true
+		m.status = ""
+		m.listInput.SetValue("")
+		return m, m.listInput.Focus()
+	case key.Matches(msg, m.keys.Remove):
+		if cursor := m.listsTable.Cursor(); cursor >= 0 && cursor < len(m.listEntries) {
+			list, entry := listNames[m.listIndex], m.listEntries[cursor]
+			if err := m.lists.Remove(list, entry); err != nil {
+				m.status = fmt.Sprintf("Failed: %v", err)
+			} else {
+				m.status = fmt.Sprintf("Removed %s from the %s list", entry, list)
+			}
+			cmd := m.load(m.loadLists())
+			m.updateListRows()
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.listsTable, cmd = m.listsTable.Update(msg)
+	return m, cmd
+}
+
+// updateListInput handles keys while an entry is typed into a list
+func (m Model) updateListInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.listAdding = false
+		m.listInput.Blur()
+		return m, nil
+	case "enter":
+		m.listAdding = false
+		m.listInput.Blur()
+		list, entry := listNames[m.listIndex], m.listInput.Value()
+		if entry == "" {
+			return m, nil
+		}
+		if err := m.lists.Add(list, entry); err != nil {
+			m.status = fmt.Sprintf("Failed: %v", err)
+		} else {
+			m.status = fmt.Sprintf("Added %s to the %s list", entry, list)
+		}
+		cmd := m.load(m.loadLists())
+		m.updateListRows()
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.listInput, cmd = m.listInput.Update(msg)
+	return m, cmd
+}
+
+// viewLists renders the Blocklist tab
+func (m Model) viewLists() string {
+	if m.lists == nil {
+		return "List management is not available."
+	}
+
+	out := m.renderListTabs() + "\n\n"
+	if m.listAdding {
+		out += m.listInput.View() + "\n"
+	} else if m.status != "" {
+		out += m.styles.text.Render(m.status) + "\n"
+	}
+	return out + m.listsTable.View()
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"log"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sourceEventsMsg carries the events read from the source
+type sourceEventsMsg struct {
+	events []models.ScanEvent
+}
+
+// statsMsg carries the statistics read from the source
+type statsMsg struct {
+	stats models.ScanStats
+}
+
+// entriesMsg carries the entries of the allow and deny lists by list name
+type entriesMsg struct {
+	entries map[string][]string
+}
+
+// loadEvents returns a command reading the events from the source
+func (m Model) loadEvents() tea.Cmd {
+	source := m.source
+	return func() tea.Msg {
+		return sourceEventsMsg{events: source.GetEvents()}
+	}
+}
+
+// loadStats returns a command reading the statistics from the source
+func (m Model) loadStats() tea.Cmd {
+	source := m.source
+	return func() tea.Msg {
+		return statsMsg{stats: source.GetStats()}
+	}
+}
+
+// loadLists returns a command reading the entries of the lists, or nil
+// when the Blocklist tab is disabled
+func (m Model) loadLists() tea.Cmd {
+	lists := m.lists
+	if lists == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		entries := make(map[string][]string, len(listNames))
+		for _, name := range listNames {
+			entries[name] = lists.Entries(name)
+		}
+		return entriesMsg{entries: entries}
+	}
+}
+
+// load runs the given commands reading from the source. A RemoteSource is
+// read in the background, so requests to the daemon never hold up the TUI,
+// and the data arrives as messages; the commands are returned for that.
+// Other sources are read in memory and their data is kept right away.
+func (m *Model) load(cmds ...tea.Cmd) tea.Cmd {
+	if _, remote := m.source.(RemoteSource); remote {
+		return tea.Batch(cmds...)
+	}
+	for _, cmd := range cmds {
+		if cmd != nil {
+			m.keep(cmd())
+		}
+	}
+	return nil
+}
+
+// keep stores the data read from the source. The views are rebuilt by the
+// caller.
+func (m *Model) keep(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case sourceEventsMsg:
+		m.events = msg.events
+		if m.subscription != nil {
+			m.loaded = make(map[string]bool, len(m.events))
+			for _, event := range m.events {
+				m.loaded[event.ID] = true
+			}
+		}
+		if m.debug {
+			log.Printf("[DEBUG] UI refresh - Retrieved %d events", len(m.events))
+		}
+	case statsMsg:
+		m.stats = msg.stats
+	case entriesMsg:
+		m.listCache = msg.entries
+	}
+}
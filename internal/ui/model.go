@@ -7,80 +7,248 @@ import (
 	"time"
 
 	"jonasbn.github.com/portscammer/internal/models"
-	"jonasbn.github.com/portscammer/internal/portscammer"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Source provides the events and statistics shown by the TUI. The TUI runs
+// on the scanner of its own process, or attached to a daemon through the
+// client of its API.
+type Source interface {
+	GetEvents() []models.ScanEvent
+	GetStats() models.ScanStats
+}
+
+// RemoteSource is a Source that reaches its data over a connection. It is
+// read in the background, and the header shows the connection failing while
+// Err returns an error.
+type RemoteSource interface {
+	Err() error
+}
+
+// HostnameSource is a Source that resolves the hostnames of sources after
+// their events are published. Pushed events get their hostname once it is
+// resolved. Hostname must not block.
+type HostnameSource interface {
+	Hostname(ip string) (string, bool)
+}
+
 // Model represents the UI model for the TUI
 type Model struct {
-	scanner    *portscammer.Scanner
-	table      table.Model
-	viewport   viewport.Model
-	events     []models.ScanEvent
-	stats      models.ScanStats
-	width      int
-	height     int
-	ready      bool
-	lastUpdate time.Time
-	tickCount  int  // Counter for debug logging every 10 seconds
-	debug      bool // Debug flag from configuration
+	source      Source
+	actions     Actions // Actions on sources, nil disables them
+	lists       Lists   // Allow and deny lists, nil disables the Blocklist tab
+	incidentGap time.Duration
+	tab         tab // Current tab
+	table       table.Model
+	columns     []string // Names of the table columns, in order
+	sortColumn  string   // Name of the column the rows are sorted on
+	sortDesc    bool
+	tableTop    int // Index of the first row shown by the events table
+	viewport    viewport.Model
+	detailView  viewport.Model
+	search      textinput.Model
+	searching   bool   // Whether the search prompt has focus
+	filter      filter // Filter applied to the table and the activity log
+	prevFilter  filter // Filter to restore when the search is cancelled
+	events      []models.ScanEvent
+	filtered    []models.ScanEvent // Events selected by the filter, oldest first
+	rows        []models.ScanEvent // Events shown in the table, in row order
+	detail      *models.ScanEvent  // Event shown in the detail view, nil when closed
+	status      string             // Outcome of the last action
+	stats       models.ScanStats
+	history     []models.StatsMinute // Event counts of the last hour, oldest first
+	width       int
+	height      int
+	ready       bool
+	lastUpdate  time.Time
+	tickCount   int  // Counter for debug logging every 10 seconds
+	debug       bool // Debug flag from configuration
+
+	// Pushed events
+	subscription  <-chan models.ScanEvent // Events as they happen, nil to poll the source
+	refreshRate   time.Duration           // Minimum time between rebuilding the views
+	loaded        map[string]bool         // IDs of the events of the last load, which may be pushed again
+	dirty         bool                    // Whether events arrived since the views were rebuilt
+	renderPending bool                    // Whether a rebuild is scheduled
+
+	// Sources tab
+	sourcesTable table.Model
+	sources      []sourceRow // Sources shown in the table, in row order
+	sourceSort   int         // Index of the column the sources are sorted on
+	sourceDesc   bool
+
+	// Ports tab
+	portsView    viewport.Model
+	portsByCount bool // Order the heatmap by count instead of port
+
+	// Incidents tab
+	incidentsTable table.Model
+	incidents      []models.Incident // Incidents shown in the table, newest first
+
+	// Blocklist tab
+	listsTable  table.Model
+	listIndex   int                 // Index of the list shown, see listNames
+	listEntries []string            // Entries shown in the table
+	listCache   map[string][]string // Entries of the lists by name, as last read
+	listInput   textinput.Model
+	listAdding  bool // Whether an entry is being typed
+
+	// Keys and theme
+	keys     KeyMap
+	styles   styles
+	help     help.Model
+	showHelp bool // Whether the full key help is shown
+
+	// Paused view
+	paused  bool
+	pending []models.ScanEvent // Events pushed while paused, shown on resume
+	unseen  int                // Number of events not shown while paused
+
+	// Annotations
+	noteInput textinput.Model
+	noting    bool     // Whether a note is being typed
+	noteIDs   []string // IDs of the events the note is for
+
+	// Export
+	exportDir   string // Directory suggested by the export prompt
+	exportInput textinput.Model
+	exporting   bool // Whether the export path is being typed
 }
 
 // NewModel creates a new UI model
-func NewModel(scanner *portscammer.Scanner, debug bool) Model {
-	columns := []table.Column{
-		{Title: "Time", Width: 19},
-		{Title: "Source IP", Width: 15},
-		{Title: "Port", Width: 6},
-		{Title: "Type", Width: 12},
-		{Title: "Severity", Width: 8},
-		{Title: "Description", Width: 30},
+func NewModel(source Source, debug bool) Model {
+	m := Model{columns: DefaultColumns, sortColumn: "time", sortDesc: true, sourceSort: 1, sourceDesc: true}
+
+	theme, _ := LookupTheme(DefaultTheme)
+	m.keys = DefaultKeyMap()
+	m.styles = newStyles(theme)
+	m.help = help.New()
+	m.help.Styles = m.styles.help
+
+	vp := viewport.New(78, 20)
+	vp.Style = m.styles.frame
+
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "severity:high proto:tcp port:22 ip:203.0.113.0/24 text"
+	ti.CharLimit = 200
+
+	li := textinput.New()
+	li.Prompt = "Add: "
+	li.Placeholder = "203.0.113.7 or 198.51.100.0/24"
+	li.CharLimit = 50
+
+	ni := textinput.New()
+	ni.Prompt = "Note: "
+	ni.Placeholder = "free text, empty removes the note"
+	ni.CharLimit = 500
+
+	ei := textinput.New()
+	ei.Prompt = "Export to: "
+	ei.CharLimit = 500
+
+	m.source = source
+	m.search = ti
+	m.incidentGap = defaultIncidentGap
+	m.table = newTable(m.tableColumns(), m.styles.table)
+	m.viewport = vp
+	m.detailView = viewport.New(78, 20)
+	m.sourcesTable = newTable(m.sourceTableColumns(), m.styles.table)
+	m.portsView = viewport.New(78, 20)
+	m.incidentsTable = newTable(m.incidentTableColumns(), m.styles.table)
+	m.listsTable = newTable(m.listTableColumns(), m.styles.table)
+	m.listInput = li
+	m.noteInput = ni
+	m.exportInput = ei
+	m.exportDir = "."
+	m.events = make([]models.ScanEvent, 0)
+	m.lastUpdate = time.Now()
+	m.refreshRate = defaultRefreshRate
+	m.debug = debug
+	return m
+}
+
+// WithColumns sets the table columns, in order. Unknown names are ignored,
+// see CheckColumns.
+func (m Model) WithColumns(names []string) Model {
+	var known []string
+	for _, name := range names {
+		if _, ok := columns[name]; ok {
+			known = append(known, name)
+		}
+	}
+	if len(known) == 0 {
+		return m
 	}
 
-	t := table.New(
-		table.WithColumns(columns),
-		table.WithFocused(true),
-		table.WithHeight(10),
-	)
+	m.columns = known
+	m.table.SetRows(nil)
+	m.table.SetColumns(m.tableColumns())
+	return m
+}
 
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
-		BorderBottom(true).
-		Bold(false)
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
-		Bold(false)
-	t.SetStyles(s)
+// WithKeyMap sets the key bindings, see ParseKeyMap
+func (m Model) WithKeyMap(keys KeyMap) Model {
+	m.keys = keys
+	return m
+}
 
-	vp := viewport.New(78, 20)
-	vp.Style = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
-		PaddingRight(2)
-
-	return Model{
-		scanner:    scanner,
-		table:      t,
-		viewport:   vp,
-		events:     make([]models.ScanEvent, 0),
-		lastUpdate: time.Now(),
-		debug:      debug,
+// WithTheme sets the colors of the TUI. Colors are left out when the
+// NO_COLOR environment variable is set, whatever the theme.
+func (m Model) WithTheme(theme Theme) Model {
+	m.styles = newStyles(theme)
+	m.help.Styles = m.styles.help
+	m.viewport.Style = m.styles.frame
+	m.table.SetStyles(m.styles.table)
+	m.sourcesTable.SetStyles(m.styles.table)
+	m.incidentsTable.SetStyles(m.styles.table)
+	m.listsTable.SetStyles(m.styles.table)
+	m.portsView.SetContent(m.renderPorts())
+	return m
+}
+
+// WithActions enables the whitelist, blacklist and block keys of the
+// detail view
+func (m Model) WithActions(actions Actions) Model {
+	m.actions = actions
+	return m
+}
+
+// WithLists enables the Blocklist tab, which manages the allow and deny lists
+func (m Model) WithLists(lists Lists) Model {
+	m.lists = lists
+	m.load(m.loadLists()) // A remote source's lists are read with its events
+	m.updateListRows()
+	return m
+}
+
+// WithIncidentGap sets the maximum gap between events grouped into one
+// incident in the detail view
+func (m Model) WithIncidentGap(gap time.Duration) Model {
+	if gap > 0 {
+		m.incidentGap = gap
 	}
+	return m
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		tea.EnterAltScreen,
-		tickCmd(),
-	)
+		func() tea.Msg { return loadMsg{} },
+		tickCmd(m.tickInterval()),
+	}
+	if m.subscription != nil {
+		cmds = append(cmds, waitForEvents(m.subscription))
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update handles messages and updates the model
@@ -95,7 +263,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
-		headerHeight := 3
+		headerHeight := 3 + chartsHeight
 		footerHeight := 3
 		verticalMarginHeight := headerHeight + footerHeight
 
@@ -109,35 +277,185 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.table.SetWidth(msg.Width - 4)
+		m.table.SetColumns(m.tableColumns())
 		m.table.SetHeight(msg.Height/2 - 5)
+		m.scrollTable()
+
+		m.detailView.Width = msg.Width
+		m.detailView.Height = msg.Height - verticalMarginHeight
+
+		// The other tabs show a single table or view below the tab bar
+		tabHeight := msg.Height - verticalMarginHeight - 4
+		m.sourcesTable.SetWidth(msg.Width - 4)
+		m.sourcesTable.SetColumns(m.sourceTableColumns())
+		m.sourcesTable.SetHeight(tabHeight)
+		m.incidentsTable.SetWidth(msg.Width - 4)
+		m.incidentsTable.SetColumns(m.incidentTableColumns())
+		m.incidentsTable.SetHeight(tabHeight)
+		m.listsTable.SetWidth(msg.Width - 4)
+		m.listsTable.SetColumns(m.listTableColumns())
+		m.listsTable.SetHeight(tabHeight - 2)
+		m.portsView.Width = msg.Width
+		m.portsView.Height = tabHeight
+		m.portsView.SetContent(m.renderPorts())
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.listAdding {
+			return m.updateListInput(msg)
+		}
+		if m.noting {
+			return m.updateNote(msg)
+		}
+		if m.exporting {
+			return m.updateExport(msg)
+		}
+		if m.showHelp {
+			switch {
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, m.keys.Help, m.keys.Back):
+				m.showHelp = false
+			}
+			return m, nil
+		}
+		if m.detail != nil {
+			return m.updateDetail(msg)
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Quit):
 			if m.debug {
 				log.Printf("[DEBUG] Quit key pressed: %s", msg.String())
 			}
 			return m, tea.Quit
-		case "r":
+		case key.Matches(msg, m.keys.Refresh):
 			if m.debug {
 				log.Printf("[DEBUG] Refresh key pressed: %s", msg.String())
 			}
-			m.refresh()
-			return m, nil // Return immediately after refresh
+			// Refreshing a paused view resumes it
+			if m.paused {
+				return m, m.togglePause()
+			}
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+		case key.Matches(msg, m.keys.Pause):
+			return m, m.togglePause()
+		case key.Matches(msg, m.keys.NextTab):
+			m.tab = (m.tab + 1) % tabCount
+			m.status = ""
+			return m, nil
+		case key.Matches(msg, m.keys.PrevTab):
+			m.tab = (m.tab + tabCount - 1) % tabCount
+			m.status = ""
+			return m, nil
+		}
+
+		switch m.tab {
+		case tabSources:
+			return m.updateSources(msg)
+		case tabPorts:
+			return m.updatePorts(msg)
+		case tabIncidents:
+			return m.updateIncidents(msg)
+		case tabLists:
+			return m.updateLists(msg)
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Details):
+			if cursor := m.table.Cursor(); cursor >= 0 && cursor < len(m.rows) {
+				m.openDetail(m.rows[cursor])
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.Acknowledge):
+			m.acknowledge()
+			return m, nil
+		case key.Matches(msg, m.keys.Note):
+			return m, m.startNote()
+		case key.Matches(msg, m.keys.Export):
+			return m, m.startExport()
+		case key.Matches(msg, m.keys.Search):
+			m.searching = true
+			m.prevFilter = m.filter
+			m.status = ""
+			m.search.SetValue(m.filter.String())
+			m.search.CursorEnd()
+			return m, m.search.Focus()
+		case key.Matches(msg, m.keys.Severity, m.keys.Protocol, m.keys.Port, m.keys.IP, m.keys.Clear):
+			m.quickFilter(msg)
+			return m, nil
+		case key.Matches(msg, m.keys.Sort):
+			m.sortBy(int(msg.String()[0] - '1'))
+			return m, nil
+		case key.Matches(msg, m.keys.SortColumn):
+			m.sortNext()
+			return m, nil
+		case key.Matches(msg, m.keys.SortReverse):
+			m.reverseSort()
+			return m, nil
 		}
 
+	case loadMsg:
+		return m, m.refresh()
+
+	case sourceEventsMsg:
+		m.keep(msg)
+		return m, m.rebuild()
+
+	case statsMsg:
+		m.keep(msg)
+		m.updateSourceRows()
+		return m, nil
+
+	case entriesMsg:
+		m.keep(msg)
+		m.updateListRows()
+		return m, nil
+
+	case eventsMsg:
+		return m.receiveEvents(msg)
+
+	case renderMsg:
+		m.renderPending = false
+		if m.dirty {
+			return m, m.rebuild()
+		}
+		return m, nil
+
 	case tickMsg:
 		m.tickCount++
-		// Log debug message every 10 seconds (every 5 ticks since ticks are every 2 seconds)
+		// Log debug message every 5 ticks
 		if m.debug && m.tickCount%5 == 0 {
 			log.Printf("[DEBUG] UI tick update - Count: %d, Events: %d, Last Update: %s",
 				m.tickCount, len(m.events), m.lastUpdate.Format("15:04:05"))
 		}
-		m.refresh()
-		return m, tickCmd()
+		// Pushed events are already in place, so only the views are rebuilt
+		switch {
+		case m.paused:
+			m.countUnseen()
+		case m.subscription != nil:
+			cmd = m.rebuild()
+		default:
+			cmd = m.refresh()
+		}
+		return m, tea.Batch(cmd, tickCmd(m.tickInterval()))
+	}
+
+	if m.detail != nil {
+		m.detailView, cmd = m.detailView.Update(msg)
+		return m, cmd
+	}
+	if m.tab != tabEvents {
+		return m, nil
 	}
 
 	m.table, cmd = m.table.Update(msg)
+	m.scrollTable()
 	cmds = append(cmds, cmd)
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -155,19 +473,70 @@ func (m Model) View() string {
 	doc := strings.Builder{}
 
 	// Header
-	header := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("205")).
-		Render("Port Scanner Detector")
+	header := m.styles.title.Render("Port Scanner Detector")
 
 	statsText := m.renderStats()
 
 	doc.WriteString(lipgloss.JoinHorizontal(lipgloss.Left, header, "  ", statsText))
 	doc.WriteString("\n\n")
+	doc.WriteString(m.renderCharts())
+	doc.WriteString("\n\n")
+
+	if m.showHelp {
+		return doc.String() + m.viewHelp(m.height-3-chartsHeight)
+	}
+	if m.detail != nil {
+		return doc.String() + m.viewDetail()
+	}
+
+	doc.WriteString(m.renderTabs())
+	doc.WriteString("\n\n")
+
+	switch m.tab {
+	case tabSources:
+		doc.WriteString(fmt.Sprintf("Top Sources (%d):\n", len(m.sources)))
+		doc.WriteString(m.sourcesTable.View())
+	case tabPorts:
+		doc.WriteString("Port Heatmap:\n")
+		doc.WriteString(m.portsView.View())
+	case tabIncidents:
+		doc.WriteString(m.viewPrompt())
+		doc.WriteString(fmt.Sprintf("Incidents (%d):\n", len(m.incidents)))
+		doc.WriteString(m.incidentsTable.View())
+	case tabLists:
+		doc.WriteString(m.viewLists())
+	default:
+		doc.WriteString(m.viewEvents())
+	}
+
+	doc.WriteString("\n")
+	doc.WriteString(m.renderHelp())
+
+	return doc.String()
+}
+
+// viewEvents renders the Events tab
+func (m Model) viewEvents() string {
+	doc := strings.Builder{}
+
+	// Filter
+	if m.searching {
+		doc.WriteString(m.search.View())
+		if m.status != "" {
+			doc.WriteString("  " + m.status)
+		}
+		doc.WriteString("\n")
+	} else {
+		if m.filter.active() {
+			doc.WriteString(m.styles.text.Render(fmt.Sprintf("Filter: %s (%d of %d events)", m.filter, len(m.filtered), len(m.events))))
+			doc.WriteString("\n")
+		}
+		doc.WriteString(m.viewPrompt())
+	}
 
 	// Events table
 	doc.WriteString("Recent Scan Events:\n")
-	doc.WriteString(m.table.View())
+	doc.WriteString(m.renderTable())
 	doc.WriteString("\n\n")
 
 	// Logs viewport
@@ -175,57 +544,123 @@ func (m Model) View() string {
 	m.viewport.SetContent(m.renderLogs())
 	doc.WriteString(m.viewport.View())
 
-	// Footer
-	footer := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241")).
-		Render("Press 'r' to refresh • Press 'q' to quit")
-
-	doc.WriteString("\n")
-	doc.WriteString(footer)
-
 	return doc.String()
 }
 
-// refresh updates the model with latest data from the scanner
-func (m *Model) refresh() {
-	if m.scanner == nil {
+// refresh updates the model with latest data from the source. A remote
+// source's events arrive as a message, and the views are rebuilt then.
+func (m *Model) refresh() tea.Cmd {
+	if m.source == nil {
 		if m.debug {
-			log.Printf("[DEBUG] UI refresh called but scanner is nil")
+			log.Printf("[DEBUG] UI refresh called but source is nil")
 		}
-		return
+		return nil
+	}
+
+	if cmd := m.load(m.loadEvents()); cmd != nil {
+		return cmd
+	}
+	return m.rebuild()
+}
+
+// rebuild updates the statistics and lists from the source and rebuilds the
+// views from the events. A remote source's statistics and lists arrive as
+// messages, until then the views show the last ones received.
+func (m *Model) rebuild() tea.Cmd {
+	var cmds []tea.Cmd
+	if m.source != nil {
+		cmds = append(cmds, m.loadStats())
 	}
+	cmd := m.load(append(cmds, m.loadLists())...)
 
-	// Get latest events and stats
-	m.events = m.scanner.GetEvents()
-	m.stats = m.scanner.GetStats()
 	m.lastUpdate = time.Now()
+	m.dirty = false
+	m.updateHistory()
+	m.fillHostnames()
+
+	m.updateRows()
+	m.updateSourceRows()
+	m.updateIncidentRows()
+	m.updateListRows()
+	m.portsView.SetContent(m.renderPorts())
+	if m.detail != nil {
+		m.detailView.SetContent(m.renderDetail(*m.detail))
+	}
+	return cmd
+}
 
-	if m.debug {
-		log.Printf("[DEBUG] UI refresh - Retrieved %d events, %d total scans", len(m.events), m.stats.TotalScans)
+// fillHostnames fills in the hostnames resolved since events were pushed
+func (m *Model) fillHostnames() {
+	resolver, ok := m.source.(HostnameSource)
+	if !ok {
+		return
+	}
+	for i := range m.events {
+		if m.events[i].Hostname == "" {
+			m.events[i].Hostname, _ = resolver.Hostname(m.events[i].SourceIP)
+		}
+	}
+	if m.detail != nil && m.detail.Hostname == "" {
+		m.detail.Hostname, _ = resolver.Hostname(m.detail.SourceIP)
+	}
+}
+
+// openDetail opens the detail view of event
+func (m *Model) openDetail(event models.ScanEvent) {
+	m.detail = &event
+	m.status = ""
+	m.detailView.SetContent(m.renderDetail(event))
+	m.detailView.GotoTop()
+}
+
+// updateRows shows the events selected by the filter in the table, in the
+// sort order. The selected event stays selected unless the first one was.
+func (m *Model) updateRows() {
+	var selected string
+	if cursor := m.table.Cursor(); cursor > 0 && cursor < len(m.rows) {
+		selected = m.rows[cursor].ID
 	}
 
-	// Update table rows
-	rows := make([]table.Row, 0, len(m.events))
+	m.filtered = m.filter.apply(m.events)
 
-	// Show only the most recent events (last 50)
-	start := 0
-	if len(m.events) > 50 {
-		start = len(m.events) - 50
+	var incidents map[string]string
+	for _, name := range m.columns {
+		if name == "incident" {
+			incidents = make(map[string]string)
+			for _, incident := range models.GroupIncidents(m.events, m.incidentGap) {
+				for _, id := range incident.EventIDs {
+					incidents[id] = incident.ID
+				}
+			}
+		}
 	}
 
-	for i := start; i < len(m.events); i++ {
-		event := m.events[i]
-		rows = append(rows, table.Row{
-			event.Timestamp.Format("2006-01-02 15:04:05"),
-			event.SourceIP,
-			fmt.Sprintf("%d", event.TargetPort),
-			event.ScanType,
-			event.Severity.String(),
-			event.Description,
-		})
+	sorted := make([]tableRow, 0, len(m.filtered))
+	for i := len(m.filtered) - 1; i >= 0; i-- {
+		event := m.filtered[i]
+		sorted = append(sorted, tableRow{event: event, incident: incidents[event.ID]})
+	}
+	m.sortRows(sorted)
+
+	rows := make([]table.Row, 0, len(sorted))
+	m.rows = make([]models.ScanEvent, 0, len(sorted))
+	cursor := 0
+
+	for _, r := range sorted {
+		if r.event.ID == selected && selected != "" {
+			cursor = len(m.rows)
+		}
+		m.rows = append(m.rows, r.event)
+		row := make(table.Row, len(m.columns))
+		for i, name := range m.columns {
+			row[i] = columns[name].value(r)
+		}
+		rows = append(rows, row)
 	}
 
 	m.table.SetRows(rows)
+	m.table.SetCursor(cursor)
+	m.scrollTable()
 	if m.debug {
 		log.Printf("[DEBUG] UI refresh - Set %d rows in table", len(rows))
 	}
@@ -233,16 +668,22 @@ func (m *Model) refresh() {
 
 // renderStats renders the statistics section
 func (m Model) renderStats() string {
-	style := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("86"))
-
 	stats := []string{
 		fmt.Sprintf("Total Scans: %d", m.stats.TotalScans),
 		fmt.Sprintf("Unique IPs: %d", m.stats.UniqueIPs),
 		fmt.Sprintf("Last Updated: %s", m.lastUpdate.Format("15:04:05")),
 	}
 
-	return style.Render(strings.Join(stats, " | "))
+	out := m.styles.text.Render(strings.Join(stats, " | "))
+	if m.paused {
+		out += "  " + m.styles.title.Render(fmt.Sprintf("PAUSED, %d unseen", m.unseen))
+	}
+	if source, ok := m.source.(RemoteSource); ok {
+		if err := source.Err(); err != nil {
+			out += "  " + m.styles.severity[models.SeverityCritical].Render(fmt.Sprintf("DISCONNECTED: %v", err))
+		}
+	}
+	return out
 }
 
 // renderLogs renders the activity log
@@ -250,26 +691,32 @@ func (m Model) renderLogs() string {
 	var logs []string
 
 	// Add some recent activity
-	if len(m.events) > 0 {
+	if len(m.filtered) > 0 {
 		logs = append(logs, "Recent Activity:")
 		logs = append(logs, "")
 
 		// Show last 10 events in log format
 		start := 0
-		if len(m.events) > 10 {
-			start = len(m.events) - 10
+		if len(m.filtered) > 10 {
+			start = len(m.filtered) - 10
 		}
 
-		for i := start; i < len(m.events); i++ {
-			event := m.events[i]
+		for i := start; i < len(m.filtered); i++ {
+			event := m.filtered[i]
+			source := event.SourceIP
+			if event.Hostname != "" {
+				source += " (" + event.Hostname + ")"
+			}
 			logLine := fmt.Sprintf("[%s] %s scan from %s to port %d - %s",
 				event.Timestamp.Format("15:04:05"),
 				event.ScanType,
-				event.SourceIP,
+				source,
 				event.TargetPort,
 				event.Severity.String())
 			logs = append(logs, logLine)
 		}
+	} else if len(m.events) > 0 {
+		logs = append(logs, "No scan events match the filter.")
 	} else {
 		logs = append(logs, "No scan events detected yet.")
 		logs = append(logs, "")
@@ -283,9 +730,9 @@ func (m Model) renderLogs() string {
 // tickMsg is sent every second to trigger UI updates
 type tickMsg time.Time
 
-// tickCmd returns a command that sends a tick message every 2 seconds
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
+// tickCmd returns a command that sends a tick message after interval
+func tickCmd(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// heatCellWidth is the width of a port heatmap cell including its margin
+const heatCellWidth = 15
+
+// heatLevel maps a count onto one of levels heat colors on a logarithmic
+// scale, so a few busy ports do not flatten the rest of the map
+func heatLevel(count, max, levels int) int {
+	if count <= 0 || max <= 1 {
+		return 0
+	}
+	level := math.Log(float64(count)) / math.Log(float64(max)) * float64(levels-1)
+	return int(math.Round(level))
+}
+
+// renderPorts renders the scanned ports from the statistics as a heatmap,
+// ordered by port or by count
+func (m Model) renderPorts() string {
+	type portCount struct{ port, count int }
+	ports := make([]portCount, 0, len(m.stats.ScansByPort))
+	max, total := 0, 0
+	for port, count := range m.stats.ScansByPort {
+		ports = append(ports, portCount{port, count})
+		total += count
+		if count > max {
+			max = count
+		}
+	}
+	if len(ports) == 0 {
+		return "No ports scanned yet."
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if m.portsByCount && ports[i].count != ports[j].count {
+			return ports[i].count > ports[j].count
+		}
+		return ports[i].port < ports[j].port
+	})
+
+	order := "port"
+	if m.portsByCount {
+		order = "count"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d ports, %d events, ordered by %s\n\n", len(ports), total, order)
+
+	perLine := m.portsView.Width / heatCellWidth
+	if perLine < 1 {
+		perLine = 1
+	}
+	cells := make([]string, 0, perLine)
+	for i, p := range ports {
+		cells = append(cells, m.styles.portCell(p.port, p.count, heatLevel(p.count, max, len(m.styles.heat))))
+
+		if len(cells) == perLine || i == len(ports)-1 {
+			b.WriteString(strings.Join(cells, " ") + "\n")
+			cells = cells[:0]
+		}
+	}
+
+	// Legend
+	b.WriteString("\nfewer ")
+	for level := range m.styles.heat {
+		b.WriteString(m.styles.heatCell(level) + m.styles.heatCell(level))
+	}
+	b.WriteString(" more events\n")
+
+	return b.String()
+}
+
+// portCell draws a cell of the port heatmap with the port and its count on
+// the heat color of level. Without colors the level is shown by a shade.
+func (s styles) portCell(port, count, level int) string {
+	text := fmt.Sprintf("%5d %7d", port, count)
+	if s.monochrome {
+		return string(shades[level]) + text
+	}
+
+	fg := lipgloss.Color("255")
+	if level >= len(s.heat)/2 {
+		fg = lipgloss.Color("16")
+	}
+	return lipgloss.NewStyle().
+		Background(s.heat[level]).
+		Foreground(fg).
+		Width(heatCellWidth - 1).
+		Render(text)
+}
+
+// updatePorts handles keys in the Ports tab
+func (m Model) updatePorts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Order) {
+		m.portsByCount = !m.portsByCount
+		m.portsView.SetContent(m.renderPorts())
+		m.portsView.GotoTop()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.portsView, cmd = m.portsView.Update(msg)
+	return m, cmd
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sourceRow is a scanning source in the Sources tab
+type sourceRow struct {
+	ip       string
+	events   int
+	ports    int
+	lastSeen time.Time
+	country  string
+	hostname string
+	latest   models.ScanEvent // Most recent event from the source
+}
+
+// sourceColumns are the columns of the Sources tab with how they sort
+var sourceColumns = []struct {
+	title string
+	width int
+	less  func(a, b sourceRow) bool
+}{
+	{"Source IP", 15, func(a, b sourceRow) bool { return compareIPs(a.ip, b.ip) < 0 }},
+	{"Events", 8, func(a, b sourceRow) bool { return a.events < b.events }},
+	{"Ports", 6, func(a, b sourceRow) bool { return a.ports < b.ports }},
+	{"Last Seen", 19, func(a, b sourceRow) bool { return a.lastSeen.Before(b.lastSeen) }},
+	{"Country", 7, func(a, b sourceRow) bool { return a.country < b.country }},
+	{"Hostname", 20, func(a, b sourceRow) bool { return a.hostname < b.hostname }},
+}
+
+// sourceTableColumns returns the Sources tab columns, marking the sort column
+func (m Model) sourceTableColumns() []table.Column {
+	result := make([]table.Column, len(sourceColumns))
+	for i, c := range sourceColumns {
+		title := c.title
+		if i == m.sourceSort {
+			if m.sourceDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		result[i] = table.Column{Title: title, Width: c.width}
+	}
+	return fillLast(result, m.tableWidth())
+}
+
+// updateSourceRows ranks the sources. Event counts come from the
+// statistics, which include stored history; distinct ports, last seen and
+// enrichment from the events.
+func (m *Model) updateSourceRows() {
+	bySource := make(map[string]*sourceRow)
+	ports := make(map[string]map[int]bool)
+	for ip, count := range m.stats.ScansByIP {
+		bySource[ip] = &sourceRow{ip: ip, events: count}
+		ports[ip] = make(map[int]bool)
+	}
+	for _, event := range m.events {
+		row, ok := bySource[event.SourceIP]
+		if !ok {
+			row = &sourceRow{ip: event.SourceIP}
+			bySource[event.SourceIP] = row
+			ports[event.SourceIP] = make(map[int]bool)
+		}
+		// Count the events when the statistics have no counts per source
+		if len(m.stats.ScansByIP) == 0 {
+			row.events++
+		}
+		ports[event.SourceIP][event.TargetPort] = true
+		if !event.Timestamp.Before(row.lastSeen) {
+			row.lastSeen = event.Timestamp
+			row.latest = event
+			row.country = event.Country
+			row.hostname = event.Hostname
+		}
+	}
+
+	sources := make([]sourceRow, 0, len(bySource))
+	for ip, row := range bySource {
+		row.ports = len(ports[ip])
+		sources = append(sources, *row)
+	}
+
+	// Sources that rank equal stay in address order
+	sort.Slice(sources, func(i, j int) bool { return compareIPs(sources[i].ip, sources[j].ip) < 0 })
+	less := sourceColumns[m.sourceSort].less
+	sort.SliceStable(sources, func(i, j int) bool {
+		if m.sourceDesc {
+			return less(sources[j], sources[i])
+		}
+		return less(sources[i], sources[j])
+	})
+
+	rows := make([]table.Row, len(sources))
+	for i, s := range sources {
+		lastSeen := ""
+		if !s.lastSeen.IsZero() {
+			lastSeen = s.lastSeen.Format("2006-01-02 15:04:05")
+		}
+		rows[i] = table.Row{s.ip, strconv.Itoa(s.events), strconv.Itoa(s.ports), lastSeen, s.country, s.hostname}
+	}
+	m.sources = sources
+	m.sourcesTable.SetRows(rows)
+}
+
+// updateSources handles keys in the Sources tab
+func (m Model) updateSources(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Details):
+		if cursor := m.sourcesTable.Cursor(); cursor >= 0 && cursor < len(m.sources) {
+			if latest := m.sources[cursor].latest; latest.SourceIP != "" {
+				m.openDetail(latest)
+			}
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Sort):
+		m.sortSourcesBy(int(msg.String()[0] - '1'))
+		return m, nil
+	case key.Matches(msg, m.keys.SortColumn):
+		m.sortSourcesBy((m.sourceSort + 1) % len(sourceColumns))
+		return m, nil
+	case key.Matches(msg, m.keys.SortReverse):
+		m.sourceDesc = !m.sourceDesc
+		m.sourcesTable.SetColumns(m.sourceTableColumns())
+		m.updateSourceRows()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.sourcesTable, cmd = m.sourcesTable.Update(msg)
+	return m, cmd
+}
+
+// sortSourcesBy sorts the sources on the column at index, reversing the
+// order when they are already sorted on it
+func (m *Model) sortSourcesBy(index int) {
+	if index < 0 || index >= len(sourceColumns) {
+		return
+	}
+	if index == m.sourceSort {
+		m.sourceDesc = !m.sourceDesc
+	} else {
+		m.sourceSort = index
+		// Rank counts and times from the highest
+		m.sourceDesc = index == 1 || index == 2 || index == 3
+	}
+	m.sourcesTable.SetColumns(m.sourceTableColumns())
+	m.updateSourceRows()
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tab is a view of the TUI selected with tab and shift+tab
+type tab int
+
+const (
+	tabEvents tab = iota
+	tabSources
+	tabPorts
+	tabIncidents
+	tabLists
+	tabCount
+)
+
+// tabTitles are the titles of the tabs in the tab bar
+var tabTitles = [tabCount]string{"Events", "Sources", "Ports", "Incidents", "Blocklist"}
+
+// renderTabs renders the tab bar, highlighting the current tab
+func (m Model) renderTabs() string {
+	tabs := make([]string, tabCount)
+	for i, title := range tabTitles {
+		if tab(i) == m.tab {
+			tabs[i] = m.styles.activeTab.Render(title)
+		} else {
+			tabs[i] = m.styles.inactiveTab.Render(title)
+		}
+	}
+	return strings.Join(tabs, " ")
+}
+
+// renderHelp renders the short key help of the current view
+func (m Model) renderHelp() string {
+	return m.help.ShortHelpView(m.helpKeys().ShortHelp())
+}
+
+// viewHelp renders the full key help of the current view in a box in the
+// middle of height lines
+func (m Model) viewHelp(height int) string {
+	box := m.styles.frame.Copy().Padding(1, 2).Render(
+		m.styles.section.Render("Keys") + "\n\n" +
+			m.help.FullHelpView(m.helpKeys().FullHelp()) + "\n\n" +
+			m.styles.muted.Render("Press "+m.keys.Help.Help().Key+" to close"))
+	return lipgloss.Place(m.width, height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// newTable creates a focused table with the styles of the TUI
+func newTable(columns []table.Column, styles table.Styles) table.Model {
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+	t.SetStyles(styles)
+	return t
+}
+
+// fillLast widens the last column so the columns fill width
+func fillLast(columns []table.Column, width int) []table.Column {
+	used := 0
+	for _, c := range columns {
+		used += c.Width + 2
+	}
+	if last := len(columns) - 1; last >= 0 && used < width {
+		columns[last].Width += width - used
+	}
+	return columns
+}
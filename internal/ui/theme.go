@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DefaultTheme is the name of the theme used unless configured otherwise
+const DefaultTheme = "dark"
+
+// Theme is the set of colors of the TUI
+type Theme struct {
+	Title      lipgloss.Color // Title and section headings
+	Text       lipgloss.Color // Statistics, labels and charts
+	Muted      lipgloss.Color // Key help and inactive tabs
+	Border     lipgloss.Color // Table header and empty chart cells
+	Frame      lipgloss.Color // Border of the activity log
+	SelectedFg lipgloss.Color // Selected row and active tab
+	SelectedBg lipgloss.Color
+	Severity   [models.SeverityCritical + 1]lipgloss.Color // Rows and charts by severity
+	Heat       []lipgloss.Color                            // Heatmap cells, coolest first
+
+	monochrome bool // Highlight by reversing instead of coloring
+}
+
+// themes are the built-in themes by name
+var themes = map[string]Theme{
+	"dark": {
+		Title:      "205",
+		Text:       "86",
+		Muted:      "241",
+		Border:     "240",
+		Frame:      "62",
+		SelectedFg: "229",
+		SelectedBg: "57",
+		Severity:   [...]lipgloss.Color{"250", "220", "208", "196"},
+		Heat:       []lipgloss.Color{"22", "28", "64", "100", "136", "172", "166", "160", "196"},
+	},
+	"light": {
+		Title:      "162",
+		Text:       "30",
+		Muted:      "244",
+		Border:     "250",
+		Frame:      "63",
+		SelectedFg: "231",
+		SelectedBg: "63",
+		Severity:   [...]lipgloss.Color{"238", "130", "166", "160"},
+		Heat:       []lipgloss.Color{"194", "157", "150", "186", "222", "215", "209", "203", "160"},
+	},
+	"high-contrast": {
+		Title:      "15",
+		Text:       "15",
+		Muted:      "15",
+		Border:     "15",
+		Frame:      "15",
+		SelectedFg: "0",
+		SelectedBg: "11",
+		Severity:   [...]lipgloss.Color{"15", "11", "13", "9"},
+		Heat:       []lipgloss.Color{"4", "12", "6", "14", "2", "10", "3", "11", "9"},
+	},
+}
+
+// noColorTheme is used when NO_COLOR is set, see https://no-color.org
+var noColorTheme = Theme{Heat: make([]lipgloss.Color, 5), monochrome: true}
+
+// shades stand in for the heat colors without colors, lightest first
+var shades = []rune("░▒▓██")
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTheme returns the built-in theme called name
+func LookupTheme(name string) (Theme, error) {
+	theme, ok := themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q, must be one of %s", name, strings.Join(ThemeNames(), ", "))
+	}
+	return theme, nil
+}
+
+// styles are the styles of the TUI derived from a theme
+type styles struct {
+	title       lipgloss.Style
+	text        lipgloss.Style
+	section     lipgloss.Style
+	muted       lipgloss.Style
+	empty       lipgloss.Style
+	frame       lipgloss.Style
+	activeTab   lipgloss.Style
+	inactiveTab lipgloss.Style
+	table       table.Styles
+	help        help.Styles
+	severity    [models.SeverityCritical + 1]lipgloss.Style
+	heat        []lipgloss.Color
+	monochrome  bool
+}
+
+// newStyles derives the styles from theme, dropping the colors when the
+// NO_COLOR environment variable is set
+func newStyles(theme Theme) styles {
+	if os.Getenv("NO_COLOR") != "" {
+		theme = noColorTheme
+	}
+	fg := func(color lipgloss.Color) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(color)
+	}
+
+	s := styles{
+		title:      fg(theme.Title).Bold(true),
+		text:       fg(theme.Text),
+		section:    fg(theme.Title).Bold(true),
+		muted:      fg(theme.Muted),
+		empty:      fg(theme.Border),
+		monochrome: theme.monochrome,
+	}
+	s.frame = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Frame).
+		PaddingRight(2)
+
+	selected := lipgloss.NewStyle().Foreground(theme.SelectedFg).Background(theme.SelectedBg)
+	if theme.monochrome {
+		selected = lipgloss.NewStyle().Reverse(true)
+	}
+	s.activeTab = selected.Copy().Bold(true).Padding(0, 1)
+	s.inactiveTab = fg(theme.Muted).Padding(0, 1)
+
+	s.table = table.DefaultStyles()
+	s.table.Header = s.table.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(theme.Border).
+		BorderBottom(true).
+		Bold(false)
+	s.table.Selected = selected.Copy().Bold(false)
+
+	s.help = help.New().Styles
+	s.help.ShortKey = fg(theme.Text)
+	s.help.ShortDesc = fg(theme.Muted)
+	s.help.ShortSeparator = fg(theme.Border)
+	s.help.FullKey = fg(theme.Text)
+	s.help.FullDesc = fg(theme.Muted)
+	s.help.FullSeparator = fg(theme.Border)
+	s.help.Ellipsis = fg(theme.Border)
+
+	for severity, color := range theme.Severity {
+		s.severity[severity] = fg(color)
+	}
+	s.heat = theme.Heat
+	return s
+}
+
+// heatCell draws a heatmap cell at level out of the heat colors
+func (s styles) heatCell(level int) string {
+	if s.monochrome {
+		return string(shades[level])
+	}
+	return lipgloss.NewStyle().Foreground(s.heat[level]).Render("█")
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultRefreshRate is the minimum time between rebuilding the views
+	defaultRefreshRate = time.Second * 2
+
+	// pushTickInterval is how often the views are rebuilt without new events
+	// when events are pushed, to move the charts and refresh the statistics
+	pushTickInterval = time.Minute
+
+	// maxEventBatch is the most events delivered in one message
+	maxEventBatch = 256
+
+	// eventLimit is the number of events kept by the TUI
+	eventLimit = 10000
+)
+
+// eventsMsg carries the events received from the subscription since the
+// last message. Closed is set when the subscription has ended.
+type eventsMsg struct {
+	events []models.ScanEvent
+	closed bool
+}
+
+// renderMsg rebuilds the views after events arrived faster than the
+// refresh rate
+type renderMsg struct{}
+
+// loadMsg loads the events and statistics from the source at startup
+type loadMsg struct{}
+
+// WithEvents makes the TUI receive events from ch as they happen instead of
+// polling the source for them. The source still provides the initial
+// events and the statistics.
+func (m Model) WithEvents(ch <-chan models.ScanEvent) Model {
+	m.subscription = ch
+	return m
+}
+
+// WithRefreshRate sets the minimum time between rebuilding the views, or the
+// polling interval when no event channel is set
+func (m Model) WithRefreshRate(rate time.Duration) Model {
+	if rate > 0 {
+		m.refreshRate = rate
+	}
+	return m
+}
+
+// waitForEvents waits for an event on ch and returns it with any events
+// already waiting behind it, so a burst is handled as one message
+func waitForEvents(ch <-chan models.ScanEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-ch
+		if !ok {
+			return eventsMsg{closed: true}
+		}
+
+		events := []models.ScanEvent{event}
+		for len(events) < maxEventBatch {
+			select {
+			case event, ok := <-ch:
+				if !ok {
+					return eventsMsg{events: events, closed: true}
+				}
+				events = append(events, event)
+			default:
+				return eventsMsg{events: events}
+			}
+		}
+		return eventsMsg{events: events}
+	}
+}
+
+// receiveEvents adds pushed events and rebuilds the views, at most once per
+// refresh rate
+func (m Model) receiveEvents(msg eventsMsg) (tea.Model, tea.Cmd) {
+	for _, event := range msg.events {
+		// The last load may already have included the event
+		if m.loaded[event.ID] {
+			continue
+		}
+		// A paused view keeps the events until it is resumed
+		if m.paused {
+			m.pending = append(m.pending, event)
+			m.unseen++
+			continue
+		}
+		m.events = append(m.events, event)
+		m.dirty = true
+	}
+	if len(m.events) > eventLimit {
+		m.events = append(m.events[:0:0], m.events[len(m.events)-eventLimit:]...)
+	}
+	if len(m.pending) > eventLimit {
+		m.pending = append(m.pending[:0:0], m.pending[len(m.pending)-eventLimit:]...)
+	}
+
+	var cmds []tea.Cmd
+	if !msg.closed {
+		cmds = append(cmds, waitForEvents(m.subscription))
+	}
+	if m.dirty && !m.renderPending {
+		if wait := m.refreshRate - time.Since(m.lastUpdate); wait > 0 {
+			m.renderPending = true
+			cmds = append(cmds, tea.Tick(wait, func(time.Time) tea.Msg { return renderMsg{} }))
+		} else {
+			cmds = append(cmds, m.rebuild())
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// togglePause freezes the views, or resumes them with the events that
+// arrived while they were frozen
+func (m *Model) togglePause() tea.Cmd {
+	m.unseen = 0
+	if !m.paused {
+		m.paused = true
+		return nil
+	}
+
+	m.paused = false
+	if m.subscription == nil {
+		return m.refresh()
+	}
+	m.events = append(m.events, m.pending...)
+	m.pending = nil
+	if len(m.events) > eventLimit {
+		m.events = append(m.events[:0:0], m.events[len(m.events)-eventLimit:]...)
+	}
+	return m.rebuild()
+}
+
+// countUnseen counts the events of the source that a paused view doesn't
+// show. Pushed events are counted as they arrive.
+func (m *Model) countUnseen() {
+	if m.subscription != nil || m.source == nil {
+		return
+	}
+	shown := make(map[string]bool, len(m.events))
+	for _, event := range m.events {
+		shown[event.ID] = true
+	}
+	m.unseen = 0
+	for _, event := range m.source.GetEvents() {
+		if !shown[event.ID] {
+			m.unseen++
+		}
+	}
+}
+
+// tickInterval returns the time between ticks
+func (m Model) tickInterval() time.Duration {
+	if m.subscription != nil {
+		return pushTickInterval
+	}
+	return m.refreshRate
+}
@@ -108,3 +108,45 @@ func RemoveString(slice []string, item string) []string {
 	}
 	return result
 }
+
+// JoinPorts formats ports as a comma separated list, e.g. "22, 80, 443"
+func JoinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseDuration parses a duration like time.ParseDuration, additionally
+// accepting a whole number of days with a "d" suffix, e.g. "7d"
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// ParseSince parses either an RFC 3339 timestamp or a duration relative to
+// now, as accepted by ParseDuration, and returns the resulting point in time
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	d, err := ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 timestamp or duration", s)
+	}
+	return now.Add(-d), nil
+}
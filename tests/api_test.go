@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/api"
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// fakeSource is a static event source for API tests
+type fakeSource struct {
+	events []models.ScanEvent
+}
+
+func (f *fakeSource) GetEvents() []models.ScanEvent {
+	return f.events
+}
+
+func (f *fakeSource) GetStats() models.ScanStats {
+	return models.ScanStats{TotalScans: len(f.events), UniqueIPs: 2}
+}
+
+// annotatingSource is a fakeSource that applies the annotations it is given
+type annotatingSource struct {
+	fakeSource
+	annotations []models.Annotation
+}
+
+func (a *annotatingSource) Annotate(annotation models.Annotation) error {
+	a.annotations = append(a.annotations, annotation)
+	annotation.ApplyTo(a.events)
+	return nil
+}
+
+// testEvents returns a fixed set of events spread over an hour
+func testEvents() []models.ScanEvent {
+	base := time.Now().Add(-time.Hour)
+	event := func(id, ip string, port int, severity models.Severity, offset time.Duration) models.ScanEvent {
+		return models.ScanEvent{
+			ID:         id,
+			SourceIP:   ip,
+			TargetPort: port,
+			Timestamp:  base.Add(offset),
+			Protocol:   "tcp",
+			ScanType:   "port_scan",
+			Severity:   severity,
+		}
+	}
+
+	return []models.ScanEvent{
+		event("1", "203.0.113.5", 22, models.SeverityLow, 0),
+		event("2", "203.0.113.5", 23, models.SeverityHigh, time.Minute),
+		event("3", "198.51.100.7", 22, models.SeverityMedium, time.Minute*50),
+		event("4", "203.0.113.9", 3389, models.SeverityCritical, time.Minute*55),
+	}
+}
+
+func doRequest(handler http.Handler, method, target, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIEventsFilters(t *testing.T) {
+	server := api.NewServer(&fakeSource{events: testEvents()}, api.Options{})
+
+	tests := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{"", []string{"4", "3", "2", "1"}},
+		{"?ip=203.0.113.0/24", []string{"4", "2", "1"}},
+		{"?port=22", []string{"3", "1"}},
+		{"?severity=high", []string{"4", "2"}},
+		{"?since=30m", []string{"4", "3"}},
+		{"?limit=2&offset=1", []string{"3", "2"}},
+	}
+
+	for _, test := range tests {
+		rec := doRequest(server, http.MethodGet, "/events"+test.query, "", "")
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET /events%s: expected status 200, got %d", test.query, rec.Code)
+			continue
+		}
+
+		var response struct {
+			Events []models.ScanEvent `json:"events"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		ids := make([]string, 0, len(response.Events))
+		for _, event := range response.Events {
+			ids = append(ids, event.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(test.expectedIDs, ",") {
+			t.Errorf("GET /events%s: expected %v, got %v", test.query, test.expectedIDs, ids)
+		}
+	}
+
+	if rec := doRequest(server, http.MethodGet, "/events?severity=bogus", "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid severity, got %d", rec.Code)
+	}
+}
+
+func TestAPIIncidents(t *testing.T) {
+	server := api.NewServer(&fakeSource{events: testEvents()}, api.Options{IncidentGap: time.Minute * 5})
+
+	rec := doRequest(server, http.MethodGet, "/incidents", "", "")
+	var incidents []models.Incident
+	if err := json.NewDecoder(rec.Body).Decode(&incidents); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(incidents) != 3 {
+		t.Fatalf("Expected 3 incidents, got %d", len(incidents))
+	}
+	if incidents[0].EventCount != 2 || incidents[0].Severity != models.SeverityHigh {
+		t.Errorf("Expected first incident with 2 events and HIGH severity, got %+v", incidents[0])
+	}
+}
+
+func TestAPIAnnotations(t *testing.T) {
+	if rec := doRequest(api.NewServer(&fakeSource{}, api.Options{}), http.MethodPost, "/annotations", `{}`, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without annotator, got %d", rec.Code)
+	}
+
+	source := &annotatingSource{fakeSource: fakeSource{events: testEvents()}}
+	server := api.NewServer(source, api.Options{IncidentGap: time.Minute * 5})
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"event_ids":["1","2"],"acknowledged":true}`, http.StatusOK},
+		{`{"event_ids":["2"],"note":"known scanner"}`, http.StatusOK},
+		{`{"event_ids":["1"]}`, http.StatusBadRequest},
+		{`{"acknowledged":true}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if rec := doRequest(server, http.MethodPost, "/annotations", test.body, ""); rec.Code != test.status {
+			t.Errorf("Expected status %d for %s, got %d", test.status, test.body, rec.Code)
+		}
+	}
+	if len(source.annotations) != 2 || source.annotations[0].Time.IsZero() {
+		t.Errorf("Expected 2 timestamped annotations, got %+v", source.annotations)
+	}
+
+	rec := doRequest(server, http.MethodGet, "/events?ip=203.0.113.5", "", "")
+	var body struct{ Events []models.ScanEvent }
+	json.NewDecoder(rec.Body).Decode(&body)
+	if len(body.Events) != 2 || !body.Events[0].Acknowledged || body.Events[0].Note != "known scanner" {
+		t.Errorf("Expected the annotations on the events, got %+v", body.Events)
+	}
+
+	rec = doRequest(server, http.MethodGet, "/incidents", "", "")
+	var incidents []models.Incident
+	json.NewDecoder(rec.Body).Decode(&incidents)
+	if len(incidents) != 3 || incidents[0].Acknowledged != 2 || len(incidents[0].Notes) != 1 {
+		t.Errorf("Expected the first incident acknowledged with a note, got %+v", incidents)
+	}
+}
+
+func TestAPIListManagement(t *testing.T) {
+	blacklist := iplist.New()
+	server := api.NewServer(&fakeSource{}, api.Options{Blacklist: blacklist})
+
+	if rec := doRequest(server, http.MethodPost, "/lists/deny", `{"entry":"203.0.113.0/24"}`, ""); rec.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", rec.Code)
+	}
+	if !blacklist.Contains("203.0.113.5") {
+		t.Error("Expected entry to be added to the blacklist")
+	}
+	if rec := doRequest(server, http.MethodPost, "/lists/deny", `{"entry":"bogus"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodDelete, "/lists/deny?entry=203.0.113.0/24", "", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/lists/allow", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a disabled list, got %d", rec.Code)
+	}
+}
+
+func TestAPIBearerToken(t *testing.T) {
+	server := api.NewServer(&fakeSource{}, api.Options{Token: "secret"})
+
+	if rec := doRequest(server, http.MethodGet, "/stats", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without token, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/stats", "", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 with wrong token, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/stats", "", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with token, got %d", rec.Code)
+	}
+}
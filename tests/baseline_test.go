@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/baseline"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// steadyTraffic feeds the detector two events a minute from rotating sources
+// on port 22 for the given number of minutes, returning the anomalies and
+// the time after the last minute
+func steadyTraffic(d *baseline.Detector, start time.Time, minutes int) ([]models.ScanEvent, time.Time) {
+	var anomalies []models.ScanEvent
+	for i := 0; i < minutes; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		for j := 0; j < 2; j++ {
+			event := models.ScanEvent{SourceIP: fmt.Sprintf("203.0.113.%d", (i+j)%5), TargetPort: 22, Timestamp: at.Add(time.Duration(j) * time.Second)}
+			anomalies = append(anomalies, d.Observe(event)...)
+		}
+	}
+	return anomalies, start.Add(time.Duration(minutes) * time.Minute)
+}
+
+func TestEWMA(t *testing.T) {
+	var e baseline.EWMA
+	for i := 0; i < 100; i++ {
+		e.Update(4, 0.1)
+	}
+	if math.Abs(e.Mean-4) > 1e-9 || e.Variance > 1e-9 {
+		t.Errorf("Expected mean 4 and variance 0, got %v and %v", e.Mean, e.Variance)
+	}
+	// A flat history falls back to the Poisson deviation
+	if score := e.Score(10); math.Abs(score-3) > 1e-9 {
+		t.Errorf("Expected score 3, got %v", score)
+	}
+	if score := e.Score(2); score >= 0 {
+		t.Errorf("Expected a negative score, got %v", score)
+	}
+}
+
+func TestBaselineAnomalies(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	d := baseline.New(baseline.Options{Learning: time.Hour, MinSamples: 2})
+
+	anomalies, now := steadyTraffic(d, start, 120)
+	if len(anomalies) != 0 {
+		t.Fatalf("Expected no anomalies from steady traffic, got %v", anomalies)
+	}
+	if d.Learning(now) {
+		t.Fatal("Expected the learning period to be over")
+	}
+
+	// A burst from one source on a new port
+	for i := 0; i < 40; i++ {
+		event := models.ScanEvent{SourceIP: "198.51.100.7", TargetPort: 3389, Timestamp: now.Add(time.Duration(i) * time.Second)}
+		anomalies = append(anomalies, d.Observe(event)...)
+	}
+
+	if len(anomalies) != 2 {
+		t.Fatalf("Expected 2 anomalies, got %d: %v", len(anomalies), anomalies)
+	}
+	expected := []string{"Events per hour on port 3389", "Events per minute"}
+	for i, anomaly := range anomalies {
+		if anomaly.ScanType != baseline.ScanType {
+			t.Errorf("Expected %s, got %s", baseline.ScanType, anomaly.ScanType)
+		}
+		if anomaly.SourceIP != "198.51.100.7" || anomaly.TargetPort != 3389 {
+			t.Errorf("Expected the triggering source and port, got %s and %d", anomaly.SourceIP, anomaly.TargetPort)
+		}
+		if len(anomaly.Description) < len(expected[i]) || anomaly.Description[:len(expected[i])] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], anomaly.Description)
+		}
+		if anomaly.AnomalyScore < baseline.DefaultThreshold {
+			t.Errorf("Expected a score of at least %v, got %v", baseline.DefaultThreshold, anomaly.AnomalyScore)
+		}
+		if anomaly.Severity != baseline.Severity(anomaly.AnomalyScore, baseline.DefaultThreshold) {
+			t.Errorf("Expected severity from the score, got %s", anomaly.Severity)
+		}
+	}
+}
+
+func TestBaselineLearning(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	d := baseline.New(baseline.Options{Learning: time.Hour * 24})
+
+	_, now := steadyTraffic(d, start, 120)
+	var anomalies []models.ScanEvent
+	for i := 0; i < 40; i++ {
+		event := models.ScanEvent{SourceIP: "198.51.100.7", TargetPort: 3389, Timestamp: now.Add(time.Duration(i) * time.Second)}
+		anomalies = append(anomalies, d.Observe(event)...)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies while learning, got %d", len(anomalies))
+	}
+	if !d.Learning(now) {
+		t.Error("Expected the detector to be learning")
+	}
+}
+
+func TestBaselinePortHistory(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	d := baseline.New(baseline.Options{Learning: time.Hour})
+
+	_, now := steadyTraffic(d, start, 120)
+	d.Observe(models.ScanEvent{SourceIP: "198.51.100.7", TargetPort: 3389, Timestamp: now})
+	d.Observe(models.ScanEvent{SourceIP: "198.51.100.7", TargetPort: 22, Timestamp: now.Add(time.Hour)})
+
+	// The first hour of a new port is weighed against the empty hours before
+	state := d.State()
+	port := state.Ports[3389]
+	if port.Samples != state.Sources.Samples {
+		t.Errorf("Expected %d samples, got %d", state.Sources.Samples, port.Samples)
+	}
+	if math.Abs(port.Mean-baseline.DefaultAlpha) > 1e-9 {
+		t.Errorf("Expected mean %v, got %v", baseline.DefaultAlpha, port.Mean)
+	}
+}
+
+func TestBaselinePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	opts := baseline.Options{Learning: time.Hour * 3}
+
+	fresh, err := baseline.Load(path, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !fresh.State().Started.IsZero() {
+		t.Error("Expected an empty baseline")
+	}
+
+	d := baseline.New(opts)
+	steadyTraffic(d, start, 90)
+	if err := d.Save(path); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	loaded, err := baseline.Load(path, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	saved, restored := d.State(), loaded.State()
+	if !restored.Started.Equal(start) {
+		t.Errorf("Expected %s, got %s", start, restored.Started)
+	}
+	if restored.Connections != saved.Connections || restored.Sources != saved.Sources {
+		t.Errorf("Expected %+v and %+v, got %+v and %+v", saved.Connections, saved.Sources, restored.Connections, restored.Sources)
+	}
+	if restored.Ports[22] != saved.Ports[22] || restored.HourPorts[22] != saved.HourPorts[22] {
+		t.Errorf("Expected port 22 baseline %+v, got %+v", saved.Ports[22], restored.Ports[22])
+	}
+	// The learning period runs from the first event ever seen
+	if !loaded.Learning(start.Add(time.Hour*2)) || loaded.Learning(start.Add(time.Hour*3)) {
+		t.Error("Expected the learning period to continue after loading")
+	}
+}
+
+func TestBaselineSeverity(t *testing.T) {
+	tests := []struct {
+		score    float64
+		expected models.Severity
+	}{
+		{1, models.SeverityLow},
+		{3, models.SeverityMedium},
+		{6, models.SeverityHigh},
+		{12, models.SeverityCritical},
+	}
+	for _, test := range tests {
+		if severity := baseline.Severity(test.score, 3); severity != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, severity)
+		}
+	}
+}
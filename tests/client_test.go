@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/api"
+	"jonasbn.github.com/portscammer/internal/client"
+	"jonasbn.github.com/portscammer/internal/iplist"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/stream"
+	"jonasbn.github.com/portscammer/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestClient(t *testing.T) {
+	source := &annotatingSource{fakeSource: fakeSource{events: testEvents()}}
+	whitelist := iplist.New()
+	server := httptest.NewServer(api.NewServer(source, api.Options{Token: "secret", Whitelist: whitelist}))
+	defer server.Close()
+
+	remote, err := client.New(client.Options{Addr: server.URL, Token: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := remote.Ping(); err != nil {
+		t.Fatalf("Expected ping to succeed, got %v", err)
+	}
+
+	events := remote.GetEvents()
+	if len(events) != 4 || events[0].ID != "1" || events[3].ID != "4" {
+		t.Errorf("Expected events 1 to 4 oldest first, got %v", events)
+	}
+	if stats := remote.GetStats(); stats.TotalScans != 4 {
+		t.Errorf("Expected 4 scans, got %d", stats.TotalScans)
+	}
+
+	ack := true
+	if err := remote.Annotate(models.Annotation{EventIDs: []string{"2"}, Acknowledged: &ack}); err != nil {
+		t.Fatalf("Failed to annotate: %v", err)
+	}
+	if !source.events[1].Acknowledged {
+		t.Errorf("Expected event 2 to be acknowledged")
+	}
+
+	if err := remote.Whitelist("203.0.113.5"); err != nil {
+		t.Fatalf("Failed to whitelist: %v", err)
+	}
+	if !whitelist.Contains("203.0.113.5") {
+		t.Errorf("Expected 203.0.113.5 to be whitelisted")
+	}
+	if entries := remote.Entries("allow"); len(entries) != 1 {
+		t.Errorf("Expected 1 allow list entry, got %v", entries)
+	}
+	if err := remote.Remove("allow", "203.0.113.5"); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+	if err := remote.Blacklist("203.0.113.9"); err == nil {
+		t.Errorf("Expected an error without a deny list")
+	}
+	if err := remote.Block("203.0.113.9"); err == nil {
+		t.Errorf("Expected blocking to be unavailable")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	whitelist := iplist.New()
+	whitelist.Add("192.0.2.1")
+	server := httptest.NewServer(api.NewServer(&fakeSource{events: testEvents()}, api.Options{Token: "secret", Whitelist: whitelist}))
+
+	remote, _ := client.New(client.Options{Addr: server.URL, Token: "wrong"})
+	if err := remote.Ping(); err == nil || remote.Err() == nil {
+		t.Errorf("Expected an error with the wrong token")
+	}
+
+	// The last data received is kept while the daemon is unreachable
+	remote, _ = client.New(client.Options{Addr: server.URL, Token: "secret"})
+	if events := remote.GetEvents(); len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	if entries := remote.Entries("allow"); len(entries) != 1 {
+		t.Fatalf("Expected 1 allow list entry, got %v", entries)
+	}
+	server.Close()
+	if events := remote.GetEvents(); len(events) != 4 {
+		t.Errorf("Expected the 4 cached events, got %d", len(events))
+	}
+	if entries := remote.Entries("allow"); len(entries) != 1 {
+		t.Errorf("Expected the cached allow list entry, got %v", entries)
+	}
+	if remote.Err() == nil {
+		t.Errorf("Expected an error once the daemon is gone")
+	}
+
+	if _, err := client.New(client.Options{Addr: "ftp://localhost"}); err == nil {
+		t.Errorf("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestClientSocket(t *testing.T) {
+	// Socket paths are limited in length, so the directory is kept short
+	dir, err := os.MkdirTemp("", "psc")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "api.sock")
+	listener, err := api.Listen("unix:" + socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go http.Serve(listener, api.NewServer(&fakeSource{events: testEvents()}, api.Options{}))
+	defer listener.Close()
+
+	remote, err := client.New(client.Options{Socket: socket})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if events := remote.GetEvents(); len(events) != 4 {
+		t.Errorf("Expected 4 events over the socket, got %d", len(events))
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	broker := stream.NewBroker(10, stream.DropOldest)
+	server := httptest.NewServer(api.NewServer(&fakeSource{}, api.Options{Broker: broker, Token: "secret"}))
+	defer server.Close()
+
+	remote, _ := client.New(client.Options{Addr: server.URL, Token: "secret"})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := remote.Subscribe(ctx)
+
+	for broker.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	broker.Publish(streamEvent("1", 22, models.SeverityHigh))
+
+	select {
+	case event := <-ch:
+		if event.ID != "1" {
+			t.Errorf("Expected event 1, got %s", event.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected an event from the stream")
+	}
+
+	// The channel is closed once the context is done
+	cancel()
+	for range ch {
+	}
+}
+
+func TestUIDisconnected(t *testing.T) {
+	server := httptest.NewServer(api.NewServer(&fakeSource{events: testEvents()}, api.Options{}))
+	remote, _ := client.New(client.Options{Addr: server.URL})
+
+	var m tea.Model = ui.NewModel(remote, false)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	if view := m.View(); strings.Contains(view, "DISCONNECTED") {
+		t.Error("Expected no connection error while the daemon is reachable")
+	}
+
+	server.Close()
+	remote.GetStats()
+	if view := m.View(); !strings.Contains(view, "DISCONNECTED") {
+		t.Error("Expected the header to show the connection error")
+	}
+}
+
+// remoteLists is a remote source with lists that counts the reads of the
+// lists
+type remoteLists struct {
+	fakeSource
+	fakeLists
+	reads int
+}
+
+func (r *remoteLists) Err() error {
+	return nil
+}
+
+func (r *remoteLists) Entries(list string) []string {
+	r.reads++
+	return r.fakeLists.Entries(list)
+}
+
+func TestUIRemoteLoads(t *testing.T) {
+	source := &remoteLists{fakeSource: fakeSource{events: testEvents()}, fakeLists: fakeLists{ui.ListAllow: {"192.0.2.1"}}}
+	m := ui.NewModel(source, false).WithLists(source)
+	p := &program{model: m, msgs: make(chan tea.Msg, 100)}
+	p.model, _ = p.model.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+
+	// A remote source is only read by commands, never within Update
+	var cmd tea.Cmd
+	p.model, cmd = p.model.Update(runes("r")[0])
+	if source.reads != 0 || !strings.Contains(p.model.View(), "Total Scans: 0") {
+		t.Error("Expected no reads before the commands run")
+	}
+	p.run(cmd)
+	p.settle()
+	if view := p.model.View(); !strings.Contains(view, "Total Scans: 4") {
+		t.Error("Expected the statistics loaded by the commands")
+	}
+
+	// Rendering the Blocklist tab uses the entries read last
+	reads := source.reads
+	p.model = sendKeys(p.model, tea.KeyMsg{Type: tea.KeyShiftTab})
+	if view := p.model.View(); !strings.Contains(view, "Allow list (1)") || !strings.Contains(view, "192.0.2.1") {
+		t.Error("Expected the loaded allow list")
+	}
+	if source.reads != reads {
+		t.Errorf("Expected no reads while rendering, got %d", source.reads-reads)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// exportEvents returns a fixed set of events for formatter tests
+func exportEvents() []models.ScanEvent {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.ScanEvent{
+		{ID: "1", SourceIP: "203.0.113.5", SourcePort: 40000, TargetPort: 22, Timestamp: at, Protocol: "tcp", ScanType: "port_scan", Severity: models.SeverityHigh, Description: "SSH probe, fast"},
+		{ID: "2", SourceIP: "198.51.100.7", SourcePort: 40001, TargetPort: 80, Timestamp: at.Add(time.Minute), Protocol: "tcp", ScanType: "port_scan", Severity: models.SeverityLow, Description: "HTTP probe"},
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	events := exportEvents()
+
+	var buf bytes.Buffer
+	formatter, err := export.Get("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := formatter.FormatEvents(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []models.ScanEvent
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 {
+		t.Errorf("Expected JSON array with 2 events, got %q (%v)", buf.String(), err)
+	}
+
+	buf.Reset()
+	formatter, _ = export.Get("CSV")
+	formatter.FormatEvents(&buf, events)
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected CSV with header and 2 rows, got %d (%v)", len(records), err)
+	}
+	if records[1][7] != "SSH probe, fast" {
+		t.Errorf("Expected quoted description to survive, got %q", records[1][7])
+	}
+
+	buf.Reset()
+	formatter, _ = export.Get("table")
+	formatter.FormatEvents(&buf, events)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Errorf("Expected table with header and 2 rows, got %q", buf.String())
+	}
+
+	buf.Reset()
+	formatter, _ = export.Get("markdown")
+	events[1].Description = "HTTP | probe"
+	formatter.FormatEvents(&buf, events)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "| Time | Source IP |") || !strings.HasPrefix(lines[1], "| --- |") {
+		t.Errorf("Expected Markdown table with header and 2 rows, got %q", buf.String())
+	}
+	if !strings.Contains(lines[3], "HTTP \\| probe") {
+		t.Errorf("Expected escaped pipe in %q", lines[3])
+	}
+
+	if _, err := export.Get("bogus"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestGroupEvents(t *testing.T) {
+	events := append(exportEvents(), models.ScanEvent{
+		ID: "3", SourceIP: "203.0.113.5", TargetPort: 23, Timestamp: time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC), Severity: models.SeverityMedium,
+	})
+
+	groups, err := models.GroupEvents(events, models.GroupByIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].Key != "203.0.113.5" || groups[0].Count != 2 || groups[0].UniquePorts != 2 {
+		t.Errorf("Unexpected groups by ip: %+v", groups)
+	}
+	if groups[0].MaxSeverity != models.SeverityHigh {
+		t.Errorf("Expected max severity HIGH, got %v", groups[0].MaxSeverity)
+	}
+
+	groups, _ = models.GroupEvents(events, models.GroupByHour)
+	if len(groups) != 2 || groups[0].Key != "2024-01-02 03:00" {
+		t.Errorf("Unexpected groups by hour: %+v", groups)
+	}
+
+	if _, err := models.GroupEvents(events, "bogus"); err == nil {
+		t.Error("Expected error for unknown group key")
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteGroups(&buf, "csv", groups); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "Key,Count,") {
+		t.Errorf("Expected CSV header, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := export.WriteGroups(&buf, "markdown", groups); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[0], "| Key | Count |") || !strings.HasPrefix(lines[2], "| 2024-01-02 03:00 |") {
+		t.Errorf("Expected a Markdown table of 2 groups, got %q", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/fail2ban"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+func TestFail2banFormatLine(t *testing.T) {
+	event := models.ScanEvent{
+		SourceIP:   "203.0.113.5",
+		TargetPort: 22,
+		Timestamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ScanType:   "port scan",
+		Severity:   models.SeverityHigh,
+	}
+
+	expected := "2024-01-02T03:04:05Z portscammer[scan]: host=203.0.113.5 port=22 severity=HIGH type=port_scan"
+	if result := fail2ban.FormatLine(event); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestFail2banFailRegexMatchesLine(t *testing.T) {
+	// fail2ban replaces <HOST> with its own host pattern and strips the date
+	pattern := strings.Replace(fail2ban.FailRegex(), "<HOST>", `(?P<host>[0-9a-fA-F.:]+)`, 1)
+	re := regexp.MustCompile(pattern)
+
+	tests := []string{"192.168.1.100", "2001:db8::1"}
+	for _, ip := range tests {
+		event := *models.NewScanEvent(ip, 40000, 8080, "tcp", "port_scan", "test")
+		line := fail2ban.FormatLine(event)
+		line = line[strings.Index(line, " "):]
+
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			t.Errorf("failregex did not match %q", line)
+			continue
+		}
+		if match[1] != ip {
+			t.Errorf("Expected host %s, got %s", ip, match[1])
+		}
+	}
+}
+
+func TestFail2banBanLine(t *testing.T) {
+	expected := "2024-01-02T03:04:05Z portscammer[scan]: host=203.0.113.5 port=0 severity=CRITICAL type=blocked"
+	line := fail2ban.BanLine("203.0.113.5", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+
+	pattern := strings.Replace(fail2ban.FailRegex(), "<HOST>", `(?P<host>[0-9a-fA-F.:]+)`, 1)
+	if !regexp.MustCompile(pattern).MatchString(line[strings.Index(line, " "):]) {
+		t.Errorf("failregex did not match %q", line)
+	}
+}
+
+func TestFail2banJail(t *testing.T) {
+	opts := fail2ban.DefaultJailOptions()
+	opts.LogPath = "/tmp/portscammer.log"
+	opts.BanTime = time.Hour * 2
+
+	jail := fail2ban.Jail(opts)
+
+	expected := []string{
+		"[portscammer]",
+		"filter    = portscammer",
+		"logpath   = /tmp/portscammer.log",
+		"bantime   = 7200",
+		"findtime  = 300",
+	}
+	for _, line := range expected {
+		if !strings.Contains(jail, line) {
+			t.Errorf("Expected jail to contain %q", line)
+		}
+	}
+}
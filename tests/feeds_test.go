@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/intel"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// writeFeed writes content to name in dir and returns its path
+func writeFeed(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFeedSpec(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected intel.FeedSpec
+		wantErr  bool
+	}{
+		{"firehol=/feeds/firehol_level1.netset", intel.FeedSpec{Name: "firehol", Path: "/feeds/firehol_level1.netset"}, false},
+		{"feodo:csv=/feeds/ipblocklist", intel.FeedSpec{Name: "feodo", Path: "/feeds/ipblocklist", Format: "csv"}, false},
+		{"drop:xml=/feeds/drop.xml", intel.FeedSpec{}, true},
+		{"/feeds/drop.txt", intel.FeedSpec{}, true},
+		{"=/feeds/drop.txt", intel.FeedSpec{}, true},
+	}
+
+	for _, test := range tests {
+		spec, err := intel.ParseFeedSpec(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseFeedSpec(%q): expected error %v, got %v", test.input, test.wantErr, err)
+			continue
+		}
+		if spec != test.expected {
+			t.Errorf("ParseFeedSpec(%q): expected %+v, got %+v", test.input, test.expected, spec)
+		}
+	}
+}
+
+func TestLoadFeedFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		entries int
+		skipped int
+		match   string
+	}{
+		{"firehol.netset", "# FireHOL level 1\n203.0.113.0/24\n198.51.100.7\n", 2, 0, "203.0.113.99"},
+		{"drop.txt", "; Spamhaus DROP\n203.0.113.0/24 ; SBL123\nnot-an-ip ; SBL456\n", 1, 1, "203.0.113.1"},
+		{"feodo.csv", "# \"first_seen_utc\",\"dst_ip\",\"dst_port\"\n\"2024-01-02 03:04:05\",\"198.51.100.7\",\"443\"\nfirst_seen,dst_ip\n", 1, 0, "198.51.100.7"},
+		{"feodo.json", `[{"ip_address":"198.51.100.7","port":443},{"ip_address":"2001:db8::/32"}]`, 2, 0, "2001:db8::5"},
+		{"nested.json", `{"data":{"networks":["203.0.113.0/24"]}}`, 1, 0, "203.0.113.5"},
+	}
+
+	for _, test := range tests {
+		spec := intel.FeedSpec{Name: test.name, Path: writeFeed(t, dir, test.name, test.content)}
+		tree, info, err := intel.LoadFeed(spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if info.Entries != test.entries || info.Skipped != test.skipped {
+			t.Errorf("%s: expected %d entries and %d skipped, got %d and %d", test.name, test.entries, test.skipped, info.Entries, info.Skipped)
+		}
+		if tree.Len() != test.entries {
+			t.Errorf("%s: expected tree of %d, got %d", test.name, test.entries, tree.Len())
+		}
+
+		feeds := intel.NewFeeds([]intel.FeedSpec{spec}, 1)
+		if _, err := feeds.Refresh(true); err != nil {
+			t.Fatal(err)
+		}
+		if names := feeds.Match(test.match); len(names) != 1 {
+			t.Errorf("%s: expected %s to match, got %v", test.name, test.match, names)
+		}
+	}
+}
+
+func TestFeedsEnrich(t *testing.T) {
+	dir := t.TempDir()
+	specs := []intel.FeedSpec{
+		{Name: "firehol", Path: writeFeed(t, dir, "firehol.netset", "203.0.113.0/24\n")},
+		{Name: "drop", Path: writeFeed(t, dir, "drop.txt", "203.0.113.5\n")},
+	}
+	feeds := intel.NewFeeds(specs, 1)
+	if _, err := feeds.Refresh(true); err != nil {
+		t.Fatal(err)
+	}
+
+	event := models.ScanEvent{SourceIP: "203.0.113.5", Severity: models.SeverityMedium}
+	feeds.Enrich(&event)
+	if !reflect.DeepEqual(event.Tags, []string{"firehol", "drop"}) {
+		t.Errorf("Expected tags [firehol drop], got %v", event.Tags)
+	}
+	if event.Severity != models.SeverityHigh {
+		t.Errorf("Expected severity %s, got %s", models.SeverityHigh, event.Severity)
+	}
+
+	unlisted := models.ScanEvent{SourceIP: "198.51.100.7", Severity: models.SeverityLow}
+	feeds.Enrich(&unlisted)
+	if len(unlisted.Tags) != 0 || unlisted.Severity != models.SeverityLow {
+		t.Errorf("Expected unlisted source to be unchanged, got %v %s", unlisted.Tags, unlisted.Severity)
+	}
+}
+
+func TestFeedsRefreshOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFeed(t, dir, "drop.txt", "203.0.113.5\n")
+	feeds := intel.NewFeeds([]intel.FeedSpec{{Name: "drop", Path: path}}, 1)
+	if _, err := feeds.Refresh(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded, err := feeds.Refresh(false); err != nil || len(loaded) != 0 {
+		t.Errorf("Expected no reload of an unchanged feed, got %v (%v)", loaded, err)
+	}
+
+	writeFeed(t, dir, "drop.txt", "198.51.100.7\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded, err := feeds.Refresh(false); err != nil || len(loaded) != 1 {
+		t.Errorf("Expected the changed feed to reload, got %v (%v)", loaded, err)
+	}
+	if len(feeds.Match("198.51.100.7")) != 1 || len(feeds.Match("203.0.113.5")) != 0 {
+		t.Errorf("Expected the feed to hold the new entries")
+	}
+
+	// A feed that can no longer be read keeps its previous entries
+	os.Remove(path)
+	if _, err := feeds.Refresh(true); err == nil {
+		t.Errorf("Expected an error for a missing feed")
+	}
+	if len(feeds.Match("198.51.100.7")) != 1 {
+		t.Errorf("Expected the previous entries to be kept")
+	}
+}
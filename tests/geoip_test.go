@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"jonasbn.github.com/portscammer/internal/geoip"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+// openGeoIP opens the fixture databases in testdata/geoip
+func openGeoIP(t *testing.T, names ...string) *geoip.Reader {
+	t.Helper()
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join("testdata", "geoip", name)
+	}
+	r, err := geoip.Open(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestGeoIPLookup(t *testing.T) {
+	r := openGeoIP(t, "GeoLite2-City-Test.mmdb", "GeoLite2-ASN-Test.mmdb", "ipinfo-country-asn-Test.mmdb")
+
+	tests := []struct {
+		ip       string
+		expected geoip.Record
+		found    bool
+	}{
+		{"81.2.69.160", geoip.Record{Country: "GB", CountryName: "United Kingdom", City: "London", ASN: 20712, Org: "Andrews & Arnold Ltd"}, true},
+		{"2001:db8:1::5", geoip.Record{Country: "SE", CountryName: "Sweden", City: "Stockholm", ASN: 64512, Org: "Example Net"}, true},
+		{"203.0.113.5", geoip.Record{Country: "DE", CountryName: "Germany", ASN: 64500, Org: "Example GmbH"}, true},
+		{"198.51.100.7", geoip.Record{}, false},
+		{"not-an-ip", geoip.Record{}, false},
+	}
+
+	for _, test := range tests {
+		record, found := r.Lookup(test.ip)
+		if found != test.found {
+			t.Errorf("Lookup(%s): expected found %v, got %v", test.ip, test.found, found)
+		}
+		if record != test.expected {
+			t.Errorf("Lookup(%s): expected %+v, got %+v", test.ip, test.expected, record)
+		}
+	}
+}
+
+func TestGeoIPEnrich(t *testing.T) {
+	r := openGeoIP(t, "GeoLite2-City-Test.mmdb", "GeoLite2-ASN-Test.mmdb")
+
+	event := models.ScanEvent{SourceIP: "81.2.69.160"}
+	r.Enrich(&event)
+	if event.Country != "GB" || event.City != "London" || event.ASN != 20712 || event.Org != "Andrews & Arnold Ltd" {
+		t.Errorf("Expected GB, London, AS20712, got %s, %s, AS%d %s", event.Country, event.City, event.ASN, event.Org)
+	}
+
+	// Only the city database is needed for the country
+	r = openGeoIP(t, "GeoLite2-City-Test.mmdb")
+	event = models.ScanEvent{SourceIP: "2001:db8:1::5"}
+	r.Enrich(&event)
+	if event.Country != "SE" || event.ASN != 0 {
+		t.Errorf("Expected SE without ASN, got %s AS%d", event.Country, event.ASN)
+	}
+}
+
+func TestGeoIPOpenMissing(t *testing.T) {
+	if _, err := geoip.Open(filepath.Join("testdata", "geoip", "missing.mmdb")); err == nil {
+		t.Error("Expected error for missing database")
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/intel"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/utils"
+)
+
+// intelEvents returns events from a public, a private and an IPv6 source
+func intelEvents() []models.ScanEvent {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.ScanEvent{
+		{ID: "1", SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: at, Severity: models.SeverityLow},
+		{ID: "2", SourceIP: "203.0.113.5", TargetPort: 80, Timestamp: at.Add(time.Hour), Severity: models.SeverityHigh},
+		{ID: "3", SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: at.Add(-time.Hour), Severity: models.SeverityLow},
+		{ID: "4", SourceIP: "192.168.1.10", TargetPort: 22, Timestamp: at, Severity: models.SeverityLow},
+		{ID: "5", SourceIP: "2001:db8::1", TargetPort: 443, Timestamp: at, Severity: models.SeverityMedium},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	sightings := intel.Aggregate(intelEvents(), utils.IsPrivateIP)
+	if len(sightings) != 2 {
+		t.Fatalf("Expected 2 sightings, got %d", len(sightings))
+	}
+
+	s := sightings[1]
+	if s.IP != "203.0.113.5" {
+		t.Fatalf("Expected 203.0.113.5, got %s", s.IP)
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if s.Count != 3 || !s.FirstSeen.Equal(at.Add(-time.Hour)) || !s.LastSeen.Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected 3 events from %s to %s, got %d from %s to %s", at.Add(-time.Hour), at.Add(time.Hour), s.Count, s.FirstSeen, s.LastSeen)
+	}
+	if len(s.Ports) != 2 || s.Ports[0] != 22 || s.Ports[1] != 80 {
+		t.Errorf("Expected ports [22 80], got %v", s.Ports)
+	}
+	if s.Severity != models.SeverityHigh {
+		t.Errorf("Expected severity %s, got %s", models.SeverityHigh, s.Severity)
+	}
+	if !sightings[0].IsIPv6() || s.IsIPv6() {
+		t.Errorf("Expected only %s to be IPv6", sightings[0].IP)
+	}
+}
+
+func TestSTIXBundle(t *testing.T) {
+	now := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	sightings := intel.Aggregate(intelEvents(), utils.IsPrivateIP)
+
+	var buf bytes.Buffer
+	if err := intel.WriteSTIX(&buf, sightings, "portscammer", now); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle struct {
+		Type    string                   `json:"type"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if bundle.Type != "bundle" {
+		t.Errorf("Expected type bundle, got %s", bundle.Type)
+	}
+
+	ids := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, object := range bundle.Objects {
+		ids[object["id"].(string)] = true
+		counts[object["type"].(string)]++
+	}
+	expected := map[string]int{"identity": 1, "ipv4-addr": 1, "ipv6-addr": 1, "indicator": 2, "observed-data": 2, "sighting": 2}
+	for kind, count := range expected {
+		if counts[kind] != count {
+			t.Errorf("Expected %d %s objects, got %d", count, kind, counts[kind])
+		}
+	}
+
+	for _, object := range bundle.Objects {
+		switch object["type"] {
+		case "ipv4-addr":
+			// Deterministic identifier defined by the STIX 2.1 specification
+			if object["id"] != "ipv4-addr--826fe3cb-56b0-5620-9d30-4c17ed7b24e3" {
+				t.Errorf("Expected ipv4-addr--826fe3cb-56b0-5620-9d30-4c17ed7b24e3, got %v", object["id"])
+			}
+		case "sighting":
+			if !ids[object["sighting_of_ref"].(string)] {
+				t.Errorf("Expected sighting_of_ref to resolve, got %v", object["sighting_of_ref"])
+			}
+			if object["count"].(float64) == 3 && object["first_seen"] != "2024-01-02T02:04:05.000Z" {
+				t.Errorf("Expected first_seen 2024-01-02T02:04:05.000Z, got %v", object["first_seen"])
+			}
+		case "indicator":
+			if object["pattern"] != "[ipv4-addr:value = '203.0.113.5']" && object["pattern"] != "[ipv6-addr:value = '2001:db8::1']" {
+				t.Errorf("Unexpected pattern %v", object["pattern"])
+			}
+		}
+	}
+
+	var again bytes.Buffer
+	if err := intel.WriteSTIX(&again, sightings, "portscammer", now); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != buf.String() {
+		t.Errorf("Expected repeated exports to be identical")
+	}
+}
+
+func TestMISPEvent(t *testing.T) {
+	now := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	sightings := intel.Aggregate(intelEvents(), nil)
+
+	var buf bytes.Buffer
+	if err := intel.WriteMISP(&buf, sightings, "test", now); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Event struct {
+			Info          string `json:"info"`
+			Date          string `json:"date"`
+			ThreatLevelID string `json:"threat_level_id"`
+			Attribute     []struct {
+				Type     string `json:"type"`
+				Value    string `json:"value"`
+				LastSeen string `json:"last_seen"`
+			} `json:"Attribute"`
+		} `json:"Event"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	event := decoded.Event
+	if event.Info != "test" || event.Date != "2024-01-03" {
+		t.Errorf("Expected info test on 2024-01-03, got %s on %s", event.Info, event.Date)
+	}
+	if event.ThreatLevelID != "1" {
+		t.Errorf("Expected threat level 1, got %s", event.ThreatLevelID)
+	}
+	if len(event.Attribute) != 3 {
+		t.Fatalf("Expected 3 attributes, got %d", len(event.Attribute))
+	}
+	for _, attribute := range event.Attribute {
+		if attribute.Type != "ip-src" {
+			t.Errorf("Expected type ip-src, got %s", attribute.Type)
+		}
+		if attribute.Value == "203.0.113.5" && attribute.LastSeen != "2024-01-02T04:04:05Z" {
+			t.Errorf("Expected last_seen 2024-01-02T04:04:05Z, got %s", attribute.LastSeen)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"jonasbn.github.com/portscammer/internal/iplist"
+)
+
+func TestIPListContains(t *testing.T) {
+	list := iplist.New()
+	for _, entry := range []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"} {
+		if _, err := list.Add(entry); err != nil {
+			t.Fatalf("Unexpected error adding %s: %v", entry, err)
+		}
+	}
+
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"::ffff:10.0.0.1", true},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"invalid-ip", false},
+	}
+
+	for _, test := range tests {
+		if result := list.Contains(test.ip); result != test.expected {
+			t.Errorf("Contains(%s): expected %v, got %v", test.ip, test.expected, result)
+		}
+	}
+}
+
+func TestIPListAddRemove(t *testing.T) {
+	list := iplist.New()
+
+	if added, _ := list.Add("10.0.0.1/8"); !added {
+		t.Error("Expected first add to report a new entry")
+	}
+	if added, _ := list.Add("10.0.0.0/8"); added {
+		t.Error("Expected equivalent CIDR to be reported as existing")
+	}
+	if _, err := list.Add("not-an-ip"); err == nil {
+		t.Error("Expected error for invalid entry")
+	}
+	if removed, _ := list.Remove("10.0.0.0/8"); !removed {
+		t.Error("Expected entry to be removed")
+	}
+	if removed, _ := list.Remove("10.0.0.0/8"); removed {
+		t.Error("Expected second remove to report a missing entry")
+	}
+	if list.Len() != 0 {
+		t.Errorf("Expected empty list, got %d entries", list.Len())
+	}
+}
+
+func TestIPListLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	content := "# blocked sources\n203.0.113.0/24\n\n198.51.100.7 # single host\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := iplist.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := list.Add("2001:db8::1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Save(); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+
+	reloaded, err := iplist.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+
+	expected := []string{"198.51.100.7", "203.0.113.0/24", "2001:db8::1"}
+	if entries := reloaded.Entries(); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected entries %v, got %v", expected, entries)
+	}
+}
+
+func TestIPListConcurrentSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blacklist.txt")
+	list, err := iplist.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			list.Add(fmt.Sprintf("192.0.2.%d", i))
+			if err := list.Save(); err != nil {
+				t.Errorf("Unexpected error saving: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The last save holds every entry and no temporary files are left
+	reloaded, err := iplist.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if reloaded.Len() != 50 {
+		t.Errorf("Expected 50 entries, got %d", reloaded.Len())
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected only the list file, got %d files", len(files))
+	}
+}
+
+func TestIPListLoadMissingFile(t *testing.T) {
+	list, err := iplist.Load(filepath.Join(t.TempDir(), "missing.txt"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if list.Len() != 0 {
+		t.Errorf("Expected empty list, got %d entries", list.Len())
+	}
+}
+
+func TestTreeLongestMatch(t *testing.T) {
+	tree := iplist.NewTree[string]()
+	tree.Insert(netip.MustParsePrefix("10.0.0.0/8"), "wide")
+	tree.Insert(netip.MustParsePrefix("10.1.0.0/16"), "narrow")
+
+	tests := []struct {
+		ip       string
+		expected string
+		found    bool
+	}{
+		{"10.1.2.3", "narrow", true},
+		{"10.2.0.1", "wide", true},
+		{"11.0.0.1", "", false},
+	}
+
+	for _, test := range tests {
+		value, found := tree.Lookup(netip.MustParseAddr(test.ip))
+		if value != test.expected || found != test.found {
+			t.Errorf("Lookup(%s): expected (%q, %v), got (%q, %v)", test.ip, test.expected, test.found, value, found)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/metrics"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	stats := models.ScanStats{
+		TotalScans:     3,
+		UniqueIPs:      2,
+		LastScanTime:   time.Unix(1700000000, 0),
+		ScansByPort:    map[int]int{22: 2, 8080: 1},
+		ScansByType:    map[string]int{"port_scan": 3},
+		SeverityCounts: map[models.Severity]int{models.SeverityHigh: 1, models.SeverityMedium: 2},
+	}
+
+	m := metrics.New(func() models.ScanStats { return stats })
+	m.SetBlacklistSize(func() int { return 4 })
+	m.IncConnectionsAccepted()
+	m.IncConnectionsAccepted()
+	m.IncAlertSinkFailures()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Expected content type %q, got %q", metrics.ContentType, ct)
+	}
+
+	body := rec.Body.String()
+	expected := []string{
+		"# TYPE portscammer_connections_accepted_total counter",
+		"portscammer_connections_accepted_total 2",
+		"portscammer_scan_events_total 3",
+		`portscammer_scan_events_by_severity_total{severity="high"} 1`,
+		`portscammer_scan_events_by_severity_total{severity="low"} 0`,
+		`portscammer_scan_events_by_type_total{type="port_scan"} 3`,
+		`portscammer_scan_events_by_port_total{port="22"} 2`,
+		"portscammer_unique_source_ips 2",
+		"portscammer_last_scan_timestamp_seconds 1.7e+09",
+		"portscammer_blacklist_entries 4",
+		"portscammer_listener_errors_total 0",
+		"portscammer_alert_sink_failures_total 1",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics output to contain %q", line)
+		}
+	}
+
+	if strings.Contains(body, "portscammer_tracker_entries") {
+		t.Error("Expected tracker entries to be omitted when not configured")
+	}
+}
+
+func TestMetricsPortSeries(t *testing.T) {
+	stats := models.ScanStats{ScansByPort: make(map[int]int)}
+	for port := 1; port <= metrics.MaxPortSeries+50; port++ {
+		stats.ScansByPort[port] = 1
+	}
+	stats.ScansByPort[60000] = 5
+
+	var b strings.Builder
+	if err := metrics.New(func() models.ScanStats { return stats }).Write(&b); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	body := b.String()
+
+	series := strings.Count(body, "portscammer_scan_events_by_port_total{")
+	if series != metrics.MaxPortSeries+1 {
+		t.Errorf("Expected %d port series, got %d", metrics.MaxPortSeries+1, series)
+	}
+	for _, line := range []string{
+		`portscammer_scan_events_by_port_total{port="60000"} 5`,
+		`portscammer_scan_events_by_port_total{port="1"} 1`,
+		`portscammer_scan_events_by_port_total{port="other"} 51`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics output to contain %q", line)
+		}
+	}
+}
@@ -65,3 +65,114 @@ func TestSeverityString(t *testing.T) {
 		}
 	}
 }
+
+func TestSeverityRaise(t *testing.T) {
+	tests := []struct {
+		severity models.Severity
+		levels   int
+		expected models.Severity
+	}{
+		{models.SeverityLow, 1, models.SeverityMedium},
+		{models.SeverityMedium, 2, models.SeverityCritical},
+		{models.SeverityHigh, 5, models.SeverityCritical},
+		{models.SeverityLow, -1, models.SeverityLow},
+	}
+
+	for _, test := range tests {
+		result := test.severity.Raise(test.levels)
+		if result != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, result)
+		}
+	}
+}
+
+func TestMergeStats(t *testing.T) {
+	a := models.ScanStats{
+		TotalScans:     2,
+		LastScanTime:   time.Unix(100, 0),
+		ScansByIP:      map[string]int{"10.0.0.1": 2},
+		SeverityCounts: map[models.Severity]int{models.SeverityHigh: 2},
+		ScansByCountry: map[string]int{"GB": 2},
+	}
+	b := models.ScanStats{
+		TotalScans:     3,
+		LastScanTime:   time.Unix(200, 0),
+		ScansByIP:      map[string]int{"10.0.0.1": 1, "10.0.0.2": 2},
+		SeverityCounts: map[models.Severity]int{models.SeverityHigh: 1},
+		ScansByCountry: map[string]int{"GB": 1, "SE": 2},
+		ScansByASN:     map[uint]int{20712: 3},
+	}
+
+	merged := models.MergeStats(a, b)
+
+	if merged.TotalScans != 5 {
+		t.Errorf("Expected TotalScans 5, got %d", merged.TotalScans)
+	}
+	if merged.UniqueIPs != 2 {
+		t.Errorf("Expected UniqueIPs 2, got %d", merged.UniqueIPs)
+	}
+	if !merged.LastScanTime.Equal(time.Unix(200, 0)) {
+		t.Errorf("Expected latest LastScanTime, got %v", merged.LastScanTime)
+	}
+	if merged.SeverityCounts[models.SeverityHigh] != 3 {
+		t.Errorf("Expected 3 high severity scans, got %d", merged.SeverityCounts[models.SeverityHigh])
+	}
+	if merged.ScansByCountry["GB"] != 3 || merged.ScansByCountry["SE"] != 2 || merged.ScansByASN[20712] != 3 {
+		t.Errorf("Expected country and ASN counts to be summed, got %v %v", merged.ScansByCountry, merged.ScansByASN)
+	}
+}
+
+func TestEventTool(t *testing.T) {
+	tests := []struct {
+		event    models.ScanEvent
+		expected string
+	}{
+		{models.ScanEvent{UserAgent: "Mozilla/5.0 (compatible; Nmap Scripting Engine; https://nmap.org/book/nse.html)"}, "nmap"},
+		{models.ScanEvent{UserAgent: "Mozilla/5.0 zgrab/0.x"}, "zgrab"},
+		{models.ScanEvent{Payload: "SSH-2.0-Go\r\n"}, "go"},
+		{models.ScanEvent{UserAgent: "curl/8.4.0"}, "curl"},
+		{models.ScanEvent{UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}, ""},
+		{models.ScanEvent{}, ""},
+	}
+
+	for _, test := range tests {
+		if tool := test.event.Tool(); tool != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, tool)
+		}
+	}
+}
+
+func TestStatsHistory(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 30, 10, 0, time.UTC)
+	history := models.NewStatsHistory(time.Hour)
+	for _, event := range []models.ScanEvent{
+		{Timestamp: now.Add(-2 * time.Hour), Severity: models.SeverityLow},
+		{Timestamp: now.Add(-59 * time.Minute), Severity: models.SeverityHigh},
+		{Timestamp: now, Severity: models.SeverityLow},
+		{Timestamp: now.Add(-5 * time.Second), Severity: models.SeverityCritical},
+	} {
+		history.Add(event)
+	}
+
+	minutes := history.Minutes(now)
+	if len(minutes) != 60 {
+		t.Fatalf("Expected 60 minutes, got %d", len(minutes))
+	}
+	if !minutes[59].Start.Equal(now.Truncate(time.Minute)) {
+		t.Errorf("Expected the last minute to start at %s, got %s", now.Truncate(time.Minute), minutes[59].Start)
+	}
+
+	total := 0
+	for _, minute := range minutes {
+		total += minute.Events
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 events in the last hour, got %d", total)
+	}
+	if minutes[59].Events != 2 || minutes[59].SeverityCounts[models.SeverityCritical] != 1 {
+		t.Errorf("Expected 2 events in the last minute, got %+v", minutes[59])
+	}
+	if minutes[0].Events != 1 || minutes[0].SeverityCounts[models.SeverityHigh] != 1 {
+		t.Errorf("Expected the high event in the first minute, got %+v", minutes[0])
+	}
+}
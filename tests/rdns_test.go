@@ -0,0 +1,254 @@
+package tests
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/rdns"
+)
+
+// stubDNS is a minimal DNS server answering PTR queries from a fixed table
+type stubDNS struct {
+	conn    net.PacketConn
+	names   map[string]string // PTR query name to hostname
+	delay   time.Duration
+	queries atomic.Int32
+}
+
+// startStubDNS starts a stub DNS server on a random local UDP port
+func startStubDNS(t *testing.T, names map[string]string, delay time.Duration) *stubDNS {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubDNS{conn: conn, names: names, delay: delay}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			s.queries.Add(1)
+			query := append([]byte(nil), buf[:n]...)
+			go func() {
+				time.Sleep(s.delay)
+				if response := s.answer(query); response != nil {
+					conn.WriteTo(response, addr)
+				}
+			}()
+		}
+	}()
+
+	return s
+}
+
+// Addr returns the address of the server
+func (s *stubDNS) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// answer builds the response to a query, or nil if it can't be parsed
+func (s *stubDNS) answer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// Read the question name
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		l := int(query[i])
+		if i+1+l > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[i+1:i+1+l]))
+		i += 1 + l
+	}
+	end := i + 5 // Terminating zero, type and class
+	if end > len(query) {
+		return nil
+	}
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+
+	hostname, ok := s.names[name]
+	response := make([]byte, 12, 512)
+	copy(response, query[:2])                        // ID
+	binary.BigEndian.PutUint16(response[2:], 0x8180) // Response, recursion desired and available
+	binary.BigEndian.PutUint16(response[4:], 1)      // Questions
+	if !ok {
+		binary.BigEndian.PutUint16(response[2:], 0x8183) // NXDOMAIN
+		return append(response, query[12:end]...)
+	}
+	binary.BigEndian.PutUint16(response[6:], 1) // Answers
+	response = append(response, query[12:end]...)
+
+	var rdata []byte
+	for _, label := range strings.Split(strings.TrimSuffix(hostname, "."), ".") {
+		rdata = append(rdata, byte(len(label)))
+		rdata = append(rdata, label...)
+	}
+	rdata = append(rdata, 0)
+
+	response = append(response, 0xc0, 0x0c)                 // Pointer to the question name
+	response = binary.BigEndian.AppendUint16(response, 12)  // PTR
+	response = binary.BigEndian.AppendUint16(response, 1)   // IN
+	response = binary.BigEndian.AppendUint32(response, 300) // TTL
+	response = binary.BigEndian.AppendUint16(response, uint16(len(rdata)))
+	return append(response, rdata...)
+}
+
+// stubNames maps 203.0.113.5 to scanner.example.net
+var stubNames = map[string]string{
+	"5.113.0.203.in-addr.arpa.": "scanner.example.net.",
+}
+
+func TestResolverResolve(t *testing.T) {
+	server := startStubDNS(t, stubNames, 0)
+	r := rdns.New(rdns.Options{Server: server.Addr(), Timeout: time.Second})
+	defer r.Close()
+
+	hostname, err := r.Resolve(context.Background(), "203.0.113.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostname != "scanner.example.net" {
+		t.Errorf("Expected scanner.example.net, got %s", hostname)
+	}
+
+	if _, err := r.Resolve(context.Background(), "198.51.100.7"); err == nil {
+		t.Error("Expected error for an address without PTR record")
+	}
+}
+
+func TestResolverLookupIsAsynchronous(t *testing.T) {
+	server := startStubDNS(t, stubNames, time.Millisecond*100)
+	r := rdns.New(rdns.Options{Server: server.Addr(), Timeout: time.Second})
+	defer r.Close()
+
+	event := models.ScanEvent{SourceIP: "203.0.113.5"}
+	start := time.Now()
+	r.Enrich(&event)
+	if elapsed := time.Since(start); elapsed > time.Millisecond*50 {
+		t.Errorf("Expected Enrich to return immediately, took %s", elapsed)
+	}
+	if event.Hostname != "" {
+		t.Errorf("Expected no hostname before the lookup completes, got %s", event.Hostname)
+	}
+
+	deadline := time.Now().Add(time.Second * 2)
+	for event.Hostname == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+		r.Enrich(&event)
+	}
+	if event.Hostname != "scanner.example.net" {
+		t.Fatalf("Expected scanner.example.net, got %q", event.Hostname)
+	}
+
+	// Further lookups are served from the cache
+	queries := server.queries.Load()
+	for i := 0; i < 10; i++ {
+		r.Lookup("203.0.113.5")
+	}
+	if server.queries.Load() != queries {
+		t.Errorf("Expected cached lookups, got %d more queries", server.queries.Load()-queries)
+	}
+}
+
+func TestResolverCachedDoesNotQueue(t *testing.T) {
+	server := startStubDNS(t, stubNames, 0)
+	r := rdns.New(rdns.Options{Server: server.Addr(), Timeout: time.Second})
+	defer r.Close()
+
+	if hostname, ok := r.Cached("203.0.113.5"); ok {
+		t.Errorf("Expected no cached hostname, got %s", hostname)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if queries := server.queries.Load(); queries != 0 {
+		t.Errorf("Expected no queries on a cache miss, got %d", queries)
+	}
+
+	r.Lookup("203.0.113.5")
+	deadline := time.Now().Add(time.Second * 2)
+	hostname, _ := r.Cached("203.0.113.5")
+	for hostname == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+		hostname, _ = r.Cached("203.0.113.5")
+	}
+	if hostname != "scanner.example.net" {
+		t.Errorf("Expected scanner.example.net, got %q", hostname)
+	}
+}
+
+func TestResolverOnResolved(t *testing.T) {
+	server := startStubDNS(t, stubNames, 0)
+	resolved := make(chan string, 1)
+	r := rdns.New(rdns.Options{
+		Server:     server.Addr(),
+		Timeout:    time.Second,
+		OnResolved: func(ip, hostname string) { resolved <- ip + "=" + hostname },
+	})
+	defer r.Close()
+
+	r.Lookup("203.0.113.5")
+	select {
+	case got := <-resolved:
+		if got != "203.0.113.5=scanner.example.net" {
+			t.Errorf("Expected 203.0.113.5=scanner.example.net, got %s", got)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("Expected OnResolved to be called")
+	}
+}
+
+func TestResolverTimeout(t *testing.T) {
+	server := startStubDNS(t, stubNames, time.Second)
+	r := rdns.New(rdns.Options{Server: server.Addr(), Timeout: time.Millisecond * 100})
+	defer r.Close()
+
+	start := time.Now()
+	if _, err := r.Resolve(context.Background(), "203.0.113.5"); err == nil {
+		t.Error("Expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("Expected the lookup to give up after the timeout, took %s", elapsed)
+	}
+}
+
+func TestCacheLRUAndTTL(t *testing.T) {
+	c := rdns.NewCache(2)
+	c.Put("203.0.113.1", "a.example", time.Hour)
+	c.Put("203.0.113.2", "b.example", time.Hour)
+
+	// Using the first entry makes the second the least recently used
+	c.Get("203.0.113.1")
+	c.Put("203.0.113.3", "c.example", time.Hour)
+
+	if _, ok := c.Get("203.0.113.2"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if hostname, ok := c.Get("203.0.113.1"); !ok || hostname != "a.example" {
+		t.Errorf("Expected a.example, got %q (%v)", hostname, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+
+	c.Put("203.0.113.4", "", time.Millisecond*20)
+	if _, ok := c.Get("203.0.113.4"); !ok {
+		t.Error("Expected a cached failed lookup")
+	}
+	time.Sleep(time.Millisecond * 40)
+	if _, ok := c.Get("203.0.113.4"); ok {
+		t.Error("Expected the entry to expire")
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/report"
+)
+
+func TestReportBuild(t *testing.T) {
+	end := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	start := end.Add(-time.Hour * 24)
+
+	events := []models.ScanEvent{
+		{ID: "old", SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: start.Add(-time.Hour), Severity: models.SeverityLow},
+		{ID: "1", SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: start.Add(time.Hour), Severity: models.SeverityHigh},
+		{ID: "2", SourceIP: "198.51.100.7", TargetPort: 22, Timestamp: start.Add(time.Hour * 2), Severity: models.SeverityLow},
+		{ID: "3", SourceIP: "198.51.100.7", TargetPort: 80, Timestamp: start.Add(time.Hour*2 + time.Minute), Severity: models.SeverityCritical},
+	}
+	known := map[string]bool{"203.0.113.5": true}
+
+	r := report.Build(events, known, start, end, time.Minute*5)
+
+	if r.TotalEvents != 3 {
+		t.Errorf("Expected 3 events in period, got %d", r.TotalEvents)
+	}
+	if r.Stats.TotalScans != 3 || r.Stats.UniqueIPs != 2 || r.Stats.ScansByPort[22] != 2 {
+		t.Errorf("Expected the statistics of the period, got %+v", r.Stats)
+	}
+	if len(r.NewSources) != 1 || r.NewSources[0] != "198.51.100.7" {
+		t.Errorf("Expected 198.51.100.7 as new source, got %v", r.NewSources)
+	}
+	if len(r.RepeatSources) != 1 || r.RepeatSources[0] != "203.0.113.5" {
+		t.Errorf("Expected 203.0.113.5 as repeat source, got %v", r.RepeatSources)
+	}
+	if len(r.TopPorts) == 0 || r.TopPorts[0].Key != "22" || r.TopPorts[0].Count != 2 {
+		t.Errorf("Expected port 22 as top port, got %+v", r.TopPorts)
+	}
+	if len(r.Incidents) != 2 {
+		t.Errorf("Expected 2 incidents, got %d", len(r.Incidents))
+	}
+	if len(r.Hourly) != 24 || r.Hourly[2].Count != 2 {
+		t.Errorf("Expected 24 hourly buckets with 2 events in the third, got %d buckets", len(r.Hourly))
+	}
+}
+
+func TestReportNextRun(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		interval time.Duration
+		expected time.Time
+	}{
+		{time.Hour * 24, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{time.Hour, time.Date(2024, 1, 2, 16, 0, 0, 0, time.UTC)},
+		{time.Hour * 6, time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		if next := report.NextRun(now, test.interval); !next.Equal(test.expected) {
+			t.Errorf("Expected the next %v report at %v, got %v", test.interval, test.expected, next)
+		}
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	end := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	events := []models.ScanEvent{
+		{ID: "1", SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: end.Add(-time.Hour), Severity: models.SeverityHigh},
+	}
+	r := report.Build(events, nil, end.Add(-time.Hour*24), end, time.Minute*5)
+
+	var md bytes.Buffer
+	if err := r.Write(&md, "md"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"# Port Scammer Report", "| Scan events | 1 |", "| 22 | 1 | 1 |", "## Hourly Activity"} {
+		if !strings.Contains(md.String(), expected) {
+			t.Errorf("Expected Markdown report to contain %q", expected)
+		}
+	}
+
+	var html bytes.Buffer
+	if err := r.Write(&html, "html"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(html.String(), "<svg") != 2 {
+		t.Error("Expected two inline SVG charts in HTML report")
+	}
+	for _, external := range []string{"<script src", "<link", "<img"} {
+		if strings.Contains(html.String(), external) {
+			t.Errorf("Expected HTML report without external assets, found %q", external)
+		}
+	}
+
+	if err := r.Write(&bytes.Buffer{}, "pdf"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
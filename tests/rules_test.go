@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/rules"
+)
+
+// ruleYAML wraps a single rule with the given condition and a tag action
+func ruleYAML(condition string) string {
+	return "rules:\n  - name: test\n    condition: '" + strings.ReplaceAll(condition, "'", "''") + "'\n    action:\n      tags: [hit]\n"
+}
+
+func TestRuleConditions(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	public := models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: at, Protocol: "tcp", Severity: models.SeverityMedium, Payload: "SSH-2.0-Go", Tags: []string{"firehol"}, Country: "DE"}
+	private := models.ScanEvent{SourceIP: "192.168.1.10", TargetPort: 3389, Timestamp: at, Protocol: "tcp", Payload: "GET / HTTP/1.1"}
+
+	tests := []struct {
+		condition string
+		event     models.ScanEvent
+		expected  bool
+	}{
+		{"port in [22,3389] and not private(src)", public, true},
+		{"port in [22,3389] and not private(src)", private, false},
+		{"payload matches /^SSH-/", public, true},
+		{"payload matches /^SSH-/", private, false},
+		{"port not in [80, 443]", public, true},
+		{"src in ['203.0.113.0/24', '198.51.100.7']", public, true},
+		{"src in ['203.0.113.0/24']", private, false},
+		{"severity >= medium", public, true},
+		{"severity >= high", public, false},
+		{"tags contains 'firehol'", public, true},
+		{"'firehol' in tags", private, false},
+		{"country == 'de' or country == 'cn'", public, true},
+		{"description contains 'nmap'", public, false},
+		{"(port == 22 or port == 23) and protocol == 'tcp'", public, true},
+		{"not (port == 22)", public, false},
+		{"events >= 1", public, true},
+	}
+
+	for _, test := range tests {
+		parsed, err := rules.Parse([]byte(ruleYAML(test.condition)))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.condition, err)
+			continue
+		}
+		result := rules.NewEngine(parsed).Evaluate(test.event)
+		if matched := len(result.Matched) == 1; matched != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.condition, test.expected, matched)
+		}
+	}
+}
+
+func TestRuleValidation(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected string
+	}{
+		{ruleYAML("prot == 22"), `unknown field "prot"`},
+		{ruleYAML("port == 'ssh'"), "cannot compare number with string"},
+		{ruleYAML("port > 10 within 60s"), "within needs an aggregate"},
+		{ruleYAML("payload matches /[/"), "invalid regular expression"},
+		{ruleYAML("protocol > 'tcp'"), "strings only support == and !="},
+		{ruleYAML("port in 22"), "in needs a list"},
+		{ruleYAML("src in ['not-an-ip']"), "invalid"},
+		{ruleYAML("port == 22 and"), "unexpected end of condition"},
+		{ruleYAML("protocol == 'tcp"), "unterminated string"},
+		{"rules:\n  - name: test\n    condition: port == 22\n", "missing action"},
+		{"rules:\n  - name: test\n    condition: port == 22\n    action: {severity: extreme}\n", `unknown severity "extreme"`},
+		{"rules:\n  - name: test\n    condition: port == 22\n    action: {alert: true}\n  - name: test\n    condition: port == 23\n    action: {alert: true}\n", "duplicate name"},
+		{"rules:\n  - name: test\n    when: port == 22\n", "field when not found"},
+	}
+
+	for _, test := range tests {
+		_, err := rules.Parse([]byte(test.yaml))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestRuleEngineAggregates(t *testing.T) {
+	parsed, err := rules.Parse([]byte(`
+rules:
+  - name: sweep
+    condition: distinct_ports > 3 within 60s
+    action:
+      severity: high
+      tags: [sweep]
+      alert: true
+  - name: ignore-monitoring
+    condition: src == '198.51.100.7'
+    action:
+      suppress: true
+  - name: block-persistent
+    condition: events >= 10 within 10m
+    action:
+      severity: critical
+      block: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := rules.NewEngine(parsed)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var result rules.Result
+	for i := 0; i < 4; i++ {
+		result = engine.Evaluate(models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 20 + i, Timestamp: at.Add(time.Duration(i) * time.Second)})
+	}
+	if !reflect.DeepEqual(result.Matched, []string{"sweep"}) || !result.Alert || result.Block {
+		t.Errorf("Expected the fourth port to match sweep, got %+v", result)
+	}
+
+	event := models.ScanEvent{SourceIP: "203.0.113.5", Severity: models.SeverityLow}
+	result.Apply(&event)
+	if event.Severity != models.SeverityHigh || !event.HasTag("sweep") {
+		t.Errorf("Expected high severity and sweep tag, got %s %v", event.Severity, event.Tags)
+	}
+
+	// The ports fall out of the window
+	result = engine.Evaluate(models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 30, Timestamp: at.Add(time.Minute * 2)})
+	if len(result.Matched) != 0 {
+		t.Errorf("Expected no match after the window, got %v", result.Matched)
+	}
+
+	// Sources are counted separately
+	result = engine.Evaluate(models.ScanEvent{SourceIP: "203.0.113.6", TargetPort: 31, Timestamp: at.Add(time.Minute * 2)})
+	if len(result.Matched) != 0 {
+		t.Errorf("Expected no match for another source, got %v", result.Matched)
+	}
+
+	for i := 0; i < 5; i++ {
+		result = engine.Evaluate(models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 22, Timestamp: at.Add(time.Minute*3 + time.Duration(i)*time.Second)})
+	}
+	if !reflect.DeepEqual(result.Matched, []string{"block-persistent"}) || !result.Block || *result.Severity != models.SeverityCritical {
+		t.Errorf("Expected the tenth event to match block-persistent, got %+v", result)
+	}
+
+	result = engine.Evaluate(models.ScanEvent{SourceIP: "198.51.100.7", TargetPort: 22, Timestamp: at})
+	if !result.Suppress {
+		t.Errorf("Expected the event to be suppressed, got %+v", result)
+	}
+}
+
+func TestRuleEngineSetRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	os.WriteFile(path, []byte(ruleYAML("port == 22")), 0644)
+
+	loaded, err := rules.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := rules.NewEngine(loaded)
+	event := models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 23, Timestamp: time.Now()}
+	if len(engine.Evaluate(event).Matched) != 0 {
+		t.Error("Expected no match on port 23")
+	}
+
+	os.WriteFile(path, []byte(ruleYAML("port == 23")), 0644)
+	if loaded, err = rules.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	engine.SetRules(loaded)
+	if len(engine.Evaluate(event).Matched) != 1 {
+		t.Error("Expected the reloaded rule to match port 23")
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonasbn.github.com/portscammer/internal/export"
+	"jonasbn.github.com/portscammer/internal/models"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares got with the golden file, rewriting it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Output does not match %s\ngot:\n%s\nexpected:\n%s", path, got, expected)
+	}
+}
+
+// siemEvents returns the export test events with characters needing escaping
+func siemEvents() []models.ScanEvent {
+	events := exportEvents()
+	events[1].Description = "probe|with=special\\chars\nand\ttabs"
+	return events
+}
+
+func TestSIEMFormatsGolden(t *testing.T) {
+	for _, format := range []string{"cef", "leef", "ecs", "ocsf", "jsonl", "fail2ban"} {
+		t.Run(format, func(t *testing.T) {
+			formatter, err := export.Get(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := formatter.FormatEvents(&buf, siemEvents()); err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+				t.Errorf("Expected one line per event, got %d lines", lines)
+			}
+			assertGolden(t, format+".golden", buf.Bytes())
+		})
+	}
+}
+
+func TestSIEMJSONFormatsAreValid(t *testing.T) {
+	for _, format := range []string{"ecs", "ocsf"} {
+		formatter, err := export.GetLine(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line, err := formatter.FormatEvent(siemEvents()[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Errorf("%s: invalid JSON: %v", format, err)
+		}
+	}
+
+	if _, err := export.GetLine("csv"); err == nil {
+		t.Error("Expected csv to be rejected as line format")
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/sigma"
+)
+
+// sigmaYAML builds a network_connection Sigma rule from a detection section
+func sigmaYAML(detection string) string {
+	return "title: Test\nid: 9b1c1f52-3bd6-4cbb-9b41-0e3a5d1c2d10\nlevel: high\nlogsource:\n  category: network_connection\ndetection:\n" + detection
+}
+
+func TestSigmaMatch(t *testing.T) {
+	ssh := models.ScanEvent{SourceIP: "203.0.113.5", SourcePort: 40000, TargetPort: 22, Protocol: "tcp", Payload: "SSH-2.0-libssh", Hostname: "scanner.example.net"}
+	rdp := models.ScanEvent{SourceIP: "192.168.1.10", SourcePort: 51000, TargetPort: 3389, Protocol: "TCP", Description: "Connection attempt"}
+
+	tests := []struct {
+		detection string
+		event     models.ScanEvent
+		expected  bool
+	}{
+		{"  selection:\n    DestinationPort: [22, 23]\n  condition: selection\n", ssh, true},
+		{"  selection:\n    DestinationPort: [22, 23]\n  condition: selection\n", rdp, false},
+		{"  selection:\n    dst_port: 3389\n    protocol: tcp\n  condition: selection\n", rdp, true},
+		{"  selection:\n    src_ip|cidr: 192.168.0.0/16\n  condition: selection\n", rdp, true},
+		{"  selection:\n    src_ip|cidr: 192.168.0.0/16\n  condition: selection\n", ssh, false},
+		{"  selection:\n    payload|startswith: ssh-2.0-\n  condition: selection\n", ssh, true},
+		{"  selection:\n    SourceHostname|endswith: .example.net\n  condition: selection\n", ssh, true},
+		{"  selection:\n    payload|contains|all: [libssh, SSH]\n  condition: selection\n", ssh, true},
+		{"  selection:\n    payload|re: '^SSH-[0-9.]+-Open'\n  condition: selection\n", ssh, false},
+		{"  selection:\n    SourcePort|gte: 50000\n  condition: selection\n", rdp, true},
+		{"  selection:\n    SourceHostname: 'scanner.*'\n  condition: selection\n", ssh, true},
+		{"  selection:\n    SourceHostname: null\n  condition: selection\n", rdp, true},
+		{"  keywords:\n    - attempt\n  condition: keywords\n", rdp, true},
+		{"  selection:\n    dst_port: [22, 3389]\n  filter:\n    src_ip|cidr: 192.168.0.0/16\n  condition: selection and not filter\n", ssh, true},
+		{"  selection:\n    dst_port: [22, 3389]\n  filter:\n    src_ip|cidr: 192.168.0.0/16\n  condition: selection and not filter\n", rdp, false},
+		{"  sel_ssh:\n    dst_port: 22\n  sel_rdp:\n    dst_port: 3389\n  condition: 1 of sel_*\n", rdp, true},
+		{"  sel_ssh:\n    dst_port: 22\n  sel_tcp:\n    protocol: tcp\n  condition: all of them\n", ssh, true},
+		{"  sel_ssh:\n    dst_port: 22\n  sel_tcp:\n    protocol: tcp\n  condition: all of them\n", rdp, false},
+		{"  selection:\n    - dst_port: 22\n    - src_port: 51000\n  condition: selection\n", rdp, true},
+		{"  a:\n    dst_port: 80\n  b:\n    dst_port: 22\n  c:\n    protocol: udp\n  condition: (a or b) and not c\n", ssh, true},
+	}
+
+	for _, test := range tests {
+		rule, err := sigma.Parse([]byte(sigmaYAML(test.detection)))
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.detection, err)
+			continue
+		}
+		if matched := rule.Match(test.event); matched != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.detection, test.expected, matched)
+		}
+	}
+}
+
+func TestSigmaValidation(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{"title: Test\nlogsource:\n  category: process_creation\ndetection:\n  selection:\n    Image: x\n  condition: selection\n", "unsupported logsource category"},
+		{sigmaYAML("  selection:\n    Image: x\n  condition: selection\n"), "unsupported field"},
+		{sigmaYAML("  selection:\n    dst_port|base64: x\n  condition: selection\n"), "unsupported modifier"},
+		{sigmaYAML("  selection:\n    dst_port: 22\n"), "missing detection condition"},
+		{sigmaYAML("  selection:\n    dst_port: 22\n  condition: []\n"), "empty detection condition"},
+		{sigmaYAML("  selection:\n    dst_port: 22\n  condition: selection | count() by src_ip > 10\n"), "aggregations are not supported"},
+		{sigmaYAML("  selection:\n    dst_port: 22\n  condition: selection and other\n"), "unknown search"},
+		{sigmaYAML("  selection:\n    dst_port: 22\n  condition: (selection\n"), "missing )"},
+		{strings.Replace(sigmaYAML("  selection:\n    dst_port: 22\n  condition: selection\n"), "level: high", "level: severe", 1), "unknown level"},
+	}
+
+	for _, test := range tests {
+		_, err := sigma.Parse([]byte(test.rule))
+		if err == nil {
+			t.Errorf("Expected error containing %q, got nil", test.expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestSigmaApply(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ssh.yml": sigmaYAML("  selection:\n    dst_port: 22\n  condition: selection\n"),
+		"telnet.yaml": "title: Telnet Scan\nlevel: critical\nlogsource:\n  category: firewall\ndetection:\n" +
+			"  selection:\n    dst_port: [22, 23]\n  condition: selection\n",
+		"README.md": "not a rule",
+		"process.yml": "title: Process\nlogsource:\n  category: process_creation\ndetection:\n" +
+			"  selection:\n    Image: nc\n  condition: selection\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The unsupported rule is skipped rather than failing the others
+	loaded, skipped, err := sigma.LoadPaths([]string{dir})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(loaded))
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "process.yml") {
+		t.Errorf("Expected process.yml to be skipped, got %v", skipped)
+	}
+	if _, _, err := sigma.LoadPaths([]string{filepath.Join(dir, "process.yml")}); err == nil {
+		t.Errorf("Expected an error when no rule loads")
+	}
+
+	event := models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 22, Severity: models.SeverityLow}
+	matched := sigma.Apply(loaded, &event)
+	if len(matched) != 2 {
+		t.Errorf("Expected 2 matched rules, got %d", len(matched))
+	}
+	expectedTags := []string{"sigma:9b1c1f52-3bd6-4cbb-9b41-0e3a5d1c2d10", "sigma:Telnet Scan"}
+	if !reflect.DeepEqual(event.Tags, expectedTags) {
+		t.Errorf("Expected tags %v, got %v", expectedTags, event.Tags)
+	}
+	if event.Severity != models.SeverityCritical {
+		t.Errorf("Expected %s, got %s", models.SeverityCritical, event.Severity)
+	}
+
+	event = models.ScanEvent{SourceIP: "203.0.113.5", TargetPort: 80}
+	if matched := sigma.Apply(loaded, &event); len(matched) != 0 || len(event.Tags) != 0 {
+		t.Errorf("Expected no matches, got %d", len(matched))
+	}
+}
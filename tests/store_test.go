@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/store"
+)
+
+// storeEvent returns an event from ip to port at the given time
+func storeEvent(id, ip string, port int, at time.Time) models.ScanEvent {
+	return models.ScanEvent{
+		ID:         id,
+		SourceIP:   ip,
+		TargetPort: port,
+		Timestamp:  at,
+		Protocol:   "tcp",
+		ScanType:   "port_scan",
+		Severity:   models.SeverityMedium,
+	}
+}
+
+func TestStorePersistsEvents(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	st, err := store.Open(store.Options{Dir: dir, SegmentSize: 512})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		ip := "203.0.113.5"
+		if i%2 == 1 {
+			ip = "198.51.100.7"
+		}
+		if err := st.Append(storeEvent(fmt.Sprint(i), ip, 20+i, base.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("Unexpected error appending: %v", err)
+		}
+	}
+	stats := models.ScanStats{TotalScans: 10, ScansByIP: map[string]int{"203.0.113.5": 5, "198.51.100.7": 5}}
+	if err := st.AppendStats(base.Add(time.Minute*10), stats); err != nil {
+		t.Fatalf("Unexpected error appending stats: %v", err)
+	}
+	st.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "events-*.log"))
+	if len(segments) < 2 {
+		t.Errorf("Expected events to be split over several segments, got %d", len(segments))
+	}
+
+	reopened, err := store.Open(store.Options{Dir: dir, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error reopening: %v", err)
+	}
+
+	all, err := reopened.Events(models.EventFilter{})
+	if err != nil || len(all) != 10 {
+		t.Fatalf("Expected 10 events, got %d (%v)", len(all), err)
+	}
+
+	byIP, _ := reopened.Events(models.EventFilter{Network: netip.MustParsePrefix("198.51.100.7/32")})
+	if len(byIP) != 5 || byIP[0].ID != "1" {
+		t.Errorf("Expected 5 events from 198.51.100.7 starting with 1, got %d", len(byIP))
+	}
+
+	recent, _ := reopened.Events(models.EventFilter{Since: base.Add(time.Minute * 8)})
+	if len(recent) != 2 {
+		t.Errorf("Expected 2 recent events, got %d", len(recent))
+	}
+
+	snapshot, ok := reopened.LatestStats()
+	if !ok || snapshot.Stats.TotalScans != 10 {
+		t.Errorf("Expected latest snapshot with 10 scans, got %+v", snapshot)
+	}
+
+	if err := reopened.Append(storeEvent("x", "10.0.0.1", 1, base)); err != store.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestStoreSeenBefore(t *testing.T) {
+	base := time.Now().Add(-time.Hour * 2).Truncate(time.Second)
+	st, err := store.Open(store.Options{Dir: t.TempDir(), SegmentSize: 256})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer st.Close()
+
+	st.Append(storeEvent("1", "203.0.113.5", 22, base))
+	st.Append(storeEvent("2", "198.51.100.7", 22, base.Add(time.Hour)))
+	st.Append(storeEvent("3", "203.0.113.5", 23, base.Add(time.Hour+time.Minute)))
+
+	seen, err := st.SeenBefore([]string{"203.0.113.5", "198.51.100.7", "192.0.2.1"}, base.Add(time.Minute*30))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !seen["203.0.113.5"] || seen["198.51.100.7"] || seen["192.0.2.1"] || len(seen) != 1 {
+		t.Errorf("Expected only 203.0.113.5 to be seen before, got %v", seen)
+	}
+}
+
+func TestStoreHostnames(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	st, err := store.Open(store.Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	st.Append(storeEvent("1", "203.0.113.5", 22, base))
+	named := storeEvent("2", "203.0.113.5", 23, base.Add(time.Minute))
+	named.Hostname = "old.example.net"
+	st.Append(named)
+	if err := st.SetHostname("203.0.113.5", "scanner.example.net", base.Add(time.Minute*2)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	st.Close()
+
+	// Hostname records survive a restart and only fill in missing hostnames
+	reopened, err := store.Open(store.Options{Dir: dir, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	events, _ := reopened.Events(models.EventFilter{})
+	if len(events) != 2 || events[0].Hostname != "scanner.example.net" || events[1].Hostname != "old.example.net" {
+		t.Errorf("Expected the stored hostname on the first event only, got %+v", events)
+	}
+}
+
+func TestStoreAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	st, err := store.Open(store.Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		st.Append(storeEvent(fmt.Sprint(i), "203.0.113.5", 20+i, base.Add(time.Duration(i)*time.Minute)))
+	}
+
+	yes, no, note := true, false, "known scanner"
+	annotations := []models.Annotation{
+		{EventIDs: []string{"0", "1"}, Acknowledged: &yes},
+		{EventIDs: []string{"1"}, Note: &note},
+		{EventIDs: []string{"0"}, Acknowledged: &no},
+	}
+	for _, annotation := range annotations {
+		if err := st.Annotate(annotation); err != nil {
+			t.Fatalf("Unexpected error annotating: %v", err)
+		}
+	}
+	st.Close()
+
+	reopened, err := store.Open(store.Options{Dir: dir, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error reopening: %v", err)
+	}
+	for _, filter := range []models.EventFilter{{}, {Network: netip.MustParsePrefix("203.0.113.5/32")}} {
+		events, err := reopened.Events(filter)
+		if err != nil || len(events) != 3 {
+			t.Fatalf("Expected 3 events, got %d (%v)", len(events), err)
+		}
+		if events[0].Acknowledged || events[0].Note != "" {
+			t.Errorf("Expected event 0 unacknowledged without note, got %+v", events[0])
+		}
+		if !events[1].Acknowledged || events[1].Note != note {
+			t.Errorf("Expected event 1 acknowledged with note, got %+v", events[1])
+		}
+		if events[2].Acknowledged {
+			t.Errorf("Expected event 2 unacknowledged, got %+v", events[2])
+		}
+	}
+}
+
+func TestStoreIgnoresPartialLine(t *testing.T) {
+	dir := t.TempDir()
+
+	st, err := store.Open(store.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Append(storeEvent("1", "203.0.113.5", 22, time.Now()))
+	st.Close()
+
+	// Simulate a write in progress by another process
+	segments, _ := filepath.Glob(filepath.Join(dir, "events-*.log"))
+	file, _ := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"kind":"event","time":`)
+	file.Close()
+
+	reader, err := store.Open(store.Options{Dir: dir, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if events, _ := reader.Events(models.EventFilter{}); len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(events))
+	}
+
+	// A writer reopening the store drops the partial line before appending
+	writer, err := store.Open(store.Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Append(storeEvent("2", "203.0.113.5", 23, time.Now()))
+	writer.Close()
+
+	if err := reader.Refresh(); err != nil {
+		t.Fatalf("Unexpected error refreshing: %v", err)
+	}
+	if events, _ := reader.Events(models.EventFilter{}); len(events) != 2 {
+		t.Errorf("Expected 2 events after refresh, got %d", len(events))
+	}
+}
+
+func TestStoreRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	st, err := store.Open(store.Options{Dir: dir, SegmentSize: 256, MaxAge: time.Hour * 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	for i := 0; i < 6; i++ {
+		st.Append(storeEvent(fmt.Sprint(i), "203.0.113.5", 22, now.Add(-time.Hour*48)))
+	}
+	st.Append(storeEvent("new", "203.0.113.5", 22, now))
+	yes := true
+	st.Annotate(models.Annotation{EventIDs: []string{"0"}, Acknowledged: &yes})
+
+	if err := st.ApplyRetention(now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events, _ := st.Events(models.EventFilter{Network: netip.MustParsePrefix("203.0.113.5/32")})
+	if len(events) != 1 || events[0].ID != "new" {
+		t.Errorf("Expected only the new event to be retained, got %d events", len(events))
+	}
+
+	// The acknowledgement of the removed event is forgotten with it, so an
+	// event stored later under the same ID doesn't inherit it
+	st.Append(storeEvent("0", "203.0.113.5", 22, now))
+	events, _ = st.Events(models.EventFilter{})
+	if len(events) != 2 || events[1].Acknowledged {
+		t.Errorf("Expected the acknowledgement of the removed event dropped, got %+v", events)
+	}
+}
+
+func TestStoreSizeRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	st, err := store.Open(store.Options{Dir: dir, SegmentSize: 256, MaxSize: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	for i := 0; i < 20; i++ {
+		st.Append(storeEvent(fmt.Sprint(i), "203.0.113.5", 22, now))
+	}
+	if err := st.ApplyRetention(now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if size := st.Size(); size > 600 {
+		t.Errorf("Expected store size within 600 bytes, got %d", size)
+	}
+	events, _ := st.Events(models.EventFilter{})
+	if len(events) == 0 || events[len(events)-1].ID != "19" {
+		t.Error("Expected the newest event to be retained")
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/api"
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/stream"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamEvent returns an event with the given ID, port and severity
+func streamEvent(id string, port int, severity models.Severity) models.ScanEvent {
+	return models.ScanEvent{
+		ID:         id,
+		SourceIP:   "203.0.113.5",
+		TargetPort: port,
+		Timestamp:  time.Now(),
+		ScanType:   "port_scan",
+		Severity:   severity,
+	}
+}
+
+// receiveIDs reads n events from the subscription and returns their IDs
+func receiveIDs(t *testing.T, sub *stream.Subscription, n int) []string {
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case event := <-sub.C:
+			ids = append(ids, event.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event %d of %d", i+1, n)
+		}
+	}
+	return ids
+}
+
+func TestBrokerFilter(t *testing.T) {
+	broker := stream.NewBroker(10, stream.DropOldest)
+	sub := broker.Subscribe(models.EventFilter{Port: 22, MinSeverity: models.SeverityHigh}, 10, "")
+	defer sub.Close()
+
+	broker.Publish(streamEvent("1", 22, models.SeverityLow))
+	broker.Publish(streamEvent("2", 80, models.SeverityHigh))
+	broker.Publish(streamEvent("3", 22, models.SeverityCritical))
+
+	if ids := receiveIDs(t, sub, 1); ids[0] != "3" {
+		t.Errorf("Expected event 3, got %v", ids)
+	}
+	if len(sub.C) != 0 {
+		t.Errorf("Expected no further events, got %d", len(sub.C))
+	}
+}
+
+func TestBrokerDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy   stream.DropPolicy
+		expected string
+	}{
+		{stream.DropOldest, "3,4"},
+		{stream.DropNewest, "1,2"},
+	}
+
+	for _, test := range tests {
+		broker := stream.NewBroker(10, test.policy)
+		sub := broker.Subscribe(models.EventFilter{}, 2, "")
+
+		for _, id := range []string{"1", "2", "3", "4"} {
+			broker.Publish(streamEvent(id, 22, models.SeverityMedium))
+		}
+
+		if ids := strings.Join(receiveIDs(t, sub, 2), ","); ids != test.expected {
+			t.Errorf("Policy %d: expected %s, got %s", test.policy, test.expected, ids)
+		}
+		if sub.Dropped() != 2 {
+			t.Errorf("Policy %d: expected 2 dropped events, got %d", test.policy, sub.Dropped())
+		}
+		sub.Close()
+	}
+}
+
+func TestBrokerResume(t *testing.T) {
+	broker := stream.NewBroker(3, stream.DropOldest)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		broker.Publish(streamEvent(id, 22, models.SeverityMedium))
+	}
+
+	sub := broker.Subscribe(models.EventFilter{}, 10, "3")
+	if ids := strings.Join(receiveIDs(t, sub, 1), ","); ids != "4" {
+		t.Errorf("Expected replay of 4, got %s", ids)
+	}
+	sub.Close()
+
+	// Event 1 is no longer retained, so everything retained is replayed
+	sub = broker.Subscribe(models.EventFilter{}, 10, "1")
+	if ids := strings.Join(receiveIDs(t, sub, 3), ","); ids != "2,3,4" {
+		t.Errorf("Expected replay of 2,3,4, got %s", ids)
+	}
+	sub.Close()
+
+	if broker.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers, got %d", broker.Subscribers())
+	}
+}
+
+func TestAPIServerSentEvents(t *testing.T) {
+	broker := stream.NewBroker(10, stream.DropOldest)
+	broker.Publish(streamEvent("1", 22, models.SeverityHigh))
+	server := api.NewServer(&fakeSource{}, api.Options{Broker: broker})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/events/stream?port=22", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "0")
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	for broker.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	broker.Publish(streamEvent("2", 80, models.SeverityHigh))
+	broker.Publish(streamEvent("3", 22, models.SeverityLow))
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+	if !strings.Contains(body, "id: 1\nevent: scan\ndata: {") || !strings.Contains(body, "id: 3\n") {
+		t.Errorf("Expected events 1 and 3 in stream, got %q", body)
+	}
+	if strings.Contains(body, "id: 2\n") {
+		t.Errorf("Expected event 2 to be filtered out, got %q", body)
+	}
+}
+
+func TestAPIWebSocket(t *testing.T) {
+	broker := stream.NewBroker(10, stream.DropOldest)
+	server := httptest.NewServer(api.NewServer(&fakeSource{}, api.Options{Broker: broker, Token: "secret"}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws?severity=critical&access_token=secret"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	for broker.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	broker.Publish(streamEvent("1", 22, models.SeverityHigh))
+	broker.Publish(streamEvent("2", 22, models.SeverityCritical))
+
+	var event models.ScanEvent
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	if event.ID != "2" {
+		t.Errorf("Expected event 2, got %s", event.ID)
+	}
+}
@@ -0,0 +1,690 @@
+package tests
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"jonasbn.github.com/portscammer/internal/models"
+	"jonasbn.github.com/portscammer/internal/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// fakeActions records the actions requested from the TUI
+type fakeActions struct {
+	calls []string
+}
+
+func (a *fakeActions) Whitelist(ip string) error {
+	a.calls = append(a.calls, "whitelist "+ip)
+	return nil
+}
+
+func (a *fakeActions) Blacklist(ip string) error {
+	a.calls = append(a.calls, "blacklist "+ip)
+	return nil
+}
+
+func (a *fakeActions) Block(ip string) error {
+	a.calls = append(a.calls, "block "+ip)
+	return nil
+}
+
+// uiEvents returns events from two sources, the last one with a payload
+func uiEvents() []models.ScanEvent {
+	at := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	return []models.ScanEvent{
+		{ID: "e1", SourceIP: "203.0.113.5", TargetPort: 22, Protocol: "tcp", ScanType: "connect", Timestamp: at},
+		{ID: "e2", SourceIP: "198.51.100.7", TargetPort: 80, Protocol: "tcp", ScanType: "connect", Timestamp: at.Add(time.Second)},
+		{ID: "e3", SourceIP: "203.0.113.5", TargetPort: 3389, Protocol: "tcp", ScanType: "connect", Timestamp: at.Add(time.Minute),
+			Description: "A description that is much longer than the thirty characters of the table column",
+			Payload:     "SSH-2.0-Go\r\n", Hostname: "scanner.example.net", Country: "DE", Severity: models.SeverityHigh},
+	}
+}
+
+// runes returns the key presses typing s
+func runes(s string) []tea.KeyMsg {
+	keys := make([]tea.KeyMsg, 0, len(s))
+	for _, r := range s {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+// sendKeys feeds key presses to the model
+func sendKeys(m tea.Model, keys ...tea.KeyMsg) tea.Model {
+	for _, key := range keys {
+		m, _ = m.Update(key)
+	}
+	return m
+}
+
+func TestUIDetailView(t *testing.T) {
+	actions := &fakeActions{}
+	var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false).WithActions(actions)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+
+	// The newest event, with the payload, is the first row
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	view := m.View()
+
+	for _, expected := range []string{
+		"Details for 203.0.113.5",
+		"much longer than the thirty characters of the table column",
+		"scanner.example.net",
+		"Source History (2 events)",
+		"22, 3389",
+		"Incidents (1)",
+		"53 53 48 2d 32 2e 30 2d",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected detail view to contain %q", expected)
+		}
+	}
+
+	m = sendKeys(m,
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	expected := []string{"whitelist 203.0.113.5", "blacklist 203.0.113.5", "block 203.0.113.5"}
+	if strings.Join(actions.calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, actions.calls)
+	}
+	if !strings.Contains(m.View(), "Blocked 203.0.113.5") {
+		t.Error("Expected the status of the last action")
+	}
+
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if strings.Contains(m.View(), "Details for") {
+		t.Error("Expected the detail view to be closed")
+	}
+}
+
+func TestUIFilter(t *testing.T) {
+	var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	tests := []struct {
+		keys     []tea.KeyMsg
+		header   string
+		included []string
+		excluded []string
+	}{
+		{append(runes("/port:22 203.0.113.0/24"), tea.KeyMsg{Type: tea.KeyEnter}), "Filter: port:22 ip:203.0.113.0/24 (1 of 3 events)", []string{"203.0.113.5"}, []string{"198.51.100.7", "3389"}},
+		{runes("c"), "", []string{"203.0.113.5", "198.51.100.7"}, nil},
+		{append(runes("/scanner.example"), tea.KeyMsg{Type: tea.KeyEnter}), "Filter: scanner.example (1 of 3 events)", []string{"3389"}, []string{"198.51.100.7"}},
+		{runes("c" + "ss"), "Filter: severity:high (1 of 3 events)", []string{"3389"}, []string{"198.51.100.7"}},
+		{runes("c" + "t"), "Filter: proto:tcp (3 of 3 events)", []string{"198.51.100.7"}, nil},
+		// Quick port and IP filters use the selected (newest) event
+		{runes("c" + "oi"), "Filter: port:3389 ip:203.0.113.5 (1 of 3 events)", []string{"3389"}, []string{"198.51.100.7"}},
+		// A cancelled search keeps the previous filter
+		{append(runes("c/port:80"), tea.KeyMsg{Type: tea.KeyEsc}), "", []string{"198.51.100.7", "3389"}, nil},
+	}
+
+	for _, test := range tests {
+		m = sendKeys(m, test.keys...)
+		view := m.View()
+		if test.header != "" && !strings.Contains(view, test.header) {
+			t.Errorf("Expected header %q in view", test.header)
+		}
+		if test.header == "" && strings.Contains(view, "Filter:") {
+			t.Error("Expected no filter in view")
+		}
+		for _, s := range test.included {
+			if !strings.Contains(view, s) {
+				t.Errorf("%s: expected %q in view", test.header, s)
+			}
+		}
+		for _, s := range test.excluded {
+			if strings.Contains(view, s) {
+				t.Errorf("%s: expected no %q in view", test.header, s)
+			}
+		}
+	}
+}
+
+func TestUIColumns(t *testing.T) {
+	if err := ui.CheckColumns([]string{"time", "tool", "incident"}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	for _, names := range [][]string{{"time", "nope"}, {"port", "port"}, nil} {
+		if err := ui.CheckColumns(names); err == nil {
+			t.Errorf("Expected error for %v", names)
+		}
+	}
+
+	events := uiEvents()
+	events[1].UserAgent = "Mozilla/5.0 zgrab/0.x"
+	var m tea.Model = ui.NewModel(&fakeSource{events: events}, false).
+		WithColumns([]string{"port", "source_port", "protocol", "source", "tool", "incident"})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	view := m.View()
+	for _, expected := range []string{"Port", "Src Port", "Proto", "Source IP", "Tool", "Incident", "zgrab", "203.0.113.5@20240102T100000Z"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in view", expected)
+		}
+	}
+	if strings.Contains(view, "Description") {
+		t.Error("Expected no Description column")
+	}
+
+	// The first key sorts on the port column ascending, again descending
+	order := func(view string) bool {
+		return strings.Index(view, " 22 ") < strings.Index(view, " 3389 ")
+	}
+	m = sendKeys(m, runes("1")...)
+	if view := m.View(); !strings.Contains(view, "Port ▲") || !order(view) {
+		t.Error("Expected rows sorted on port ascending")
+	}
+	m = sendKeys(m, runes("1")...)
+	if view := m.View(); !strings.Contains(view, "Port ▼") || order(view) {
+		t.Error("Expected rows sorted on port descending")
+	}
+
+	// Every column is reached by cycling the sort column, wrapping around
+	m = sendKeys(m, runes(">>>")...)
+	if view := m.View(); !strings.Contains(view, "Source IP ▲") {
+		t.Error("Expected rows sorted on the source column")
+	}
+	m = sendKeys(m, runes("-")...)
+	if view := m.View(); !strings.Contains(view, "Source IP ▼") {
+		t.Error("Expected the sort order reversed")
+	}
+	m = sendKeys(m, runes(">>>")...)
+	if view := m.View(); !strings.Contains(view, "Port ▲") || !order(view) {
+		t.Error("Expected the sort column to wrap around to port")
+	}
+}
+
+// statsSource serves events with statistics counted per source and port
+type statsSource struct {
+	fakeSource
+}
+
+func (s *statsSource) GetStats() models.ScanStats {
+	stats := models.ScanStats{TotalScans: len(s.events), ScansByIP: map[string]int{}, ScansByPort: map[int]int{}}
+	for _, event := range s.events {
+		stats.ScansByIP[event.SourceIP]++
+		stats.ScansByPort[event.TargetPort]++
+	}
+	stats.UniqueIPs = len(stats.ScansByIP)
+	return stats
+}
+
+// fakeLists is an in-memory allow and deny list
+type fakeLists map[string][]string
+
+func (l fakeLists) Entries(list string) []string {
+	return l[list]
+}
+
+func (l fakeLists) Add(list, entry string) error {
+	l[list] = append(l[list], entry)
+	return nil
+}
+
+func (l fakeLists) Remove(list, entry string) error {
+	for i, e := range l[list] {
+		if e == entry {
+			l[list] = append(l[list][:i], l[list][i+1:]...)
+		}
+	}
+	return nil
+}
+
+func TestUITabs(t *testing.T) {
+	lists := fakeLists{ui.ListAllow: {"192.0.2.1"}}
+	source := &statsSource{fakeSource{events: uiEvents()}}
+	var m tea.Model = ui.NewModel(source, false).WithLists(lists)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	tests := []struct {
+		name     string
+		keys     []tea.KeyMsg
+		included []string
+	}{
+		{"sources", []tea.KeyMsg{tab}, []string{"Top Sources (2)", "Events ▼", "203.0.113.5", "scanner.example.net", "1-6 sort"}},
+		{"ports", []tea.KeyMsg{tab}, []string{"Port Heatmap", "3 ports, 3 events, ordered by port", "3389"}},
+		{"ports by count", runes("s"), []string{"ordered by count"}},
+		{"incidents", []tea.KeyMsg{tab}, []string{"Incidents (2)", "198.51.100.7", "22, 3389"}},
+		{"allow list", []tea.KeyMsg{tab}, []string{"Allow list (1)", "Deny list (0)", "192.0.2.1"}},
+		{"deny list add", append([]tea.KeyMsg{{Type: tea.KeyRight}, runes("n")[0]}, append(runes("198.51.100.0/24"), tea.KeyMsg{Type: tea.KeyEnter})...),
+			[]string{"Deny list (1)", "Added 198.51.100.0/24 to the deny list"}},
+		{"deny list remove", runes("x"), []string{"Deny list (0)", "Removed 198.51.100.0/24 from the deny list"}},
+		{"events", []tea.KeyMsg{tab}, []string{"Recent Scan Events", "/ search"}},
+	}
+
+	for _, test := range tests {
+		m = sendKeys(m, test.keys...)
+		view := m.View()
+		for _, expected := range test.included {
+			if !strings.Contains(view, expected) {
+				t.Errorf("%s: expected %q in view", test.name, expected)
+			}
+		}
+	}
+
+	// The sources rank by events, so the first source is the busiest
+	m = sendKeys(m, tab)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "Details for 203.0.113.5") {
+		t.Error("Expected the detail view of the busiest source")
+	}
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if !strings.Contains(m.View(), "Top Sources") {
+		t.Error("Expected to return to the Sources tab")
+	}
+}
+
+func TestUICharts(t *testing.T) {
+	events := uiEvents()
+	now := time.Now()
+	for i := range events {
+		events[i].Timestamp = now.Add(-time.Duration(i) * time.Minute)
+	}
+	var m tea.Model = ui.NewModel(&statsSource{fakeSource{events: events}}, false)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	view := m.View()
+	for _, expected := range []string{
+		"Events/min", "3 in the last hour, peak 1/min", "▁█",
+		"Severity", "low 2 medium 0 high 1 critical 0", "████",
+		"Ports", "0-65535",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in view", expected)
+		}
+	}
+}
+
+// program runs the commands of a model and feeds their messages back to it,
+// like the bubbletea runtime without a terminal
+type program struct {
+	model tea.Model
+	msgs  chan tea.Msg
+}
+
+func (p *program) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		if msg := cmd(); msg != nil {
+			p.msgs <- msg
+		}
+	}()
+}
+
+// settle handles messages until none arrive for a while
+func (p *program) settle() {
+	for {
+		select {
+		case msg := <-p.msgs:
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				for _, cmd := range batch {
+					p.run(cmd)
+				}
+				continue
+			}
+			var cmd tea.Cmd
+			p.model, cmd = p.model.Update(msg)
+			p.run(cmd)
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestUIPushedEvents(t *testing.T) {
+	tests := []struct {
+		refreshRate time.Duration
+		shown       bool
+	}{
+		{time.Millisecond, true},
+		{time.Hour, false},
+	}
+
+	for _, test := range tests {
+		events := uiEvents()
+		source := &fakeSource{events: events[:2]}
+		ch := make(chan models.ScanEvent, 10)
+		// The first pushed event is also loaded from the source
+		ch <- events[1]
+
+		m := ui.NewModel(source, false).WithEvents(ch).WithRefreshRate(test.refreshRate)
+		p := &program{model: m, msgs: make(chan tea.Msg, 100)}
+		p.model, _ = p.model.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+		p.run(p.model.Init())
+		p.settle()
+
+		p.model = sendKeys(p.model, runes("t")...)
+		if view := p.model.View(); !strings.Contains(view, "(2 of 2 events)") {
+			t.Errorf("Expected the loaded events once with refresh rate %s", test.refreshRate)
+		}
+
+		pushed := models.ScanEvent{ID: "e4", SourceIP: "192.0.2.44", TargetPort: 25, Protocol: "tcp", Timestamp: time.Now()}
+		source.events = append(source.events, pushed)
+		ch <- pushed
+		p.settle()
+
+		if shown := strings.Contains(p.model.View(), "192.0.2.44"); shown != test.shown {
+			t.Errorf("Expected pushed event shown %v with refresh rate %s, got %v", test.shown, test.refreshRate, shown)
+		}
+		close(ch)
+		p.settle()
+	}
+}
+
+func TestUIKeyMap(t *testing.T) {
+	for _, specs := range [][]string{{"nope=x"}, {"quit"}, {"quit= ,"}} {
+		if _, err := ui.ParseKeyMap(specs); err == nil {
+			t.Errorf("Expected error for %v", specs)
+		}
+	}
+
+	keys, err := ui.ParseKeyMap([]string{"search=f", "help=h,?"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if help := keys.Search.Help(); help.Key != "f" || help.Desc != "search" {
+		t.Errorf("Expected f search, got %s %s", help.Key, help.Desc)
+	}
+
+	var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false).WithKeyMap(keys)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	if view := m.View(); !strings.Contains(view, "f search") || strings.Contains(view, "/ search") {
+		t.Error("Expected the remapped search key in the footer")
+	}
+	m = sendKeys(m, runes("f")...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	m = sendKeys(m, runes("h")...)
+	view := m.View()
+	for _, expected := range []string{"Keys", "previous view", "go to end", "Press h/? to close"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in the help overlay", expected)
+		}
+	}
+	if strings.Contains(view, "Recent Scan Events") {
+		t.Error("Expected the help overlay to replace the events")
+	}
+	m = sendKeys(m, runes("?")...)
+	if !strings.Contains(m.View(), "Recent Scan Events") {
+		t.Error("Expected the help overlay to be closed")
+	}
+}
+
+func TestUITheme(t *testing.T) {
+	for _, name := range []string{"dark", "light", "high-contrast"} {
+		if _, err := ui.LookupTheme(name); err != nil {
+			t.Errorf("Unexpected error %v for %s", err, name)
+		}
+	}
+	if _, err := ui.LookupTheme("neon"); err == nil {
+		t.Error("Expected error for unknown theme")
+	}
+
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+
+	view := func() string {
+		theme, _ := ui.LookupTheme("dark")
+		var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false).WithTheme(theme)
+		m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+		m = sendKeys(m, runes("r")...)
+		// Select the oldest event, so the high severity row is not selected
+		return sendKeys(m, runes("G")...).View()
+	}
+
+	// The row of the high severity event is colored by its severity
+	rows := 0
+	for _, line := range strings.Split(view(), "\n") {
+		if strings.Contains(line, "2024-01-02 10:01:00") && !strings.Contains(line, "38;5;208") {
+			t.Errorf("Expected the high severity row in the high color, got %q", line)
+		}
+		if strings.Contains(line, "2024-01-02 10:01:00") {
+			rows++
+		}
+	}
+	if rows != 1 {
+		t.Errorf("Expected 1 high severity row, got %d", rows)
+	}
+
+	// Rows that read the same are still colored by their own severities
+	theme, _ := ui.LookupTheme("dark")
+	events := []models.ScanEvent{
+		{ID: "1", SourceIP: "192.0.2.10", TargetPort: 23, Severity: models.SeverityLow, Timestamp: time.Now()},
+		{ID: "2", SourceIP: "192.0.2.10", TargetPort: 23, Severity: models.SeverityHigh, Timestamp: time.Now()},
+		{ID: "3", SourceIP: "192.0.2.10", TargetPort: 23, Severity: models.SeverityLow, Timestamp: time.Now()},
+	}
+	var m tea.Model = ui.NewModel(&fakeSource{events: events}, false).WithTheme(theme).WithColumns([]string{"source", "port"})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+	high := 0
+	for _, line := range strings.Split(m.View(), "\n") {
+		if strings.Contains(line, "192.0.2.10") && strings.Contains(line, "38;5;208") {
+			high++
+		}
+	}
+	if high != 1 {
+		t.Errorf("Expected 1 row in the high color, got %d", high)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if colored := view(); strings.Contains(colored, "38;5;") || strings.Contains(colored, "48;5;") {
+		t.Error("Expected no colors with NO_COLOR set")
+	}
+}
+
+func TestUITableScroll(t *testing.T) {
+	var events []models.ScanEvent
+	start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		events = append(events, models.ScanEvent{ID: strconv.Itoa(i), SourceIP: "192.0.2.10", TargetPort: 1000 + i, Timestamp: start.Add(time.Duration(i) * time.Second)})
+	}
+	var m tea.Model = ui.NewModel(&fakeSource{events: events}, false).WithColumns([]string{"port", "source"})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 30})
+	m = sendKeys(m, runes("r")...)
+
+	// The activity log shows the ports too, so rows are matched with the source
+	shown := func(view string, port int) bool {
+		return strings.Contains(view, fmt.Sprintf(" %d    192.0.2.10", port))
+	}
+
+	// The newest event is first, the table scrolls with the cursor
+	if view := m.View(); !shown(view, 1049) || shown(view, 1000) {
+		t.Error("Expected the newest events shown")
+	}
+	m = sendKeys(m, runes("G")...)
+	if view := m.View(); shown(view, 1049) || !shown(view, 1000) {
+		t.Error("Expected the oldest events shown at the bottom")
+	}
+	m = sendKeys(m, runes("g")...)
+	for i := 0; i < 20; i++ {
+		m = sendKeys(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if view := m.View(); shown(view, 1049) || !shown(view, 1029) {
+		t.Error("Expected the table to scroll down with the cursor")
+	}
+}
+
+func TestUIAnnotations(t *testing.T) {
+	var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("ra")...)
+	if !strings.Contains(m.View(), "Annotations are not available") {
+		t.Error("Expected annotations to be unavailable without an annotator")
+	}
+
+	source := &annotatingSource{fakeSource: fakeSource{events: uiEvents()}}
+	m = ui.NewModel(source, false)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	// The newest event is selected
+	m = sendKeys(m, runes("a")...)
+	if view := m.View(); !strings.Contains(view, "Acknowledged 1 event") || !strings.Contains(view, "✓") {
+		t.Error("Expected the selected event to be acknowledged")
+	}
+	m = sendKeys(m, runes("n")...)
+	m = sendKeys(m, runes("bad actor")...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "Saved the note of 1 event") {
+		t.Error("Expected the note to be saved")
+	}
+	if e3 := source.events[2]; !e3.Acknowledged || e3.Note != "bad actor" {
+		t.Errorf("Expected e3 acknowledged with a note, got %+v", e3)
+	}
+
+	m = sendKeys(m, runes("/")...)
+	m = sendKeys(m, runes("ack:no")...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "Filter: ack:no (2 of 3 events)") {
+		t.Error("Expected the ack filter to select the unacknowledged events")
+	}
+	m = sendKeys(m, runes("c")...)
+
+	// The incident of 203.0.113.5 is second, with one of its two events acknowledged
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab})
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyDown})
+	if !strings.Contains(m.View(), "1/2") {
+		t.Error("Expected the incident to be partly acknowledged")
+	}
+	m = sendKeys(m, runes("a")...)
+	if !strings.Contains(m.View(), "Acknowledged 2 events") || !source.events[0].Acknowledged {
+		t.Error("Expected all events of the incident to be acknowledged")
+	}
+}
+
+// resolvingSource is a fakeSource with hostnames resolved after the events
+type resolvingSource struct {
+	fakeSource
+	hostnames map[string]string
+}
+
+func (r *resolvingSource) Hostname(ip string) (string, bool) {
+	hostname, ok := r.hostnames[ip]
+	return hostname, ok
+}
+
+func TestUIPushedHostnames(t *testing.T) {
+	source := &resolvingSource{hostnames: make(map[string]string)}
+	ch := make(chan models.ScanEvent, 10)
+
+	m := ui.NewModel(source, false).WithEvents(ch).WithRefreshRate(time.Millisecond)
+	p := &program{model: m, msgs: make(chan tea.Msg, 100)}
+	p.model, _ = p.model.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	p.run(p.model.Init())
+	p.settle()
+
+	ch <- models.ScanEvent{ID: "e1", SourceIP: "192.0.2.44", TargetPort: 25, Protocol: "tcp", Timestamp: time.Now()}
+	p.settle()
+
+	// The hostname resolved after the event was pushed shows on the next rebuild
+	source.hostnames["192.0.2.44"] = "mail.example.org"
+	ch <- models.ScanEvent{ID: "e2", SourceIP: "198.51.100.7", TargetPort: 80, Protocol: "tcp", Timestamp: time.Now()}
+	p.settle()
+
+	p.model = sendKeys(p.model, tea.KeyMsg{Type: tea.KeyTab})
+	if !strings.Contains(p.model.View(), "mail.example.org") {
+		t.Error("Expected the resolved hostname in the Sources tab")
+	}
+	close(ch)
+	p.settle()
+}
+
+func TestUIPause(t *testing.T) {
+	events := uiEvents()
+	source := &fakeSource{events: events[:2]}
+	ch := make(chan models.ScanEvent, 10)
+
+	m := ui.NewModel(source, false).WithEvents(ch).WithRefreshRate(time.Millisecond)
+	p := &program{model: m, msgs: make(chan tea.Msg, 100)}
+	p.model, _ = p.model.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	p.run(p.model.Init())
+	p.settle()
+
+	p.model = sendKeys(p.model, runes("p")...)
+	ch <- events[2]
+	p.settle()
+
+	view := p.model.View()
+	if !strings.Contains(view, "PAUSED, 1 unseen") || !strings.Contains(view, "p resume") {
+		t.Error("Expected the paused view to count the unseen event")
+	}
+	if strings.Contains(view, "3389") {
+		t.Error("Expected the paused view not to show the pushed event")
+	}
+
+	p.model = sendKeys(p.model, runes("p")...)
+	if view := p.model.View(); strings.Contains(view, "PAUSED") || !strings.Contains(view, "3389") {
+		t.Error("Expected the resumed view to show the pushed event")
+	}
+	close(ch)
+	p.settle()
+}
+
+func TestUIExport(t *testing.T) {
+	dir := t.TempDir()
+	var m tea.Model = ui.NewModel(&fakeSource{events: uiEvents()}, false).WithExportDir(dir)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 60})
+	m = sendKeys(m, runes("r")...)
+
+	m = sendKeys(m, runes("/")...)
+	m = sendKeys(m, runes("ip:203.0.113.5")...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	m = sendKeys(m, runes("e")...)
+	if view := m.View(); !strings.Contains(view, "Export to: "+dir) || !strings.Contains(view, ".json") {
+		t.Error("Expected the export prompt with a JSON file in the export directory")
+	}
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyTab})
+	if !strings.Contains(m.View(), ".csv") {
+		t.Error("Expected tab to switch the export to CSV")
+	}
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "Exported 2 events to "+dir) {
+		t.Error("Expected the export to be confirmed")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "portscammer-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one CSV file, got %v", files)
+	}
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(records) != 3 || records[1][1] != "203.0.113.5" {
+		t.Errorf("Expected the 2 filtered events in the CSV file, got %v (%v)", records, err)
+	}
+
+	// An unknown extension is reported in the status bar
+	m = sendKeys(m, runes("e")...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = sendKeys(m, runes(filepath.Join(dir, "events.txt"))...)
+	m = sendKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), `Failed: unknown extension ".txt"`) {
+		t.Error("Expected an unknown extension to fail")
+	}
+}
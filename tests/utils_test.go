@@ -128,3 +128,50 @@ func TestTruncateString(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{"90s", time.Second * 90, false},
+		{"24h", time.Hour * 24, false},
+		{"7d", time.Hour * 24 * 7, false},
+		{"xd", 0, true},
+		{"bogus", 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := utils.ParseDuration(test.input)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Expected error for input %s, but got none", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for input %s: %v", test.input, err)
+		}
+		if result != test.expected {
+			t.Errorf("ParseDuration(%s): expected %v, got %v", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestJoinPorts(t *testing.T) {
+	tests := []struct {
+		input    []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{22}, "22"},
+		{[]int{22, 80, 443}, "22, 80, 443"},
+	}
+
+	for _, test := range tests {
+		if result := utils.JoinPorts(test.input); result != test.expected {
+			t.Errorf("JoinPorts(%v): expected %q, got %q", test.input, test.expected, result)
+		}
+	}
+}